@@ -6,6 +6,7 @@ import (
 	"orzbob/config"
 	"orzbob/keys"
 	"orzbob/log"
+	"orzbob/prompts"
 	"orzbob/session"
 	"orzbob/ui"
 	"orzbob/ui/overlay"
@@ -40,6 +41,32 @@ const (
 	statePrompt
 	// stateHelp is the state when a help screen is displayed.
 	stateHelp
+	// stateSearch is the state when the user is searching the output of all instances.
+	stateSearch
+	// stateFilter is the state when the user is typing a filter query for the list.
+	stateFilter
+	// stateTags is the state when the user is editing the tags of an instance.
+	stateTags
+	// stateScroll is the state when the user is scrolling through the history of the preview.
+	stateScroll
+	// stateGrid is the state when the grid of previews is shown.
+	stateGrid
+	// stateSendPrompt is the state when the user is entering a prompt for an existing instance.
+	stateSendPrompt
+	// statePalette is the state when the command palette is open.
+	statePalette
+	// stateRename is the state when the user is renaming an instance.
+	stateRename
+	// stateNewPrompt is the state when the user is writing the prompt for a new instance which is named after it.
+	stateNewPrompt
+	// stateNewNames is the state when the user is editing the title and branch suggested for a new instance.
+	stateNewNames
+	// stateBulk is the state when an action is being run on the marked instances.
+	stateBulk
+	// stateTemplatePicker is the state when a prompt template is being picked to insert into a prompt.
+	stateTemplatePicker
+	// stateTemplateFields is the state when the fields of the picked prompt template are being filled in.
+	stateTemplateFields
 )
 
 type home struct {
@@ -75,9 +102,50 @@ type home struct {
 	// textInputOverlay is the component for handling text input with state
 	textInputOverlay *overlay.TextInputOverlay
 
+	// formOverlay is the component for editing the title and branch of a new instance
+	formOverlay *overlay.FormOverlay
+	// pendingPrompt is the prompt for the new instance whose title and branch are being edited
+	pendingPrompt string
+
+	// progressOverlay shows the progress of a bulk action on the marked instances
+	progressOverlay *overlay.ProgressOverlay
+	// bulkAction is the action being run on bulkInstances, the instances marked when it started
+	bulkAction    *bulkAction
+	bulkInstances []*session.Instance
+
 	// textOverlay is the component for displaying text information
 	textOverlay *overlay.TextOverlay
 
+	// grid is the grid of previews. It's set while the grid is shown instead of the list and tabbed window.
+	grid *ui.Grid
+	// promptReturnState is the state to go back to after sending a prompt to an existing instance.
+	promptReturnState state
+
+	// paletteOverlay is the command palette
+	paletteOverlay *overlay.PaletteOverlay
+	// paletteActions are the actions and instances listed in the command palette, in the same order as its items
+	paletteActions []paletteAction
+
+	// templates are the prompt templates listed in the picker, in the same order as its items
+	templates []*prompts.Template
+	// pickedTemplate is the template whose fields are being filled in
+	pickedTemplate *prompts.Template
+	// templateContext is what the builtin variables of the picked template are filled in with
+	templateContext prompts.Context
+	// templateReturnState is the state of the prompt overlay the template picker was opened from
+	templateReturnState state
+	// historyRepo is the repository whose prompt history the prompt overlay shows, empty if there's none
+	historyRepo string
+	// cloudDials holds when connecting to each cloud instance was last tried, see connectCloud
+	cloudDials map[*session.Instance]time.Time
+
+	// searchOverlay is the component for searching the output of all instances
+	searchOverlay *overlay.SearchOverlay
+	// scrollbacks is the output captured when the search overlay was opened
+	scrollbacks []session.Scrollback
+	// searchMatches are the matches currently shown in the search overlay
+	searchMatches []session.SearchMatch
+
 	// keySent is used to manage underlining menu items
 	keySent bool
 }
@@ -109,7 +177,20 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		state:        stateDefault,
 		appState:     appState,
 	}
+	if err := applyTheme(appConfig.Theme); err != nil {
+		// Fall back to the detected theme rather than refusing to start.
+		log.ErrorLog.Printf("%v", err)
+		h.errBox.SetError(err)
+	}
+
+	if err := applyKeyBindings(appConfig.KeyBindings); err != nil {
+		// A bad override shouldn't stop the app from starting, so show the error and carry on with the defaults.
+		log.ErrorLog.Printf("%v", err)
+		h.errBox.SetError(err)
+	}
+
 	h.list = ui.NewList(&h.spinner, autoYes)
+	h.list.SetView(listViewFromState(appState.GetListView()))
 
 	// Load saved instances
 	instances, err := storage.LoadInstances()
@@ -144,18 +225,39 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 
 	m.tabbedWindow.SetSize(tabsWidth, contentHeight)
 	m.list.SetSize(listWidth, contentHeight)
+	if m.grid != nil {
+		m.grid.SetSize(msg.Width, contentHeight)
+	}
 
 	if m.textInputOverlay != nil {
 		m.textInputOverlay.SetSize(int(float32(msg.Width)*0.6), int(float32(msg.Height)*0.4))
 	}
+	if m.formOverlay != nil {
+		m.formOverlay.SetSize(int(float32(msg.Width)*0.5), int(float32(msg.Height)*0.4))
+	}
+	if m.progressOverlay != nil {
+		m.progressOverlay.SetSize(int(float32(msg.Width)*0.5), int(float32(msg.Height)*0.6))
+	}
 	if m.textOverlay != nil {
 		m.textOverlay.SetWidth(int(float32(msg.Width) * 0.6))
 	}
+	if m.searchOverlay != nil {
+		m.searchOverlay.SetSize(int(float32(msg.Width)*0.7), int(float32(msg.Height)*0.7))
+	}
+	if m.paletteOverlay != nil {
+		m.paletteOverlay.SetSize(int(float32(msg.Width)*0.5), int(float32(msg.Height)*0.6))
+	}
 
 	previewWidth, previewHeight := m.tabbedWindow.GetPreviewSize()
 	if err := m.list.SetSessionPreviewSize(previewWidth, previewHeight); err != nil {
 		log.ErrorLog.Print(err)
 	}
+	if m.grid != nil {
+		// Sessions in the grid are sized to their tiles instead.
+		if err := m.grid.SetSessionPreviewSize(); err != nil {
+			log.ErrorLog.Print(err)
+		}
+	}
 	m.menu.SetSize(msg.Width, menuHeight)
 }
 
@@ -169,6 +271,7 @@ func (m *home) Init() tea.Cmd {
 			return previewTickMsg{}
 		},
 		tickUpdateMetadataCmd,
+		m.measureDiskUsage(),
 	)
 }
 
@@ -189,13 +292,15 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.menu.ClearKeydown()
 		return m, nil
 	case tickUpdateMetadataMessage:
+		now := time.Now()
 		for _, instance := range m.list.GetInstances() {
-			if !instance.Started() || instance.Paused() {
+			if !instance.Started() || instance.Paused() || m.inBulk(instance) {
 				continue
 			}
 			updated, prompt := instance.HasUpdated()
 			if updated {
 				instance.SetStatus(session.Running)
+				instance.MarkActive(now)
 			} else {
 				if prompt {
 					if instance.AutoYes {
@@ -211,7 +316,7 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				log.WarningLog.Printf("could not update diff stats: %v", err)
 			}
 		}
-		return m, tickUpdateMetadataCmd
+		return m, tea.Batch(tickUpdateMetadataCmd, m.pauseIdleInstances(now))
 	case tea.MouseMsg:
 		// Handle mouse wheel scrolling in the diff view
 		if m.tabbedWindow.IsInDiffTab() {
@@ -225,8 +330,23 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, m.instanceChanged()
 				}
 			}
+			return m, nil
 		}
-		return m, nil
+		return m.handlePreviewMouse(msg)
+	case bulkStepMsg:
+		return m.handleBulkStep(msg)
+	case diskUsageTickMsg:
+		return m, m.measureDiskUsage()
+	case diskUsageMsg:
+		return m.handleDiskUsage(msg)
+	case editorFinishedMsg:
+		return m.handleEditorFinished(msg)
+	case cloudConnectedMsg:
+		return m.handleCloudConnected(msg)
+	case cloudDetachedMsg:
+		return m.handleCloudDetached(msg)
+	case promptSentMsg:
+		return m.handlePromptSent(msg)
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 	case tea.WindowSizeMsg:
@@ -254,7 +374,10 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 		m.keySent = false
 		return nil, false
 	}
-	if m.state == statePrompt || m.state == stateHelp {
+	if m.state == statePrompt || m.state == stateHelp || m.state == stateSearch || m.state == stateFilter ||
+		m.state == stateTags || m.state == stateScroll || m.state == stateSendPrompt || m.state == statePalette ||
+		m.state == stateRename || m.state == stateNewPrompt || m.state == stateNewNames || m.state == stateBulk ||
+		m.state == stateTemplatePicker || m.state == stateTemplateFields {
 		return nil, false
 	}
 	// If it's in the global keymap, we should try to highlight it.
@@ -291,6 +414,58 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		return m.handleHelpState(msg)
 	}
 
+	if m.state == stateSearch {
+		return m.handleSearchState(msg)
+	}
+
+	if m.state == stateFilter {
+		return m.handleFilterState(msg)
+	}
+
+	if m.state == stateTags {
+		return m.handleTagsState(msg)
+	}
+
+	if m.state == stateScroll {
+		return m.handleScrollState(msg)
+	}
+
+	if m.state == stateGrid {
+		return m.handleGridState(msg)
+	}
+
+	if m.state == stateSendPrompt {
+		return m.handleSendPromptState(msg)
+	}
+
+	if m.state == statePalette {
+		return m.handlePaletteState(msg)
+	}
+
+	if m.state == stateRename {
+		return m.handleRenameState(msg)
+	}
+
+	if m.state == stateNewPrompt {
+		return m.handleNewPromptState(msg)
+	}
+
+	if m.state == stateNewNames {
+		return m.handleNewNamesState(msg)
+	}
+
+	if m.state == stateBulk {
+		return m.handleBulkState(msg)
+	}
+
+	if m.state == stateTemplatePicker {
+		return m.handleTemplatePickerState(msg)
+	}
+
+	if m.state == stateTemplateFields {
+		return m.handleTemplateFieldsState(msg)
+	}
+
 	if m.state == stateNew {
 		// Handle quit commands first. Don't handle q because the user might want to type that.
 		if msg.String() == "ctrl+c" {
@@ -335,7 +510,7 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				m.state = statePrompt
 				m.menu.SetState(ui.StatePrompt)
 				// Initialize the text input overlay
-				m.textInputOverlay = overlay.NewTextInputOverlay("Enter prompt", "")
+				m.textInputOverlay = m.newPromptOverlay("Enter prompt")
 				m.promptAfterName = false
 			} else {
 				m.menu.SetState(ui.StateDefault)
@@ -344,8 +519,8 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 
 			return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
 		case tea.KeyRunes:
-			if len(instance.Title) >= 32 {
-				return m, m.handleError(fmt.Errorf("title cannot be longer than 32 characters"))
+			if len(instance.Title) >= maxTitleLength {
+				return m, m.handleError(fmt.Errorf("title cannot be longer than %d characters", maxTitleLength))
 			}
 			if err := instance.SetTitle(instance.Title + string(msg.Runes)); err != nil {
 				return m, m.handleError(err)
@@ -382,12 +557,19 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 
 		// Check if the form was submitted or canceled
 		if shouldClose {
+			if m.textInputOverlay.TemplateRequested {
+				return m.openTemplatePicker()
+			}
+			if m.textInputOverlay.EditorRequested {
+				return m.openEditor()
+			}
 			if m.textInputOverlay.IsSubmitted() {
 				// Form was submitted, process the input
 				selected := m.list.GetSelectedInstance()
 				if selected == nil {
 					return m, nil
 				}
+				m.recordPrompt(m.textInputOverlay.GetValue())
 				if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
 					return m, m.handleError(err)
 				}
@@ -410,18 +592,77 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 	}
 
 	// Handle quit commands first
-	if msg.String() == "ctrl+c" || msg.String() == "q" {
+	if msg.String() == "ctrl+c" {
 		return m.handleQuit()
 	}
 
+	// Escape returns a preview pinned to a search result back to the live output.
+	if msg.Type == tea.KeyEsc && m.tabbedWindow.IsPreviewScrolled() {
+		m.tabbedWindow.ResetPreviewScroll()
+		return m, m.instanceChanged()
+	}
+
+	// Escape clears the marks, so the next actions apply to the selected instance again.
+	if msg.Type == tea.KeyEsc && len(m.list.MarkedInstances()) > 0 {
+		m.list.ClearMarks()
+		return m, nil
+	}
+
 	name, ok := keys.GlobalKeyStringsMap[msg.String()]
 	if !ok {
 		return m, nil
 	}
+	return m.runAction(name)
+}
+
+// runAction runs the action bound to a key in the default state. It's also used by the command palette.
+func (m *home) runAction(name keys.KeyName) (tea.Model, tea.Cmd) {
+	// Pausing, resuming, pushing and killing apply to every marked instance instead of the selected one.
+	if len(m.list.MarkedInstances()) > 0 {
+		if action, ok := m.bulkActionFor(name); ok {
+			return m.startBulk(action)
+		}
+	}
 
 	switch name {
+	case keys.KeyQuit:
+		return m.handleQuit()
 	case keys.KeyHelp:
 		return m.showHelpScreen(helpTypeGeneral, nil)
+	case keys.KeySearch:
+		return m.openSearch()
+	case keys.KeyFilter:
+		m.state = stateFilter
+		m.list.SetFiltering(true)
+		return m, nil
+	case keys.KeyFilterMode:
+		m.list.CycleFilter()
+		return m, tea.Batch(m.saveListView(), m.instanceChanged())
+	case keys.KeySort:
+		m.list.CycleSort()
+		return m, tea.Batch(m.saveListView(), m.instanceChanged())
+	case keys.KeyCollapse:
+		m.list.ToggleGroup()
+		return m, tea.Batch(m.saveListView(), m.instanceChanged())
+	case keys.KeyTags:
+		return m.openTagEditor()
+	case keys.KeyGrid:
+		return m.openGrid()
+	case keys.KeySendPrompt:
+		return m.openSendPrompt()
+	case keys.KeyPalette:
+		return m.openPalette()
+	case keys.KeyMark:
+		m.list.ToggleMark()
+		return m, nil
+	case keys.KeyMove:
+		return m.moveSelected()
+	case keys.KeyKeepAlive:
+		return m.toggleKeepAlive()
+	case keys.KeyRename:
+		return m.openRename()
+	case keys.KeyPromptNew:
+		return m.openNewFromPrompt()
 	case keys.KeyPrompt:
 		if m.list.NumInstances() >= GlobalInstanceLimit {
 			return m, m.handleError(
@@ -437,7 +678,7 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 
 		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+		m.list.SelectInstance(instance)
 		m.state = stateNew
 		m.menu.SetState(ui.StateNewInstance)
 		m.promptAfterName = true
@@ -458,21 +699,24 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 
 		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+		m.list.SelectInstance(instance)
 		m.state = stateNew
 		m.menu.SetState(ui.StateNewInstance)
 
 		return m, nil
 	case keys.KeyUp:
 		m.list.Up()
+		m.tabbedWindow.ResetPreviewScroll()
 		return m, m.instanceChanged()
 	case keys.KeyDown:
 		m.list.Down()
+		m.tabbedWindow.ResetPreviewScroll()
 		return m, m.instanceChanged()
 	case keys.KeyShiftUp:
-		if m.tabbedWindow.IsInDiffTab() {
-			m.tabbedWindow.ScrollUp()
+		if !m.tabbedWindow.IsInDiffTab() {
+			return m.enterScrollMode()
 		}
+		m.tabbedWindow.ScrollUp()
 		return m, m.instanceChanged()
 	case keys.KeyShiftDown:
 		if m.tabbedWindow.IsInDiffTab() {
@@ -488,6 +732,13 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
+		if selected.IsCloud {
+			if err := m.killCloudInstance(selected); err != nil {
+				return m, m.handleError(err)
+			}
+			m.list.Kill()
+			return m, m.instanceChanged()
+		}
 
 		worktree, err := selected.GetGitWorktree()
 		if err != nil {
@@ -552,29 +803,38 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 		return m, tea.WindowSize()
 	case keys.KeyEnter:
-		if m.list.NumInstances() == 0 {
-			return m, nil
-		}
-		selected := m.list.GetSelectedInstance()
-		if selected == nil || selected.Paused() || !selected.TmuxAlive() {
-			return m, nil
-		}
-		// Show help screen before attaching
-		m.showHelpScreen(helpTypeInstanceAttach, func() {
-			ch, err := m.list.Attach()
-			if err != nil {
-				m.handleError(err)
-				return
-			}
-			<-ch
-			m.state = stateDefault
-		})
-		return m, nil
+		return m.attachSelected()
 	default:
 		return m, nil
 	}
 }
 
+// attachSelected attaches to the selected instance. After detaching, the app goes back to the state it was in.
+func (m *home) attachSelected() (tea.Model, tea.Cmd) {
+	if m.list.NumInstances() == 0 {
+		return m, nil
+	}
+	selected := m.list.GetSelectedInstance()
+	if selected != nil && selected.IsCloud {
+		return m.attachCloud(selected)
+	}
+	if selected == nil || selected.Paused() || !selected.TmuxAlive() {
+		return m, nil
+	}
+	returnState := m.state
+	// Show help screen before attaching
+	m.showHelpScreen(helpTypeInstanceAttach, func() {
+		ch, err := m.list.Attach()
+		if err != nil {
+			m.handleError(err)
+			return
+		}
+		<-ch
+		m.state = returnState
+	})
+	return m, nil
+}
+
 // instanceChanged updates the preview pane, menu, and diff pane based on the selected instance. It returns an error
 // Cmd if there was any error.
 func (m *home) instanceChanged() tea.Cmd {
@@ -589,7 +849,12 @@ func (m *home) instanceChanged() tea.Cmd {
 	if err := m.tabbedWindow.UpdatePreview(selected); err != nil {
 		return m.handleError(err)
 	}
-	return nil
+	if m.grid != nil {
+		if err := m.grid.UpdateContent(); err != nil {
+			return m.handleError(err)
+		}
+	}
+	return m.connectCloud(selected)
 }
 
 type keyupMsg struct{}
@@ -638,9 +903,14 @@ func (m *home) handleError(err error) tea.Cmd {
 }
 
 func (m *home) View() string {
-	listWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.list.String())
-	previewWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.tabbedWindow.String())
-	listAndPreview := lipgloss.JoinHorizontal(lipgloss.Top, listWithPadding, previewWithPadding)
+	var listAndPreview string
+	if m.grid != nil {
+		listAndPreview = lipgloss.NewStyle().PaddingTop(1).Render(m.grid.String())
+	} else {
+		listWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.list.String())
+		previewWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.tabbedWindow.String())
+		listAndPreview = lipgloss.JoinHorizontal(lipgloss.Top, listWithPadding, previewWithPadding)
+	}
 
 	mainView := lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -649,7 +919,8 @@ func (m *home) View() string {
 		m.errBox.String(),
 	)
 
-	if m.state == statePrompt {
+	if m.state == statePrompt || m.state == stateTags || m.state == stateSendPrompt || m.state == stateRename ||
+		m.state == stateNewPrompt {
 		if m.textInputOverlay == nil {
 			log.ErrorLog.Printf("text input overlay is nil")
 		}
@@ -659,6 +930,26 @@ func (m *home) View() string {
 			log.ErrorLog.Printf("text overlay is nil")
 		}
 		return overlay.PlaceOverlay(0, 0, m.textOverlay.Render(), mainView, true, true)
+	} else if m.state == stateSearch {
+		if m.searchOverlay == nil {
+			log.ErrorLog.Printf("search overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.searchOverlay.Render(), mainView, true, true)
+	} else if m.state == stateNewNames || m.state == stateTemplateFields {
+		if m.formOverlay == nil {
+			log.ErrorLog.Printf("form overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.formOverlay.Render(), mainView, true, true)
+	} else if m.state == statePalette || m.state == stateTemplatePicker {
+		if m.paletteOverlay == nil {
+			log.ErrorLog.Printf("palette overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.paletteOverlay.Render(), mainView, true, true)
+	} else if m.state == stateBulk {
+		if m.progressOverlay == nil {
+			log.ErrorLog.Printf("progress overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.progressOverlay.Render(), mainView, true, true)
 	}
 
 	return mainView
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/keys"
+	"orzbob/session"
+	"orzbob/ui/overlay"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bulkAction is an action run on every marked instance, one at a time.
+type bulkAction struct {
+	// title describes the action in the progress overlay, e.g. "Pausing".
+	title string
+	// run runs the action on one instance. It's called outside of the update loop so the progress can be shown.
+	run func(instance *session.Instance) error
+	// after is called in the update loop once run succeeded on an instance. It may be nil.
+	after func(instance *session.Instance)
+}
+
+// bulkStepMsg is sent when a bulk action has finished on one instance.
+type bulkStepMsg struct {
+	index int
+	err   error
+}
+
+// bulkActionFor returns the bulk version of the action bound to a key, if it has one. Sending a prompt isn't
+// included since the prompt has to be written first.
+func (m *home) bulkActionFor(name keys.KeyName) (bulkAction, bool) {
+	switch name {
+	case keys.KeyCheckout:
+		return bulkAction{title: "Pausing", run: (*session.Instance).Pause}, true
+	case keys.KeyResume:
+		return bulkAction{title: "Resuming", run: (*session.Instance).Resume}, true
+	case keys.KeySubmit:
+		return bulkAction{title: "Pushing", run: pushInstance}, true
+	case keys.KeyKill:
+		return bulkAction{title: "Killing", run: m.killInstance, after: m.list.Remove}, true
+	}
+	return bulkAction{}, false
+}
+
+// sendPromptAction is the bulk action which sends the same prompt to every marked instance.
+func sendPromptAction(prompt string) bulkAction {
+	return bulkAction{
+		title: "Sending prompt to",
+		run: func(instance *session.Instance) error {
+			return instance.SendPrompt(prompt)
+		},
+	}
+}
+
+// pushInstance commits and pushes the changes of an instance without opening the branch in the browser.
+func pushInstance(instance *session.Instance) error {
+	commitMsg := fmt.Sprintf("[orzbob] update from '%s' on %s", instance.Title, time.Now().Format(time.RFC822))
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return err
+	}
+	return worktree.PushChanges(commitMsg, false)
+}
+
+// killInstance deletes an instance from storage and kills it. It's removed from the list afterwards, in the update
+// loop.
+func (m *home) killInstance(instance *session.Instance) error {
+	if instance.IsCloud {
+		return m.killCloudInstance(instance)
+	}
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return err
+	}
+	checkedOut, err := worktree.IsBranchCheckedOut()
+	if err != nil {
+		return err
+	}
+	if checkedOut {
+		return fmt.Errorf("instance %s is currently checked out", instance.Title)
+	}
+	if err := m.storage.DeleteInstance(instance.Title); err != nil {
+		return err
+	}
+	return instance.Kill()
+}
+
+// startBulk runs an action on every marked instance, showing the progress of each one. A failure on one instance
+// doesn't stop the others.
+func (m *home) startBulk(action bulkAction) (tea.Model, tea.Cmd) {
+	instances := m.list.MarkedInstances()
+	names := make([]string, len(instances))
+	for i, instance := range instances {
+		names[i] = instance.Title
+	}
+	m.bulkAction = &action
+	m.bulkInstances = instances
+	m.progressOverlay = overlay.NewProgressOverlay(fmt.Sprintf("%s %d instances", action.title, len(instances)), names)
+	m.state = stateBulk
+	return m, tea.Batch(tea.WindowSize(), m.runBulkStep())
+}
+
+// inBulk reports whether a bulk action is running on an instance. The action changes it in the background, so the
+// update loop has to leave it alone until the action is done.
+func (m *home) inBulk(instance *session.Instance) bool {
+	if m.state != stateBulk {
+		return false
+	}
+	for _, bulkInstance := range m.bulkInstances {
+		if bulkInstance == instance {
+			return true
+		}
+	}
+	return false
+}
+
+// runBulkStep starts the action on the next pending instance. It returns nil once there are none left.
+func (m *home) runBulkStep() tea.Cmd {
+	i := m.progressOverlay.Next()
+	if i < 0 {
+		return nil
+	}
+	m.progressOverlay.Start(i)
+	instance, run := m.bulkInstances[i], m.bulkAction.run
+	return func() tea.Msg {
+		return bulkStepMsg{index: i, err: run(instance)}
+	}
+}
+
+// handleBulkStep records the result of the action on one instance and moves on to the next one. Once every instance
+// is done, the instances are saved.
+func (m *home) handleBulkStep(msg bulkStepMsg) (tea.Model, tea.Cmd) {
+	m.progressOverlay.Finish(msg.index, msg.err)
+	if msg.err == nil && m.bulkAction.after != nil {
+		m.bulkAction.after(m.bulkInstances[msg.index])
+	}
+	if next := m.runBulkStep(); next != nil {
+		return m, next
+	}
+
+	var cmd tea.Cmd
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		cmd = m.handleError(err)
+	}
+	// Resumed instances need to be sized to the preview.
+	return m, tea.Batch(cmd, tea.WindowSize(), m.instanceChanged())
+}
+
+// handleBulkState handles key events while the progress of a bulk action is shown. Escape cancels the instances
+// which haven't started yet, and the overlay can be closed once everything has finished.
+func (m *home) handleBulkState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.progressOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	m.progressOverlay = nil
+	m.bulkAction = nil
+	m.bulkInstances = nil
+	m.state = stateDefault
+	return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
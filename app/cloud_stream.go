@@ -0,0 +1,67 @@
+package app
+
+import (
+	"orzbob/internal/tunnel"
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/session/cloud"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cloudRedialInterval is how long to wait before connecting to a cloud instance again after it failed.
+const cloudRedialInterval = 30 * time.Second
+
+// cloudConnectedMsg is sent when connecting to the terminal of a cloud instance finished.
+type cloudConnectedMsg struct {
+	instance *session.Instance
+	stream   *tunnel.Stream
+	err      error
+}
+
+// connectCloud starts connecting to the terminal of instance if it's a cloud instance which isn't connected yet, so
+// its output is recorded for scroll mode and search. It returns nil if there's nothing to do.
+func (m *home) connectCloud(instance *session.Instance) tea.Cmd {
+	if instance == nil || !instance.IsCloud || instance.CloudConnected() || instance.AttachURL == "" {
+		return nil
+	}
+	if tried, ok := m.cloudDials[instance]; ok && time.Since(tried) < cloudRedialInterval {
+		return nil
+	}
+	if m.cloudDials == nil {
+		m.cloudDials = make(map[*session.Instance]time.Time)
+	}
+	m.cloudDials[instance] = time.Now()
+
+	ctx, id, attachURL := m.ctx, instance.CloudInstanceID, instance.AttachURL
+	return func() tea.Msg {
+		// The stored attach URL holds a token which expires, so get a fresh one if possible.
+		if manager := cloud.NewManager(); manager.IsAuthenticated() {
+			if fresh, err := manager.GetInstanceWithAttachURL(ctx, id); err == nil && fresh.AttachURL != "" {
+				attachURL = fresh.AttachURL
+			}
+		}
+		stream, err := tunnel.DialStream(attachURL)
+		return cloudConnectedMsg{instance: instance, stream: stream, err: err}
+	}
+}
+
+// handleCloudConnected hands the connection to a cloud instance to it. The connection is closed if the instance was
+// killed or brought home in the meantime.
+func (m *home) handleCloudConnected(msg cloudConnectedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		log.WarningLog.Printf("failed to connect to cloud instance %s: %v", msg.instance.Title, msg.err)
+		return m, nil
+	}
+	for _, instance := range m.list.GetInstances() {
+		if instance == msg.instance && instance.IsCloud {
+			if err := instance.ConnectCloud(msg.stream); err != nil {
+				log.WarningLog.Printf("failed to connect to cloud instance %s: %v", instance.Title, err)
+			}
+			return m, nil
+		}
+	}
+	msg.stream.Close()
+	return m, nil
+}
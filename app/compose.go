@@ -0,0 +1,121 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/log"
+	"orzbob/prompts"
+	"orzbob/session/git"
+	"orzbob/ui/overlay"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newPromptOverlay returns the overlay to write a prompt in, with the templates, the editor and the prompt history
+// of the repository the prompt is for. The state it's for must already be set.
+func (m *home) newPromptOverlay(title string) *overlay.TextInputOverlay {
+	textInput := overlay.NewTextInputOverlay(title, "")
+	textInput.AllowTemplates()
+	textInput.AllowEditor()
+
+	m.historyRepo = ""
+	if instance := m.promptInstance(); instance != nil {
+		if worktree, err := instance.GetGitWorktree(); err == nil {
+			m.historyRepo = worktree.GetRepoPath()
+		}
+	} else if repoPath, err := git.RepoRoot("."); err == nil {
+		m.historyRepo = repoPath
+	}
+	if m.historyRepo == "" {
+		return textInput
+	}
+	dir, err := prompts.HistoryDir()
+	if err != nil {
+		log.WarningLog.Printf("failed to load the prompt history: %v", err)
+		return textInput
+	}
+	history, err := prompts.LoadHistory(dir, m.historyRepo)
+	if err != nil {
+		log.WarningLog.Printf("failed to load the prompt history: %v", err)
+	}
+	textInput.SetHistory(history)
+	return textInput
+}
+
+// recordPrompt adds a prompt which was sent to the history of the repository the prompt overlay was opened for.
+func (m *home) recordPrompt(prompt string) {
+	if m.historyRepo == "" {
+		return
+	}
+	dir, err := prompts.HistoryDir()
+	if err == nil {
+		err = prompts.AddHistory(dir, m.historyRepo, prompt)
+	}
+	if err != nil {
+		log.WarningLog.Printf("failed to save the prompt history: %v", err)
+	}
+}
+
+// editorFinishedMsg is sent when the editor opened with ctrl+o in a prompt exits.
+type editorFinishedMsg struct {
+	path string
+	err  error
+}
+
+// editorCommand returns the command to edit a file with, $VISUAL or $EDITOR with their arguments, or vi.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) > 0 {
+			return args
+		}
+	}
+	return []string{"vi"}
+}
+
+// openEditor suspends the app to write the prompt in the editor after ctrl+o was pressed in the prompt overlay. It
+// edits a temporary file holding the prompt so far, which replaces the prompt when the editor exits.
+func (m *home) openEditor() (tea.Model, tea.Cmd) {
+	text := m.textInputOverlay.GetValue()
+	file, err := os.CreateTemp("", "orzbob-prompt-*.md")
+	if err != nil {
+		m.textInputOverlay.SetEditorText(text)
+		return m, m.handleError(err)
+	}
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		m.textInputOverlay.SetEditorText(text)
+		return m, m.handleError(err)
+	}
+
+	args := editorCommand()
+	cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{path: file.Name(), err: err}
+	})
+}
+
+// handleEditorFinished puts what was written in the editor into the prompt overlay. The prompt is kept as it was if
+// the editor failed.
+func (m *home) handleEditorFinished(msg editorFinishedMsg) (tea.Model, tea.Cmd) {
+	defer os.Remove(msg.path)
+	if m.textInputOverlay == nil {
+		return m, nil
+	}
+	text := m.textInputOverlay.GetValue()
+	var cmd tea.Cmd
+	if msg.err != nil {
+		cmd = m.handleError(fmt.Errorf("the editor failed: %w", msg.err))
+	} else if data, err := os.ReadFile(msg.path); err != nil {
+		cmd = m.handleError(err)
+	} else {
+		text = strings.TrimRight(string(data), "\n")
+	}
+	m.textInputOverlay.SetEditorText(text)
+	return m, tea.Batch(cmd, tea.WindowSize())
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"orzbob/log"
+	"orzbob/session"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diskUsageInterval is how long to wait between measuring the worktrees of the instances. Walking big worktrees
+// takes a while, so it's done far less often than the other updates.
+const diskUsageInterval = time.Minute
+
+// diskUsageTickMsg is sent when it's time to measure the worktrees again.
+type diskUsageTickMsg struct{}
+
+// diskUsageMsg holds the size of the worktree of each running instance.
+type diskUsageMsg struct {
+	sizes map[*session.Instance]int64
+}
+
+// measureDiskUsage measures the worktrees of the running instances in the background.
+func (m *home) measureDiskUsage() tea.Cmd {
+	paths := make(map[*session.Instance]string)
+	for _, instance := range m.list.GetInstances() {
+		if instance.Started() && !instance.Paused() {
+			paths[instance] = instance.WorktreePath()
+		}
+	}
+	return func() tea.Msg {
+		sizes := make(map[*session.Instance]int64, len(paths))
+		for instance, path := range paths {
+			size, err := session.DiskUsage(path)
+			if err != nil {
+				log.ForInstance(instance.Title).Warn("could not measure worktree", "error", err)
+				continue
+			}
+			sizes[instance] = size
+		}
+		return diskUsageMsg{sizes: sizes}
+	}
+}
+
+// handleDiskUsage shows the measured sizes in the list and schedules the next measurement. Paused instances have no
+// worktree, so they show no size.
+func (m *home) handleDiskUsage(msg diskUsageMsg) (tea.Model, tea.Cmd) {
+	for _, instance := range m.list.GetInstances() {
+		instance.SetDiskUsage(msg.sizes[instance])
+	}
+	return m, tea.Tick(diskUsageInterval, func(time.Time) tea.Msg {
+		return diskUsageTickMsg{}
+	})
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"orzbob/keys"
+	"orzbob/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openGrid replaces the list and tabbed window with a grid of live previews of the instances shown in the list.
+func (m *home) openGrid() (tea.Model, tea.Cmd) {
+	grid, err := ui.NewGrid(&m.spinner, m.list.VisibleInstances())
+	if err != nil {
+		return m, m.handleError(err)
+	}
+	if selected := m.list.GetSelectedInstance(); selected != nil {
+		grid.Focus(selected)
+	}
+	m.grid = grid
+	m.state = stateGrid
+	// Resizing resizes the sessions to fit their tiles.
+	return m, tea.WindowSize()
+}
+
+// closeGrid goes back to the list with the focused instance selected.
+func (m *home) closeGrid() (tea.Model, tea.Cmd) {
+	m.list.SelectInstance(m.grid.Focused())
+	m.grid = nil
+	m.state = stateDefault
+	// Resizing gives the sessions back the size of the preview pane.
+	return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
+
+// handleGridState handles key events while the grid is shown. Arrow keys move the focus between tiles, and
+// attaching or sending a prompt applies to the focused tile.
+func (m *home) handleGridState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m.closeGrid()
+	case "left", "h":
+		m.grid.Move(-1, 0)
+		return m.gridFocusChanged()
+	case "right", "l":
+		m.grid.Move(1, 0)
+		return m.gridFocusChanged()
+	}
+
+	name, ok := keys.GlobalKeyStringsMap[msg.String()]
+	if !ok {
+		return m, nil
+	}
+	switch name {
+	case keys.KeyGrid:
+		return m.closeGrid()
+	case keys.KeyUp:
+		m.grid.Move(0, -1)
+		return m.gridFocusChanged()
+	case keys.KeyDown:
+		m.grid.Move(0, 1)
+		return m.gridFocusChanged()
+	case keys.KeyEnter:
+		return m.attachSelected()
+	case keys.KeySendPrompt:
+		return m.openSendPrompt()
+	case keys.KeyQuit:
+		return m.handleQuit()
+	}
+	return m, nil
+}
+
+// gridFocusChanged selects the focused instance in the list so the menu and actions follow the focus.
+func (m *home) gridFocusChanged() (tea.Model, tea.Cmd) {
+	m.list.SelectInstance(m.grid.Focused())
+	return m, m.instanceChanged()
+}
@@ -2,10 +2,13 @@ package app
 
 import (
 	"fmt"
+	"orzbob/keys"
 	"orzbob/log"
 	"orzbob/session"
 	"orzbob/ui"
 	"orzbob/ui/overlay"
+	"orzbob/ui/theme"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -30,12 +33,39 @@ const (
 )
 
 var (
-	titleStyle  = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("#7D56F4"))
-	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#36CFC9"))
-	keyStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFCC00"))
-	descStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	titleStyle  lipgloss.Style
+	headerStyle lipgloss.Style
+	keyStyle    lipgloss.Style
+	descStyle   lipgloss.Style
 )
 
+func init() {
+	setHelpStyles(theme.Current())
+}
+
+// setHelpStyles builds the styles of the help screens from a theme.
+func setHelpStyles(t *theme.Theme) {
+	titleStyle = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(t.Color(theme.Primary))
+	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Color(theme.Info))
+	keyStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Color(theme.Emphasis))
+	descStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Text))
+}
+
+// helpLine renders a line of the help screen, padding the key to width columns so descriptions line up.
+func helpLine(key string, width int, desc string) string {
+	pad := max(width-lipgloss.Width(key), 1)
+	return keyStyle.Render(key) + descStyle.Render(strings.Repeat(" ", pad)+"- "+desc)
+}
+
+// keyLine renders a line of the help screen for one or more actions using their effective key bindings.
+func keyLine(width int, desc string, names ...keys.KeyName) string {
+	helpKeys := make([]string, len(names))
+	for i, name := range names {
+		helpKeys[i] = keys.HelpKey(name)
+	}
+	return helpLine(strings.Join(helpKeys, ", "), width, desc)
+}
+
 func (h helpType) ToContent(instance *session.Instance) string {
 	switch h {
 	case helpTypeGeneral:
@@ -45,22 +75,43 @@ func (h helpType) ToContent(instance *session.Instance) string {
 			"A terminal UI that manages multiple Claude Code (and other local agents) in separate workspaces.",
 			"",
 			headerStyle.Render("Managing:"),
-			keyStyle.Render("n")+descStyle.Render("         - Create a new session"),
-			keyStyle.Render("N")+descStyle.Render("         - Create a new session with a prompt"),
-			keyStyle.Render("D")+descStyle.Render("         - Kill (delete) the selected session"),
-			keyStyle.Render("↑/j, ↓/k")+descStyle.Render("  - Navigate between sessions"),
-			keyStyle.Render("↵/o")+descStyle.Render("       - Attach to the selected session"),
-			keyStyle.Render("ctrl-q")+descStyle.Render("    - Detach from session"),
+			keyLine(10, "Create a new session", keys.KeyNew),
+			keyLine(10, "Create a new session with a prompt", keys.KeyPrompt),
+			keyLine(10, "Kill (delete) the selected session", keys.KeyKill),
+			keyLine(10, "Navigate between sessions", keys.KeyUp, keys.KeyDown),
+			keyLine(10, "Attach to the selected session", keys.KeyEnter),
+			keyLine(10, "Write a prompt and start a session named after it", keys.KeyPromptNew),
+			keyLine(10, "Send a prompt to the selected session", keys.KeySendPrompt),
+			helpLine("ctrl+t", 10, "In a prompt: insert a saved prompt template"),
+			helpLine("ctrl+o", 10, "In a prompt: write it in $EDITOR"),
+			helpLine("↑/ctrl+r", 10, "In a prompt: browse or search the prompts sent in the repo"),
+			keyLine(10, "Rename the session, its branch, tmux session and worktree", keys.KeyRename),
+			keyLine(10, "Mark the session (or every session in a repo group) for bulk actions, esc clears", keys.KeyMark),
+			helpLine(keys.DetachKey, 10, "Detach from session"),
+			"",
+			headerStyle.Render("Organizing:"),
+			keyLine(10, "Filter sessions by title or tag", keys.KeyFilter),
+			keyLine(10, "Cycle which sessions are shown (waiting, running, paused, local, cloud)", keys.KeyFilterMode),
+			keyLine(10, "Cycle the sort order (status, repo, program, age, diff, location)", keys.KeySort),
+			keyLine(10, "Collapse or expand the selected repo group", keys.KeyCollapse),
+			keyLine(10, "Edit the tags of the selected session", keys.KeyTags),
 			"",
 			headerStyle.Render("Handoff:"),
-			keyStyle.Render("p")+descStyle.Render("         - Commit and push branch to github"),
-			keyStyle.Render("c")+descStyle.Render("         - Checkout: commit changes and pause session"),
-			keyStyle.Render("r")+descStyle.Render("         - Resume a paused session"),
+			keyLine(10, "Commit and push the branch to its remote", keys.KeySubmit),
+			keyLine(10, "Checkout: commit changes and pause session", keys.KeyCheckout),
+			keyLine(10, "Resume a paused session", keys.KeyResume),
+			keyLine(10, "Send the session to the cloud, or bring a cloud session home", keys.KeyMove),
+			keyLine(10, "Keep the session running when idle, or let it be paused again (☾ marks idle pauses)",
+				keys.KeyKeepAlive),
 			"",
 			headerStyle.Render("Other:"),
-			keyStyle.Render("tab")+descStyle.Render("       - Switch between preview and diff tabs"),
-			keyStyle.Render("shift-↓/↑")+descStyle.Render(" - Scroll in diff view"),
-			keyStyle.Render("q")+descStyle.Render("         - Quit the application"),
+			keyLine(10, "Switch between preview and diff tabs", keys.KeyTab),
+			keyLine(10, "Scroll the diff, or page through the preview's history", keys.KeyShiftDown, keys.KeyShiftUp),
+			helpLine("/, n/N, f", 10, "In the preview's history: search, next/previous match, follow output"),
+			keyLine(10, "Search the output of all sessions", keys.KeySearch),
+			keyLine(10, "Show a grid of live previews (arrows move, attach and prompt the focused tile)", keys.KeyGrid),
+			keyLine(10, "Open the command palette to run any action or jump to a session", keys.KeyPalette),
+			keyLine(10, "Quit the application", keys.KeyQuit),
 		)
 		return content
 
@@ -73,13 +124,13 @@ func (h helpType) ToContent(instance *session.Instance) string {
 			descStyle.Render(fmt.Sprintf("• %s running in background tmux session", lipgloss.NewStyle().Bold(true).Render(instance.Program))),
 			"",
 			headerStyle.Render("Managing:"),
-			keyStyle.Render("↵/o")+descStyle.Render("   - Attach to the session to interact with it directly"),
-			keyStyle.Render("tab")+descStyle.Render("   - Switch preview panes to view session diff"),
-			keyStyle.Render("D")+descStyle.Render("     - Kill (delete) the selected session"),
+			keyLine(6, "Attach to the session to interact with it directly", keys.KeyEnter),
+			keyLine(6, "Switch preview panes to view session diff", keys.KeyTab),
+			keyLine(6, "Kill (delete) the selected session", keys.KeyKill),
 			"",
 			headerStyle.Render("Handoff:"),
-			keyStyle.Render("c")+descStyle.Render("     - Checkout this instance's branch"),
-			keyStyle.Render("p")+descStyle.Render("     - Push branch to GitHub to create a PR"),
+			keyLine(6, "Checkout this instance's branch", keys.KeyCheckout),
+			keyLine(6, "Push branch to create a PR", keys.KeySubmit),
 		)
 		return content
 
@@ -87,7 +138,7 @@ func (h helpType) ToContent(instance *session.Instance) string {
 		content := lipgloss.JoinVertical(lipgloss.Left,
 			titleStyle.Render("Attaching to Instance"),
 			"",
-			descStyle.Render("To detach from a session, press ")+keyStyle.Render("ctrl-q"),
+			descStyle.Render("To detach from a session, press ")+keyStyle.Render(keys.DetachKey),
 		)
 		return content
 
@@ -95,13 +146,13 @@ func (h helpType) ToContent(instance *session.Instance) string {
 		content := lipgloss.JoinVertical(lipgloss.Left,
 			titleStyle.Render("Checkout Instance"),
 			"",
-			"Changes will be committed and pushed to GitHub. The branch name has been copied to your clipboard for you to checkout.",
+			"Changes will be committed to the branch locally, without pushing it. The branch name has been copied to your clipboard for you to checkout.",
 			"",
 			"Feel free to make changes to the branch and commit them. When resuming, the session will continue from where you left off.",
 			"",
 			headerStyle.Render("Commands:"),
-			keyStyle.Render("c")+descStyle.Render(" - Checkout: commit changes and pause session"),
-			keyStyle.Render("r")+descStyle.Render(" - Resume a paused session"),
+			keyLine(2, "Checkout: commit changes and pause session", keys.KeyCheckout),
+			keyLine(2, "Resume a paused session", keys.KeyResume),
 		)
 		return content
 	}
@@ -151,7 +202,11 @@ func (m *home) handleHelpState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Any key press will close the help overlay
 	shouldClose := m.textOverlay.HandleKeyPress(msg)
 	if shouldClose {
-		m.state = stateDefault
+		// The dismiss callback may have moved to another state already, for example back to the grid after
+		// attaching from it.
+		if m.state == stateHelp {
+			m.state = stateDefault
+		}
 		return m, tea.Sequence(
 			tea.WindowSize(),
 			func() tea.Msg {
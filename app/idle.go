@@ -0,0 +1,55 @@
+package app
+
+import (
+	"orzbob/log"
+	"orzbob/session"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pauseIdleInstances pauses the instances which have been idle for longer than the configured auto_pause_hours.
+func (m *home) pauseIdleInstances(now time.Time) tea.Cmd {
+	// Bulk actions change instances in the background, so leave them alone until it's done.
+	if m.state == stateBulk {
+		return nil
+	}
+	policy := session.IdlePolicy{After: time.Duration(m.appConfig.AutoPauseHours) * time.Hour}
+
+	paused := false
+	for _, instance := range m.list.GetInstances() {
+		if !policy.ShouldPause(instance, now) {
+			continue
+		}
+		if err := instance.AutoPause(); err != nil {
+			log.ForInstance(instance.Title).Error("could not pause idle instance", "error", err)
+			// Try again once it has been idle for another period instead of on every tick.
+			instance.MarkActive(now)
+			continue
+		}
+		log.ForInstance(instance.Title).Info("paused while idle", "idle_hours", m.appConfig.AutoPauseHours)
+		paused = true
+	}
+	if !paused {
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		cmd = m.handleError(err)
+	}
+	return tea.Batch(cmd, m.instanceChanged())
+}
+
+// toggleKeepAlive opts the selected instance out of being paused when idle, or back in.
+func (m *home) toggleKeepAlive() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || selected.IsCloud {
+		return m, nil
+	}
+	selected.KeepAlive = !selected.KeepAlive
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m, m.handleError(err)
+	}
+	return m, nil
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"orzbob/keys"
+	"orzbob/session/tmux"
+)
+
+// applyKeyBindings applies the key binding overrides from the config, including the key which detaches from an
+// attached session.
+func applyKeyBindings(overrides map[string][]string) error {
+	if err := keys.ApplyOverrides(overrides); err != nil {
+		return err
+	}
+	b, err := keys.ControlByte(keys.DetachKey)
+	if err != nil {
+		return err
+	}
+	tmux.SetDetachKey(b)
+	return nil
+}
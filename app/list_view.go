@@ -0,0 +1,121 @@
+package app
+
+import (
+	"orzbob/config"
+	"orzbob/ui"
+	"orzbob/ui/overlay"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// listViewFromState converts the saved list view into the one used by the list.
+func listViewFromState(saved config.ListView) ui.ListView {
+	view := ui.ListView{
+		Sort:      ui.SortMode(saved.SortMode),
+		Filter:    ui.FilterMode(saved.FilterMode),
+		Query:     saved.Query,
+		Collapsed: make(map[string]bool),
+	}
+	for _, group := range saved.CollapsedGroups {
+		view.Collapsed[group] = true
+	}
+	return view
+}
+
+// saveListView persists how the list is filtered, sorted and grouped so it's restored on the next start.
+func (m *home) saveListView() tea.Cmd {
+	view := m.list.View()
+	saved := config.ListView{
+		SortMode:   string(view.Sort),
+		FilterMode: string(view.Filter),
+		Query:      view.Query,
+	}
+	for group := range view.Collapsed {
+		saved.CollapsedGroups = append(saved.CollapsedGroups, group)
+	}
+	sort.Strings(saved.CollapsedGroups)
+
+	if err := m.appState.SetListView(saved); err != nil {
+		return m.handleError(err)
+	}
+	return nil
+}
+
+// handleFilterState handles key events while the user types a filter query. The list updates as they type. Enter
+// keeps the query and escape clears it.
+func (m *home) handleFilterState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	query := m.list.View().Query
+	switch msg.Type {
+	case tea.KeyEnter:
+	case tea.KeyEsc:
+		query = ""
+	case tea.KeyBackspace:
+		if len(query) > 0 {
+			runes := []rune(query)
+			query = string(runes[:len(runes)-1])
+		}
+		m.list.SetQuery(query)
+		return m, m.instanceChanged()
+	case tea.KeyRunes, tea.KeySpace:
+		m.list.SetQuery(query + string(msg.Runes))
+		return m, m.instanceChanged()
+	default:
+		return m, nil
+	}
+
+	m.list.SetQuery(query)
+	m.list.SetFiltering(false)
+	m.state = stateDefault
+	return m, tea.Batch(m.saveListView(), m.instanceChanged())
+}
+
+// openTagEditor shows an overlay to edit the tags of the selected instance.
+func (m *home) openTagEditor() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return m, nil
+	}
+	m.state = stateTags
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Tags (comma separated)", strings.Join(selected.Tags, ", "))
+	return m, tea.WindowSize()
+}
+
+// handleTagsState handles key events while the tag editor is open.
+func (m *home) handleTagsState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.textInputOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if m.textInputOverlay.IsSubmitted() {
+		if selected := m.list.GetSelectedInstance(); selected != nil {
+			selected.Tags = parseTags(m.textInputOverlay.GetValue())
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				cmd = m.handleError(err)
+			}
+		}
+	}
+
+	m.textInputOverlay = nil
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+	return m, tea.Batch(cmd, tea.WindowSize(), m.instanceChanged())
+}
+
+// parseTags splits a comma separated list of tags, dropping empty and duplicate entries.
+func parseTags(value string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"orzbob/session"
+	"orzbob/session/cloud"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// killCloudInstance deletes a cloud instance and removes it from storage. Its branch is kept if it was sent from
+// here.
+func (m *home) killCloudInstance(instance *session.Instance) error {
+	if err := cloud.NewManager().DeleteInstance(m.ctx, instance.CloudInstanceID); err != nil {
+		return err
+	}
+	return m.storage.DeleteInstance(instance.Title)
+}
+
+// cloudAttachCommand runs orz cloud attach for a cloud instance. What it writes to stderr is kept to tell why it
+// failed, since the screen is redrawn once it exits and orz doesn't exit with an error status.
+type cloudAttachCommand struct {
+	*exec.Cmd
+	stderr bytes.Buffer
+}
+
+func (c *cloudAttachCommand) SetStdin(r io.Reader) {
+	c.Stdin = r
+}
+
+func (c *cloudAttachCommand) SetStdout(w io.Writer) {
+	c.Stdout = w
+}
+
+func (c *cloudAttachCommand) SetStderr(w io.Writer) {
+	c.Stderr = io.MultiWriter(w, &c.stderr)
+}
+
+// cloudDetachedMsg is sent when orz cloud attach exits.
+type cloudDetachedMsg struct {
+	err error
+}
+
+// attachCloud suspends the app and attaches to the terminal of a cloud instance over its WebSocket with orz cloud
+// attach. ctrl+c detaches and returns to the app.
+func (m *home) attachCloud(instance *session.Instance) (tea.Model, tea.Cmd) {
+	target := instance.CloudInstanceID
+	if target == "" {
+		target = instance.AttachURL
+	}
+	if target == "" {
+		return m, m.handleError(fmt.Errorf("cloud instance %s can't be attached to, it has no ID", instance.Title))
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return m, m.handleError(err)
+	}
+
+	cmd := &cloudAttachCommand{Cmd: exec.Command(executable, "cloud", "attach", target)}
+	return m, tea.Exec(cmd, func(err error) tea.Msg {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && !exitErr.Exited() {
+			// Detaching with ctrl+c interrupts it.
+			err = nil
+		}
+		for _, line := range strings.Split(cmd.stderr.String(), "\n") {
+			if reason, ok := strings.CutPrefix(line, "Error: "); ok {
+				err = fmt.Errorf("failed to attach to %s: %s", instance.Title, reason)
+				break
+			}
+		}
+		return cloudDetachedMsg{err: err}
+	})
+}
+
+// handleCloudDetached shows why attaching to a cloud instance failed, if it did.
+func (m *home) handleCloudDetached(msg cloudDetachedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, tea.Batch(m.handleError(msg.err), tea.WindowSize())
+	}
+	return m, tea.WindowSize()
+}
+
+// moveSelected sends the selected local instance to the cloud, or brings the selected cloud instance home. The
+// instance keeps its title and history either way.
+func (m *home) moveSelected() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || (!selected.Started() && !selected.IsCloud) {
+		return m, nil
+	}
+	manager := cloud.NewManager()
+	if !manager.IsAuthenticated() {
+		return m, m.handleError(fmt.Errorf("not logged in to orzbob cloud, run 'orz login' first"))
+	}
+
+	var err error
+	if selected.IsCloud {
+		err = manager.BringHome(m.ctx, selected)
+		if errors.Is(err, cloud.ErrNotPushed) {
+			err = fmt.Errorf("%w. Run 'orz cloud home --force %q' to bring it home without it", err, selected.Title)
+		}
+	} else {
+		tier := m.appConfig.CloudTier
+		if tier == "" {
+			tier = cloud.DefaultTier
+		}
+		err = manager.SendToCloud(m.ctx, selected, tier)
+	}
+
+	// Save even if the move failed part way, e.g. when the cloud instance couldn't be deleted after coming home.
+	var cmd tea.Cmd
+	if err != nil {
+		cmd = m.handleError(err)
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		cmd = m.handleError(err)
+	}
+	return m, tea.Batch(cmd, tea.WindowSize(), m.instanceChanged())
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/session"
+	"orzbob/session/git"
+	"orzbob/ui"
+	"orzbob/ui/overlay"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openNewFromPrompt starts creating an instance from just a prompt. The title and branch are derived from the
+// prompt once it's written.
+func (m *home) openNewFromPrompt() (tea.Model, tea.Cmd) {
+	if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m, m.handleError(
+			fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	m.state = stateNewPrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = m.newPromptOverlay("Describe the task for the new instance")
+	return m, tea.WindowSize()
+}
+
+// handleNewPromptState handles key events while the prompt for a new instance is being written. Submitting it
+// suggests a title and branch, which can be edited before the instance starts.
+func (m *home) handleNewPromptState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.textInputOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	if m.textInputOverlay.TemplateRequested {
+		return m.openTemplatePicker()
+	}
+	if m.textInputOverlay.EditorRequested {
+		return m.openEditor()
+	}
+
+	prompt := strings.TrimSpace(m.textInputOverlay.GetValue())
+	submitted := m.textInputOverlay.IsSubmitted()
+	m.textInputOverlay = nil
+	if submitted {
+		m.recordPrompt(prompt)
+	}
+	if !submitted || prompt == "" {
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		return m, tea.WindowSize()
+	}
+
+	title := m.uniqueTitle(git.TitleFromPrompt(prompt, maxTitleLength))
+	branch, err := git.ExpandBranchTemplate(m.appConfig.BranchTemplate, title, git.BranchTemplateUser("."), time.Now())
+	if err == nil {
+		branch = git.UniqueBranchName(".", branch)
+	}
+
+	sparse, sparseErr := git.SparseDefaults(".")
+
+	m.pendingPrompt = prompt
+	m.formOverlay = overlay.NewFormOverlay("New instance",
+		[]string{"Title", "Branch", "Base: branch, tag, commit or #PR (HEAD if empty, the branch itself to continue it)",
+			"Sparse checkout: directories to check out (everything if empty)"},
+		[]string{title, branch, "", strings.Join(sparse, " ")})
+	if err != nil {
+		// Show why there's no suggested branch, but still let the user name it by hand.
+		m.formOverlay.Reject(err)
+	} else if sparseErr != nil {
+		m.formOverlay.Reject(sparseErr)
+	}
+	m.state = stateNewNames
+	return m, tea.WindowSize()
+}
+
+// uniqueTitle returns title, or title with the lowest suffix " 2", " 3", ... which no other instance has. The
+// suffix replaces the end of the title if it would make it too long.
+func (m *home) uniqueTitle(title string) string {
+	taken := make(map[string]bool)
+	for _, instance := range m.list.GetInstances() {
+		taken[instance.Title] = true
+	}
+	unique := title
+	for n := 2; taken[unique]; n++ {
+		suffix := fmt.Sprintf(" %d", n)
+		unique = git.TruncateTitle(title, maxTitleLength-len(suffix)) + suffix
+	}
+	return unique
+}
+
+// handleNewNamesState handles key events while the suggested title and branch of a new instance, the base it starts
+// from and the directories it checks out are being edited. Submitting them starts the instance and sends it the prompt.
+func (m *home) handleNewNamesState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.formOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	if m.formOverlay.Canceled {
+		m.formOverlay = nil
+		m.pendingPrompt = ""
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		return m, tea.WindowSize()
+	}
+
+	title := strings.TrimSpace(m.formOverlay.Value(0))
+	branch := strings.TrimSpace(m.formOverlay.Value(1))
+	base := strings.TrimSpace(m.formOverlay.Value(2))
+	// Naming the branch itself as the base continues it.
+	continueBranch := base != "" && (base == branch || base == "origin/"+branch)
+	if continueBranch {
+		base = ""
+	}
+	if err := m.validateNewNames(title, branch, continueBranch); err != nil {
+		// Keep the form open so the names can be fixed.
+		m.formOverlay.Reject(err)
+		return m, nil
+	}
+	sparse, err := git.ParseSparsePatterns(m.formOverlay.Value(3))
+	if err != nil {
+		m.formOverlay.Reject(err)
+		return m, nil
+	}
+
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:    title,
+		Path:     ".",
+		Program:  m.program,
+		Branch:   branch,
+		Base:     base,
+		Continue: continueBranch,
+		Sparse:   sparse,
+	})
+	if err != nil {
+		m.formOverlay.Reject(err)
+		return m, nil
+	}
+	instance.Prompt = m.pendingPrompt
+	if err := instance.Start(true); err != nil {
+		m.formOverlay.Reject(err)
+		return m, nil
+	}
+
+	m.formOverlay = nil
+	m.pendingPrompt = ""
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+
+	m.list.AddInstance(instance)()
+	m.list.SelectInstance(instance)
+	if m.autoYes {
+		instance.AutoYes = true
+	}
+	var cmd tea.Cmd
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		cmd = m.handleError(err)
+	}
+	return m, tea.Batch(cmd, sendPromptWhenReady(instance), tea.WindowSize(), m.instanceChanged())
+}
+
+// promptSentMsg is sent once the prompt of a new instance was sent to it, or couldn't be.
+type promptSentMsg struct {
+	instance *session.Instance
+	err      error
+}
+
+// sendPromptWhenReady waits in the background until the program of a new instance shows its prompt, so the prompt
+// isn't typed into its start-up screen, and then sends it.
+func sendPromptWhenReady(instance *session.Instance) tea.Cmd {
+	prompt := instance.Prompt
+	return func() tea.Msg {
+		err := instance.WaitReady()
+		if err == nil {
+			err = instance.SendPrompt(prompt)
+		}
+		return promptSentMsg{instance: instance, err: err}
+	}
+}
+
+// handlePromptSent shows why the prompt of a new instance couldn't be sent, unless it was killed or paused while it
+// started.
+func (m *home) handlePromptSent(msg promptSentMsg) (tea.Model, tea.Cmd) {
+	if msg.err == nil || msg.instance.Paused() {
+		return m, nil
+	}
+	for _, instance := range m.list.GetInstances() {
+		if instance == msg.instance {
+			return m, m.handleError(fmt.Errorf("could not send the prompt to %s: %w", instance.Title, msg.err))
+		}
+	}
+	return m, nil
+}
+
+// validateNewNames checks the title and branch chosen for a new instance. The branch has to be new unless it's
+// continued.
+func (m *home) validateNewNames(title, branch string, continueBranch bool) error {
+	if title == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+	if len(title) > maxTitleLength {
+		return fmt.Errorf("title cannot be longer than %d characters", maxTitleLength)
+	}
+	for _, instance := range m.list.GetInstances() {
+		if instance.Title == title {
+			return fmt.Errorf("an instance named %s already exists", title)
+		}
+	}
+	if err := git.ValidateBranchName(branch); err != nil {
+		return err
+	}
+	if !continueBranch && git.BranchExists(".", branch) {
+		return fmt.Errorf("branch %s already exists, set the base to it to continue it", branch)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/keys"
+	"orzbob/session"
+	"orzbob/ui"
+	"orzbob/ui/overlay"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// paletteCommands are the actions listed in the command palette, in the order they're shown before anything is
+// typed. Each one runs the same code as its key.
+var paletteCommands = []struct {
+	name  keys.KeyName
+	title string
+}{
+	{keys.KeyNew, "New instance"},
+	{keys.KeyPrompt, "New instance with prompt"},
+	{keys.KeyPromptNew, "New instance named after its prompt"},
+	{keys.KeySendPrompt, "Send prompt to instance"},
+	{keys.KeyEnter, "Attach to instance"},
+	{keys.KeySubmit, "Push branch"},
+	{keys.KeyCheckout, "Checkout and pause instance"},
+	{keys.KeyResume, "Resume instance"},
+	{keys.KeyKill, "Kill instance"},
+	{keys.KeySearch, "Search output of all instances"},
+	{keys.KeyFilter, "Filter instances"},
+	{keys.KeyFilterMode, "Cycle which instances are shown"},
+	{keys.KeySort, "Cycle sort order"},
+	{keys.KeyCollapse, "Collapse or expand repo group"},
+	{keys.KeyTags, "Edit tags"},
+	{keys.KeyRename, "Rename instance"},
+	{keys.KeyMark, "Mark instance for bulk actions"},
+	{keys.KeyMove, "Send instance to cloud or bring it home"},
+	{keys.KeyKeepAlive, "Keep instance running when idle"},
+	{keys.KeyGrid, "Show grid of previews"},
+	{keys.KeyTab, "Switch between preview and diff"},
+	{keys.KeyHelp, "Help"},
+	{keys.KeyQuit, "Quit"},
+}
+
+// paletteAction is what happens when an item in the command palette is chosen: either an action runs or an
+// instance is selected.
+type paletteAction struct {
+	name     keys.KeyName
+	instance *session.Instance
+}
+
+// openPalette shows the command palette with every action followed by every instance.
+func (m *home) openPalette() (tea.Model, tea.Cmd) {
+	var items []overlay.PaletteItem
+	m.paletteActions = nil
+	for _, command := range paletteCommands {
+		items = append(items, overlay.PaletteItem{Title: command.title, Key: keys.HelpKey(command.name)})
+		m.paletteActions = append(m.paletteActions, paletteAction{name: command.name})
+	}
+	for _, instance := range m.list.GetInstances() {
+		kind := "instance"
+		if instance.Paused() {
+			kind = "paused instance"
+		}
+		items = append(items, overlay.PaletteItem{Title: instance.Title, Kind: kind})
+		m.paletteActions = append(m.paletteActions, paletteAction{instance: instance})
+	}
+
+	m.paletteOverlay = overlay.NewPaletteOverlay(items)
+	m.state = statePalette
+	return m, tea.WindowSize()
+}
+
+// handlePaletteState handles key events when the command palette is open. Choosing an action closes the palette
+// and runs it as if its key was pressed. Choosing an instance selects it.
+func (m *home) handlePaletteState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.paletteOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+
+	var chosen *paletteAction
+	if m.paletteOverlay.Submitted {
+		chosen = &m.paletteActions[m.paletteOverlay.Selected()]
+	}
+	m.paletteOverlay = nil
+	m.paletteActions = nil
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+
+	switch {
+	case chosen == nil:
+		return m, tea.WindowSize()
+	case chosen.instance != nil:
+		if !m.list.SelectInstance(chosen.instance) {
+			return m, m.handleError(fmt.Errorf("%s is hidden by the current filter", chosen.instance.Title))
+		}
+		m.tabbedWindow.ResetPreviewScroll()
+		return m, m.instanceChanged()
+	default:
+		model, cmd := m.runAction(chosen.name)
+		return model, tea.Batch(tea.WindowSize(), cmd)
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/session"
+	"orzbob/ui"
+	"orzbob/ui/overlay"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxTitleLength is the longest title an instance can have.
+const maxTitleLength = 32
+
+// openRename shows an overlay to edit the title of the selected instance.
+func (m *home) openRename() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || !selected.Started() {
+		return m, nil
+	}
+	m.state = stateRename
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay("Rename "+selected.Title, selected.Title)
+	return m, tea.WindowSize()
+}
+
+// handleRenameState handles key events while the rename overlay is open. Submitting renames the instance and
+// everything named after it, then saves the instances.
+func (m *home) handleRenameState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.textInputOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if m.textInputOverlay.IsSubmitted() {
+		if selected := m.list.GetSelectedInstance(); selected != nil {
+			cmd = m.renameInstance(selected, strings.TrimSpace(m.textInputOverlay.GetValue()))
+		}
+	}
+
+	m.textInputOverlay = nil
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+	return m, tea.Batch(cmd, tea.WindowSize(), m.instanceChanged())
+}
+
+// renameInstance checks the new title and renames the instance. It returns an error Cmd if the rename failed.
+func (m *home) renameInstance(instance *session.Instance, title string) tea.Cmd {
+	if title == instance.Title {
+		return nil
+	}
+	if title == "" {
+		return m.handleError(fmt.Errorf("title cannot be empty"))
+	}
+	if len(title) > maxTitleLength {
+		return m.handleError(fmt.Errorf("title cannot be longer than %d characters", maxTitleLength))
+	}
+	for _, other := range m.list.GetInstances() {
+		if other != instance && other.Title == title {
+			return m.handleError(fmt.Errorf("an instance named %s already exists", title))
+		}
+	}
+
+	// Saving is the last step of the rename, so a failed save renames everything back.
+	if err := instance.Rename(title, func() error { return m.storage.SaveInstances(m.list.GetInstances()) }); err != nil {
+		return m.handleError(err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"orzbob/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mouseScrollLines is the number of lines the mouse wheel scrolls the preview in scroll mode.
+const mouseScrollLines = 3
+
+// enterScrollMode captures the history of the selected instance and pins the preview to it so it can be paged
+// through without attaching. A cloud instance's history is recorded from when it was first selected, see
+// connectCloud. The transcript of an imported instance can be read even while it's paused.
+func (m *home) enterScrollMode() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || !selected.HasScrollback() || m.tabbedWindow.IsInDiffTab() {
+		return m, nil
+	}
+	lines, err := selected.ScrollbackLines()
+	if err != nil {
+		return m, m.handleError(err)
+	}
+
+	preview := m.tabbedWindow.Preview()
+	preview.EnterScrollMode(lines)
+	preview.ScrollBy(-1)
+	m.state = stateScroll
+	return m, m.instanceChanged()
+}
+
+// exitScrollMode returns the preview to the live pane content.
+func (m *home) exitScrollMode() (tea.Model, tea.Cmd) {
+	m.tabbedWindow.ResetPreviewScroll()
+	m.state = stateDefault
+	m.menu.SetState(ui.StateDefault)
+	return m, m.instanceChanged()
+}
+
+// handleScrollState handles key events while the preview is in scroll mode.
+func (m *home) handleScrollState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	preview := m.tabbedWindow.Preview()
+
+	if preview.IsSearching() {
+		query := preview.SearchQuery()
+		switch msg.Type {
+		case tea.KeyEnter:
+			preview.EndSearch(false)
+		case tea.KeyEsc:
+			preview.EndSearch(true)
+		case tea.KeyBackspace:
+			if runes := []rune(query); len(runes) > 0 {
+				preview.SetSearchQuery(string(runes[:len(runes)-1]))
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			preview.SetSearchQuery(query + string(msg.Runes))
+		}
+		return m, m.instanceChanged()
+	}
+
+	switch msg.String() {
+	case "esc", "q":
+		return m.exitScrollMode()
+	case "up", "k", "shift+up":
+		preview.ScrollBy(-1)
+	case "down", "j", "shift+down":
+		preview.ScrollBy(1)
+	case "pgup", "ctrl+b":
+		preview.PageBy(-1)
+	case "pgdown", "ctrl+d", " ":
+		preview.PageBy(1)
+	case "home", "g":
+		preview.ScrollToTop()
+	case "end", "G":
+		preview.ScrollToBottom()
+	case "/":
+		preview.StartSearch()
+	case "n":
+		preview.NextMatch(true)
+	case "N":
+		preview.NextMatch(false)
+	case "f":
+		preview.ToggleFollow()
+	default:
+		return m, nil
+	}
+	return m, m.instanceChanged()
+}
+
+// handlePreviewMouse scrolls the preview with the mouse wheel, entering scroll mode when scrolling up.
+func (m *home) handlePreviewMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+	if m.state == stateDefault && msg.Button == tea.MouseButtonWheelUp {
+		return m.enterScrollMode()
+	}
+	if m.state != stateScroll {
+		return m, nil
+	}
+
+	preview := m.tabbedWindow.Preview()
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		preview.ScrollBy(-mouseScrollLines)
+	case tea.MouseButtonWheelDown:
+		preview.ScrollBy(mouseScrollLines)
+	default:
+		return m, nil
+	}
+	return m, m.instanceChanged()
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/ui"
+	"orzbob/ui/overlay"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchContextLines is the number of lines shown above and below each search result.
+const searchContextLines = 1
+
+// openSearch captures the output of every instance and shows the search overlay. The capture happens once so that
+// typing in the overlay only searches in memory.
+func (m *home) openSearch() (tea.Model, tea.Cmd) {
+	scrollbacks, err := session.CaptureScrollback(m.list.GetInstances())
+	if err != nil {
+		// Still search the instances we could capture.
+		log.WarningLog.Printf("could not capture every instance for search: %v", err)
+	}
+	m.scrollbacks = scrollbacks
+	m.searchMatches = nil
+	m.searchOverlay = overlay.NewSearchOverlay(m.runSearch)
+	m.state = stateSearch
+	return m, tea.WindowSize()
+}
+
+// runSearch searches the captured output. The search is case-insensitive unless the query has an upper case letter.
+func (m *home) runSearch(query string) ([]overlay.SearchResult, error) {
+	matches, err := session.Search(m.scrollbacks, session.SearchOptions{
+		Query:      query,
+		IgnoreCase: strings.ToLower(query) == query,
+		Context:    searchContextLines,
+	})
+	m.searchMatches = matches
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]overlay.SearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = overlay.SearchResult{
+			Title:  match.Instance.Title,
+			Line:   match.Line,
+			Text:   match.Text,
+			Before: match.Before,
+			After:  match.After,
+		}
+	}
+	return results, nil
+}
+
+// handleSearchState handles key events when the search overlay is open. Choosing a result selects its instance
+// and opens the preview in scroll mode at the matching line.
+func (m *home) handleSearchState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.searchOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+
+	m.state = stateDefault
+	if m.searchOverlay.Submitted {
+		match := m.searchMatches[m.searchOverlay.Selected()]
+		if m.list.SelectInstance(match.Instance) {
+			for _, sb := range m.scrollbacks {
+				if sb.Instance == match.Instance {
+					// Open the match in scroll mode so the output around it can be read.
+					m.tabbedWindow.ScrollPreviewTo(sb.Lines, match.Line)
+					m.menu.SetInDiffTab(false)
+					m.state = stateScroll
+					break
+				}
+			}
+		}
+	}
+
+	m.searchOverlay = nil
+	m.scrollbacks = nil
+	m.searchMatches = nil
+	m.menu.SetState(ui.StateDefault)
+	return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
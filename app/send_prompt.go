@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openSendPrompt shows an overlay to type a prompt for the selected instance, or for every marked instance if
+// there are any.
+func (m *home) openSendPrompt() (tea.Model, tea.Cmd) {
+	if marked := m.list.MarkedInstances(); len(marked) > 0 && m.state == stateDefault {
+		m.promptReturnState = m.state
+		m.state = stateSendPrompt
+		m.menu.SetState(ui.StatePrompt)
+		m.textInputOverlay = m.newPromptOverlay(fmt.Sprintf("Send prompt to %d marked instances", len(marked)))
+		return m, tea.WindowSize()
+	}
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || !selected.Started() || selected.Paused() {
+		return m, nil
+	}
+	m.promptReturnState = m.state
+	m.state = stateSendPrompt
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = m.newPromptOverlay(fmt.Sprintf("Send prompt to %s", selected.Title))
+	return m, tea.WindowSize()
+}
+
+// handleSendPromptState handles key events while the prompt overlay for an existing instance is open. Closing it
+// returns to the state it was opened from.
+func (m *home) handleSendPromptState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.textInputOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	if m.textInputOverlay.TemplateRequested {
+		return m.openTemplatePicker()
+	}
+	if m.textInputOverlay.EditorRequested {
+		return m.openEditor()
+	}
+	if m.textInputOverlay.IsSubmitted() {
+		m.recordPrompt(m.textInputOverlay.GetValue())
+	}
+
+	if m.textInputOverlay.IsSubmitted() && len(m.list.MarkedInstances()) > 0 && m.promptReturnState == stateDefault {
+		prompt := m.textInputOverlay.GetValue()
+		m.textInputOverlay = nil
+		m.menu.SetState(ui.StateDefault)
+		return m.startBulk(sendPromptAction(prompt))
+	}
+
+	var cmd tea.Cmd
+	if m.textInputOverlay.IsSubmitted() {
+		if selected := m.list.GetSelectedInstance(); selected != nil {
+			if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
+				cmd = m.handleError(err)
+			}
+		}
+	}
+
+	m.textInputOverlay = nil
+	m.state = m.promptReturnState
+	m.menu.SetState(ui.StateDefault)
+	return m, tea.Batch(cmd, tea.WindowSize(), m.instanceChanged())
+}
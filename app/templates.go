@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"orzbob/prompts"
+	"orzbob/session"
+	"orzbob/session/git"
+	"orzbob/ui/overlay"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// promptInstance returns the instance the prompt being written is for, or nil if it's for a new instance which
+// isn't named yet or for the marked instances.
+func (m *home) promptInstance() *session.Instance {
+	switch {
+	case m.state == statePrompt:
+		return m.list.GetSelectedInstance()
+	case m.state == stateSendPrompt && (len(m.list.MarkedInstances()) == 0 || m.promptReturnState != stateDefault):
+		return m.list.GetSelectedInstance()
+	}
+	return nil
+}
+
+// promptContext returns what the builtin variables of templates are filled in with for instance, or for the
+// repository in the current directory if it's nil.
+func promptContext(instance *session.Instance) (prompts.Context, error) {
+	if instance == nil {
+		repoPath, err := git.RepoRoot(".")
+		if err != nil {
+			return prompts.Context{}, err
+		}
+		return prompts.Context{Repo: filepath.Base(repoPath), Dir: repoPath}, nil
+	}
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return prompts.Context{}, err
+	}
+	return prompts.Context{
+		Title:  instance.Title,
+		Branch: instance.Branch,
+		Base:   worktree.GetBaseRef(),
+		Repo:   worktree.GetRepoName(),
+		Dir:    worktree.GetWorktreePath(),
+	}, nil
+}
+
+// openTemplatePicker lists the prompt templates of the user and of the repository the prompt is for, after ctrl+t
+// was pressed in the prompt overlay. The overlay is kept to insert the chosen template into.
+func (m *home) openTemplatePicker() (tea.Model, tea.Cmd) {
+	instance := m.promptInstance()
+	ctx, err := promptContext(instance)
+	if err != nil {
+		m.textInputOverlay.InsertTemplate("")
+		return m, m.handleError(err)
+	}
+	repoPath := ctx.Dir
+	if instance != nil {
+		repoPath = instance.Path
+	}
+	userDir, err := prompts.UserDir()
+	if err != nil {
+		m.textInputOverlay.InsertTemplate("")
+		return m, m.handleError(err)
+	}
+	templates, err := prompts.Load(userDir, repoPath)
+	if err != nil {
+		m.textInputOverlay.InsertTemplate("")
+		return m, m.handleError(err)
+	}
+	if len(templates) == 0 {
+		m.textInputOverlay.InsertTemplate("")
+		return m, m.handleError(fmt.Errorf("there are no prompt templates yet, add <name>.md files to %s or to %s "+
+			"in the repo", userDir, prompts.RepoDir))
+	}
+
+	items := make([]overlay.PaletteItem, len(templates))
+	for i, t := range templates {
+		items[i] = overlay.PaletteItem{Title: t.Name, Kind: t.Description, Key: t.Scope}
+	}
+	m.templates = templates
+	m.templateContext = ctx
+	m.templateReturnState = m.state
+	m.paletteOverlay = overlay.NewPaletteOverlay(items)
+	m.paletteOverlay.SetLabels("Prompt templates", "Type a template name...", "insert")
+	m.state = stateTemplatePicker
+	return m, tea.WindowSize()
+}
+
+// handleTemplatePickerState handles key events while a prompt template is being picked. A template with fields asks
+// for their values next, one without is inserted right away.
+func (m *home) handleTemplatePickerState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.paletteOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	chosen := -1
+	if m.paletteOverlay.Submitted {
+		chosen = m.paletteOverlay.Selected()
+	}
+	m.paletteOverlay = nil
+	if chosen < 0 {
+		return m.closeTemplate("")
+	}
+
+	t := m.templates[chosen]
+	// Every instance has a title, so there's none if the prompt isn't for one instance.
+	if m.templateContext.Title == "" && (t.Uses("Title") || t.Uses("Branch")) {
+		m.closeTemplate("")
+		return m, m.handleError(fmt.Errorf("template %s uses the title or branch, which aren't known for this prompt",
+			t.Name))
+	}
+	m.pickedTemplate = t
+	if len(t.Fields) == 0 {
+		return m.insertTemplate(nil)
+	}
+	labels := make([]string, len(t.Fields))
+	values := make([]string, len(t.Fields))
+	for i, f := range t.Fields {
+		labels[i] = f.Label()
+		values[i] = f.Default
+	}
+	m.formOverlay = overlay.NewFormOverlay("Template "+t.Name, labels, values)
+	m.state = stateTemplateFields
+	return m, tea.WindowSize()
+}
+
+// handleTemplateFieldsState handles key events while the fields of the picked template are being filled in.
+func (m *home) handleTemplateFieldsState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if !m.formOverlay.HandleKeyPress(msg) {
+		return m, nil
+	}
+	if m.formOverlay.Canceled {
+		m.formOverlay = nil
+		return m.closeTemplate("")
+	}
+	values := make(map[string]string, len(m.pickedTemplate.Fields))
+	for i, f := range m.pickedTemplate.Fields {
+		values[f.Name] = strings.TrimSpace(m.formOverlay.Value(i))
+	}
+	return m.insertTemplate(values)
+}
+
+// insertTemplate fills in the picked template and inserts it into the prompt. If that fails, the form of its fields
+// stays open to fix them, or the error is shown if it has none.
+func (m *home) insertTemplate(values map[string]string) (tea.Model, tea.Cmd) {
+	text, err := m.pickedTemplate.Render(m.templateContext, values)
+	if err != nil {
+		if m.formOverlay != nil {
+			m.formOverlay.Reject(err)
+			return m, nil
+		}
+		m.closeTemplate("")
+		return m, m.handleError(err)
+	}
+	m.formOverlay = nil
+	return m.closeTemplate(text)
+}
+
+// closeTemplate goes back to the prompt overlay the template picker was opened from and inserts text into it.
+func (m *home) closeTemplate(text string) (tea.Model, tea.Cmd) {
+	m.textInputOverlay.InsertTemplate(text)
+	m.templates = nil
+	m.pickedTemplate = nil
+	m.state = m.templateReturnState
+	return m, tea.WindowSize()
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"orzbob/config"
+	"orzbob/ui"
+	"orzbob/ui/theme"
+	"path/filepath"
+)
+
+// themesDirName is the directory in the config directory holding custom themes.
+const themesDirName = "themes"
+
+// applyTheme loads the named theme and restyles the UI with it. If the theme can't be loaded, the detected theme is
+// used instead and the error is returned.
+func applyTheme(name string) error {
+	t, err := loadTheme(name)
+	if err != nil {
+		t = theme.Detect()
+	}
+	ui.ApplyTheme(t)
+	setHelpStyles(t)
+	return err
+}
+
+func loadTheme(name string) (*theme.Theme, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return theme.Load(name, filepath.Join(configDir, themesDirName))
+}
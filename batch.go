@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"orzbob/app"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/session/cloud"
+	"orzbob/session/git"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <manifest>",
+	Short: "Start an instance for each task of a manifest or Markdown checklist",
+	Long: `Start an instance for each task in a YAML manifest, at most --concurrency at
+a time. A manifest is a list of tasks, or a mapping with tasks, defaults and
+concurrency. Each task has a title, a prompt, and optionally a program, a base
+to start from (see 'orz new --base'), cloud: true to run it on a cloud runner
+and the tier of the runner. A task without a title is named after its prompt.
+
+A Markdown file (.md) starts a task for each unchecked checklist item, with
+the item and the lines indented below it as the prompt, so a planning doc can
+be fanned out in one command. The flags apply to tasks which don't set them.`,
+	Example: `  orz batch tasks.yaml
+  orz batch plan.md --base main -j 2
+  orz batch plan.md --cloud --tier medium --dry-run
+
+  # tasks.yaml
+  concurrency: 3
+  defaults:
+    program: claude
+    base: main
+  tasks:
+    - title: fix login
+      prompt: Fix the login redirect loop
+    - prompt: Add tests for the billing webhooks
+      cloud: true
+      tier: large`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runBatch,
+}
+
+var (
+	batchConcurrency int
+	batchProgram     string
+	batchBase        string
+	batchCloud       bool
+	batchTier        string
+	batchDryRun      bool
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "j", 4,
+		"How many instances to start at the same time, overriding the manifest's concurrency")
+	batchCmd.Flags().StringVarP(&batchProgram, "program", "p", "", "Program to run (default: default_program)")
+	batchCmd.Flags().StringVar(&batchBase, "base", "", "Branch, tag, commit or pull request to start from (default: HEAD)")
+	batchCmd.Flags().BoolVar(&batchCloud, "cloud", false, "Run all tasks on cloud runners")
+	batchCmd.Flags().StringVarP(&batchTier, "tier", "t", "", "Tier of cloud runners (default: cloud_tier, or small)")
+	batchCmd.Flags().BoolVar(&batchDryRun, "dry-run", false, "Show the instances which would be started")
+}
+
+// batchJob is a task of the batch with the branch its instance works on.
+type batchJob struct {
+	session.BatchTask
+	branch string
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	cfg := config.LoadConfig()
+	batch, err := session.ParseBatch(args[0], data, session.BatchTask{
+		Program: batchProgram,
+		Base:    batchBase,
+		Cloud:   batchCloud,
+		Tier:    batchTier,
+	})
+	if err != nil {
+		return err
+	}
+	concurrency := batchConcurrency
+	if !cmd.Flags().Changed("concurrency") && batch.Concurrency > 0 {
+		concurrency = batch.Concurrency
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+
+	storage, err := session.NewStorage(config.LoadState())
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+	if len(instances)+len(batch.Tasks) > app.GlobalInstanceLimit {
+		return fmt.Errorf("%d more instances would be more than %d", len(batch.Tasks), app.GlobalInstanceLimit)
+	}
+	taken := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		taken[instance.Title] = true
+	}
+	if err := batch.AssignTitles(taken); err != nil {
+		return err
+	}
+
+	jobs, err := batchJobs(batch.Tasks, cfg)
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if jobs[i].Program == "" {
+			jobs[i].Program = cfg.DefaultProgram
+		}
+		if jobs[i].Cloud && jobs[i].Tier == "" {
+			jobs[i].Tier = cfg.CloudTier
+		}
+		if jobs[i].Cloud && jobs[i].Tier == "" {
+			jobs[i].Tier = cloud.DefaultTier
+		}
+	}
+	if batchDryRun {
+		for _, job := range jobs {
+			fmt.Printf("%s  %s\n", job.Title, job.describe())
+		}
+		return nil
+	}
+
+	var manager *cloud.Manager
+	for _, job := range jobs {
+		if job.Cloud {
+			manager = cloud.NewManager()
+			if !manager.IsAuthenticated() {
+				return fmt.Errorf("not logged in, run 'orz login' first")
+			}
+			break
+		}
+	}
+
+	fmt.Printf("🚀 Starting %d instances, %d at a time\n", len(jobs), concurrency)
+	var (
+		mu       sync.Mutex
+		gitMu    sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+		failed   int
+		slots    = make(chan struct{}, concurrency)
+		progress = func(format string, args ...any) {
+			fmt.Printf("[%d/%d] %s\n", done, len(jobs), fmt.Sprintf(format, args...))
+		}
+		finish = func(job batchJob, instance *session.Instance, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if instance != nil {
+				instances = append(instances, instance)
+				if saveErr := storage.SaveInstances(instances); saveErr != nil && err == nil {
+					err = saveErr
+				}
+			}
+			if err != nil {
+				failed++
+				progress("❌ %s: %v", job.Title, err)
+				return
+			}
+			progress("✅ %s  %s", job.Title, job.describe())
+		}
+	)
+	for _, job := range jobs {
+		slots <- struct{}{}
+		// Worktrees are created one at a time, since git locks the repository while it adds one. Only the agents
+		// start concurrently.
+		gitMu.Lock()
+		instance, err := job.setup()
+		gitMu.Unlock()
+		if err != nil {
+			finish(job, nil, err)
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			instance, err := job.start(instance, manager, &gitMu)
+			finish(job, instance, err)
+		}()
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(jobs))
+	}
+	fmt.Printf("✅ Started all %d instances\n", len(jobs))
+	return nil
+}
+
+// batchJobs names the branches of the tasks with the branch_template. Branches which exist or which another task gets
+// get a suffix -2, -3, ...
+func batchJobs(tasks []session.BatchTask, cfg *config.Config) ([]batchJob, error) {
+	user := git.BranchTemplateUser(".")
+	taken := make(map[string]bool, len(tasks))
+	jobs := make([]batchJob, len(tasks))
+	for i, task := range tasks {
+		name, err := git.ExpandBranchTemplate(cfg.BranchTemplate, task.Title, user, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		branch := git.UniqueBranchName(".", name)
+		for n := 2; taken[branch]; n++ {
+			branch = git.UniqueBranchName(".", fmt.Sprintf("%s-%d", name, n))
+		}
+		taken[branch] = true
+		jobs[i] = batchJob{BatchTask: task, branch: branch}
+	}
+	return jobs, nil
+}
+
+// setup creates the worktree of the job and starts its program. A cloud job only gets its branch, since its program
+// runs on the cloud runner.
+func (job batchJob) setup() (*session.Instance, error) {
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   job.Title,
+		Path:    ".",
+		Program: job.Program,
+		Branch:  job.branch,
+		Base:    job.Base,
+	})
+	if err != nil {
+		return nil, err
+	}
+	instance.Prompt = job.Prompt
+	start := func() error { return instance.Start(true) }
+	if job.Cloud {
+		start = instance.StartPaused
+	}
+	if err := start(); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// start sends the prompt to the instance set up for the job once its program is ready. A cloud job's branch is
+// pushed and its instance is sent to a cloud runner like with orz cloud send instead, and the runner gets the prompt. gitLock is held for what
+// changes the repository. The instance is returned if it was started, even if sending the prompt failed.
+func (job batchJob) start(instance *session.Instance, manager *cloud.Manager, gitLock sync.Locker) (*session.Instance,
+	error) {
+	if job.Cloud {
+		err := manager.SendToCloudLocking(context.Background(), instance, cloud.CreateOptions{
+			Tier:   job.Tier,
+			Prompt: job.Prompt,
+		}, gitLock)
+		if err != nil {
+			gitLock.Lock()
+			defer gitLock.Unlock()
+			if killErr := instance.Kill(); killErr != nil {
+				err = fmt.Errorf("%v (cleanup error: %v)", err, killErr)
+			}
+			return nil, err
+		}
+		return instance, nil
+	}
+	if job.Prompt != "" {
+		if err := instance.WaitReady(); err != nil {
+			return instance, err
+		}
+		if err := instance.SendPrompt(job.Prompt); err != nil {
+			return instance, err
+		}
+	}
+	return instance, nil
+}
+
+// describe returns where the job runs, its branch, what it's based on and the start of its prompt.
+func (job batchJob) describe() string {
+	parts := []string{job.Program}
+	if job.Cloud {
+		parts = []string{fmt.Sprintf("%s in the cloud (%s)", job.Program, job.Tier)}
+	}
+	parts = append(parts, "branch "+job.branch)
+	if job.Base != "" {
+		parts = append(parts, "from "+job.Base)
+	}
+	if prompt, _, _ := strings.Cut(job.Prompt, "\n"); prompt != "" {
+		if runes := []rune(prompt); len(runes) > 60 {
+			prompt = string(runes[:57]) + "..."
+		}
+		parts = append(parts, fmt.Sprintf("%q", prompt))
+	}
+	return strings.Join(parts, ", ")
+}
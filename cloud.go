@@ -617,27 +617,10 @@ func init() {
 
 // attachToInstance connects to a cloud instance via WebSocket
 func attachToInstance(urlStr string) error {
-	// Parse URL to extract token
-	parsedURL, err := url.Parse(urlStr)
+	wsURL, token, err := tunnel.ParseAttachURL(urlStr)
 	if err != nil {
-		return fmt.Errorf("invalid attach URL: %w", err)
-	}
-
-	// Extract token from query params
-	token := parsedURL.Query().Get("token")
-	if token == "" {
-		return fmt.Errorf("no token found in attach URL")
-	}
-
-	// Convert to WebSocket URL
-	wsScheme := "ws"
-	if parsedURL.Scheme == "https" {
-		wsScheme = "wss"
+		return err
 	}
-	parsedURL.Scheme = wsScheme
-	parsedURL.RawQuery = "" // Remove query params from WebSocket URL
-
-	wsURL := parsedURL.String()
 
 	// Create WebSocket client with JWT token
 	client, err := tunnel.NewClientWithToken(wsURL, token)
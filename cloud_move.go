@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/session/cloud"
+
+	"github.com/spf13/cobra"
+)
+
+var cloudSendCmd = &cobra.Command{
+	Use:   "send <title>",
+	Short: "Move a local instance to a cloud runner",
+	Long: `Commit and push the instance's branch, then start a cloud runner with the
+same program on that branch. The local session and worktree are removed but
+the instance keeps its title, and can be brought back with 'orz cloud home'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tier, _ := cmd.Flags().GetString("tier")
+		return moveInstance(args[0], func(ctx context.Context, m *cloud.Manager, instance *session.Instance) error {
+			if tier == "" {
+				tier = config.LoadConfig().CloudTier
+			}
+			if tier == "" {
+				tier = cloud.DefaultTier
+			}
+			if err := m.SendToCloud(ctx, instance, tier); err != nil {
+				return err
+			}
+			fmt.Printf("✅ %s is now running in cloud instance %s\n", instance.Title, instance.CloudInstanceID)
+			return nil
+		})
+	},
+}
+
+var cloudHomeCmd = &cobra.Command{
+	Use:   "home <title>",
+	Short: "Bring an instance back from a cloud runner",
+	Long: `Have the cloud runner commit and push its work, fast-forward the instance's
+branch to what it pushed, resume the instance in a local worktree and delete
+the cloud runner. The instance stays in the cloud if its work can't be pushed,
+unless --force brings it home with only what it pushed before, losing the rest.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return moveInstance(args[0], func(ctx context.Context, m *cloud.Manager, instance *session.Instance) error {
+			bringHome := m.BringHome
+			if force {
+				bringHome = m.BringHomeUnpushed
+			}
+			if err := bringHome(ctx, instance); err != nil {
+				if errors.Is(err, cloud.ErrNotPushed) {
+					return fmt.Errorf("%w. Push it from 'orz cloud attach', or bring it home without it with --force", err)
+				}
+				return err
+			}
+			fmt.Printf("✅ %s is back home on branch %s\n", instance.Title, instance.Branch)
+			return nil
+		})
+	},
+}
+
+func init() {
+	cloudCmd.AddCommand(cloudSendCmd)
+	cloudCmd.AddCommand(cloudHomeCmd)
+	cloudSendCmd.Flags().StringP("tier", "t", "", "Instance tier (small, medium, large)")
+	cloudHomeCmd.Flags().Bool("force", false, "Bring it home even if its work can't be pushed, losing what wasn't")
+}
+
+// moveInstance runs move on the stored instance with the given title and saves the instances afterwards.
+func moveInstance(title string, move func(context.Context, *cloud.Manager, *session.Instance) error) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	manager := cloud.NewManager()
+	if !manager.IsAuthenticated() {
+		return fmt.Errorf("not logged in, run 'orz login' first")
+	}
+
+	storage, err := session.NewStorage(config.LoadState())
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+	for _, instance := range instances {
+		if instance.Title != title {
+			continue
+		}
+		// Save even if the move failed part way, e.g. when the cloud runner couldn't be deleted after coming home.
+		moveErr := move(context.Background(), manager, instance)
+		if err := storage.SaveInstances(instances); err != nil {
+			return err
+		}
+		return moveErr
+	}
+	return fmt.Errorf("instance not found: %s", title)
+}
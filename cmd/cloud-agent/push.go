@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// handlePush commits what the program changed and pushes the branch, so the work outlives the runner when it's
+// brought home and deleted. It answers with the pushed commit, or with why the work couldn't be pushed.
+func handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	commit, err := pushWork()
+	if err != nil {
+		log.Printf("Failed to push work: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Pushed work as %s", commit)
+	_ = json.NewEncoder(w).Encode(map[string]string{"commit": commit})
+}
+
+// pushWork commits all changes in the repository and pushes them to the branch the runner was started on. It returns
+// the pushed commit.
+func pushWork() (string, error) {
+	branch := os.Getenv("BRANCH")
+	if branch == "" {
+		return "", fmt.Errorf("no BRANCH set, so there's no branch to push to")
+	}
+
+	if _, err := runGit("add", "-A"); err != nil {
+		return "", err
+	}
+	status, err := runGit("status", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	if status != "" {
+		message := fmt.Sprintf("[orzbob] update from cloud instance %s on %s", os.Getenv("INSTANCE_ID"),
+			time.Now().Format(time.RFC822))
+		var env []string
+		if _, err := runGit("config", "user.email"); err != nil {
+			// Runners have no git identity unless the cloud config sets one.
+			env = []string{"GIT_AUTHOR_NAME=Orzbob Cloud", "GIT_AUTHOR_EMAIL=cloud@orzbob.com",
+				"GIT_COMMITTER_NAME=Orzbob Cloud", "GIT_COMMITTER_EMAIL=cloud@orzbob.com"}
+		}
+		if _, err := runGitWithEnv(env, "commit", "-q", "-m", message); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := runGit("push", "-q", "origin", "HEAD:refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return runGit("rev-parse", "HEAD")
+}
+
+// runGit runs git in the repository and returns its trimmed output.
+func runGit(args ...string) (string, error) {
+	return runGitWithEnv(nil, args...)
+}
+
+// runGitWithEnv runs git like runGit, with env added to the environment.
+func runGitWithEnv(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
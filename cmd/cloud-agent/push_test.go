@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestPushWork(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// The runner has no git identity, so the agent brings its own.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	origin, workspace := filepath.Join(dir, "origin.git"), filepath.Join(dir, "workspace")
+	git(t, "init", "-q", "--bare", origin)
+	git(t, "-c", "user.name=test", "-c", "user.email=test@example.com", "init", "-q", "-b", "main", workspace)
+	git(t, "-C", workspace, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "--allow-empty", "-m", "initial")
+	git(t, "-C", workspace, "remote", "add", "origin", origin)
+	if err := os.WriteFile(filepath.Join(workspace, "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workspace); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	t.Setenv("BRANCH", "")
+	if _, err := pushWork(); err == nil {
+		t.Error("work was pushed without a branch to push to")
+	}
+
+	t.Setenv("BRANCH", "session/fix")
+	commit, err := pushWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed := git(t, "--git-dir", origin, "rev-parse", "session/fix"); pushed != commit {
+		t.Errorf("origin has session/fix at %s, want %s", pushed, commit)
+	}
+	if files := git(t, "--git-dir", origin, "ls-tree", "--name-only", "session/fix"); files != "fix.txt" {
+		t.Errorf("the pushed commit has %q, want fix.txt", files)
+	}
+
+	// Nothing new is pushed as it is.
+	again, err := pushWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != commit {
+		t.Errorf("pushing again made commit %s, want %s", again, commit)
+	}
+}
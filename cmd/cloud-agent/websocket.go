@@ -19,9 +19,10 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// startWebSocketServer starts a WebSocket server for tmux attachment
+// startWebSocketServer starts a WebSocket server for tmux attachment, which also pushes the work on request
 func startWebSocketServer() error {
 	http.HandleFunc("/attach", handleWebSocketAttach)
+	http.HandleFunc("/push", handlePush)
 
 	port := os.Getenv("WS_PORT")
 	if port == "" {
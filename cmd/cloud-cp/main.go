@@ -69,6 +69,8 @@ type Server struct {
 	heartbeatMu  sync.RWMutex
 	tokenManager *auth.TokenManager
 	baseURL      string
+	// minClientVersion is the oldest orz which may use the control plane. orz updates itself when it's older.
+	minClientVersion string
 
 	// Quota tracking: orgID -> instance count
 	instanceCounts map[string]int
@@ -108,6 +110,7 @@ func NewServer(p provider.Provider) *Server {
 		freeQuota:      3, // Free tier allows 3 instances for testing
 		instanceStarts: make(map[string]time.Time),
 	}
+	s.minClientVersion = os.Getenv("MIN_CLIENT_VERSION")
 
 	// Initialize billing if configured
 	billingConfig := billing.LoadConfigOptional()
@@ -256,6 +259,7 @@ func (s *Server) setupRoutes() {
 		r.Delete("/instances/{id}", s.handleDeleteInstance)
 		r.Get("/instances", s.handleListInstances)
 		r.Post("/instances/{id}/heartbeat", s.handleHeartbeat)
+		r.Post("/instances/{id}/push", s.handlePushInstance)
 
 		// Secrets management
 		r.Post("/secrets", s.handleCreateSecret)
@@ -274,10 +278,14 @@ func (s *Server) setupRoutes() {
 // handleHealth handles health check requests
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
+	response := map[string]string{
 		"status":  "healthy",
 		"version": version,
-	})
+	}
+	if s.minClientVersion != "" {
+		response["minimum_client_version"] = s.minClientVersion
+	}
+	_ = json.NewEncoder(w).Encode(response)
 }
 
 // handleCreateInstance handles instance creation requests
@@ -468,6 +476,29 @@ func (s *Server) handleDeleteInstance(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handlePushInstance has the runner commit its work and push its branch, so it isn't lost when the instance is
+// deleted
+func (s *Server) handlePushInstance(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, err := s.provider.GetInstance(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, "Instance not found")
+		return
+	}
+
+	commit, err := s.provider.PushBranch(r.Context(), id)
+	if err != nil {
+		log.Printf("Failed to push the work of instance %s: %v", id, err)
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to push the work of the instance: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"commit": commit,
+	})
+}
+
 // handleListInstances handles list instances requests
 func (s *Server) handleListInstances(w http.ResponseWriter, r *http.Request) {
 	instances, err := s.provider.ListInstances(r.Context())
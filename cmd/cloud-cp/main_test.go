@@ -427,3 +427,54 @@ func TestInstanceCreationWithJWT(t *testing.T) {
 		t.Errorf("Token expiry time off by %v", diff)
 	}
 }
+
+func TestHealthAdvertisesMinimumClientVersion(t *testing.T) {
+	t.Setenv("MIN_CLIENT_VERSION", "1.4.0")
+	server := NewServer(provider.NewFakeProvider())
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	var health map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&health); err != nil {
+		t.Fatalf("Failed to decode health response: %v", err)
+	}
+	if health["minimum_client_version"] != "1.4.0" {
+		t.Errorf("Expected minimum client version 1.4.0, got %q", health["minimum_client_version"])
+	}
+}
+
+func TestPushEndpoint(t *testing.T) {
+	fakeProvider := provider.NewFakeProvider()
+	server := NewServer(fakeProvider)
+	instance, err := fakeProvider.CreateInstance(context.Background(), "small")
+	if err != nil {
+		t.Fatalf("Failed to create instance: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/instances/"+instance.ID+"/push", nil)
+	addTestAuth(req, server)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode push response: %v", err)
+	}
+	if resp["commit"] != "fake-commit-"+instance.ID {
+		t.Errorf("Expected the pushed commit, got %q", resp["commit"])
+	}
+
+	req = httptest.NewRequest("POST", "/v1/instances/non-existent/push", nil)
+	addTestAuth(req, server)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for non-existent instance, got %d", rr.Code)
+	}
+}
@@ -34,6 +34,27 @@ type Config struct {
 	AutoInstallUpdates bool `json:"auto_install_updates"`
 	// LastUpdateCheck is the timestamp of the last update check
 	LastUpdateCheck int64 `json:"last_update_check"`
+	// MinimumVersion is the oldest version the control plane supported at the last update check. It's enforced on
+	// every start, not only when updates are checked for.
+	MinimumVersion string `json:"minimum_version,omitempty"`
+	// UpdateChannel is where updates come from: "stable" (the default), "beta" or "nightly".
+	UpdateChannel string `json:"update_channel,omitempty"`
+	// PinnedVersion keeps orz at this version. Updates install it instead of the latest release.
+	PinnedVersion string `json:"pinned_version,omitempty"`
+	// Theme is the name of the TUI theme: "auto" (the default) picks dark or light from the terminal background,
+	// "dark", "light", "high-contrast" and "no-color" are built in, and other names are loaded from
+	// themes/<name>.json in the config directory.
+	Theme string `json:"theme,omitempty"`
+	// BranchTemplate is how branches of instances created from a prompt are named. {slug} is the title, {user}
+	// the git user name and {date} today's date, for example "{user}/{date}-{slug}". It defaults to "session/{slug}".
+	BranchTemplate string `json:"branch_template,omitempty"`
+	// AutoPauseHours pauses local instances which have been ready without output for this many hours. Zero, the
+	// default, never pauses them.
+	AutoPauseHours int `json:"auto_pause_hours,omitempty"`
+	// CloudTier is the tier of the cloud instance an instance is sent to: small (the default), medium or large.
+	CloudTier string `json:"cloud_tier,omitempty"`
+	// KeyBindings overrides the keys bound to actions, for example {"kill": ["d"], "detach": ["ctrl+b"]}.
+	KeyBindings map[string][]string `json:"key_bindings,omitempty"`
 }
 
 // DefaultConfig returns the default configuration
@@ -29,6 +29,10 @@ type AppState interface {
 	GetHelpScreensSeen() uint32
 	// SetHelpScreensSeen updates the bitmask of seen help screens
 	SetHelpScreensSeen(seen uint32) error
+	// GetListView returns how the instance list is filtered, sorted and grouped
+	GetListView() ListView
+	// SetListView updates how the instance list is filtered, sorted and grouped
+	SetListView(view ListView) error
 }
 
 // StateManager combines instance storage and app state management
@@ -37,10 +41,24 @@ type StateManager interface {
 	AppState
 }
 
+// ListView holds the filter, sort and grouping preferences of the instance list
+type ListView struct {
+	// SortMode is the order of the instances. Empty means creation order.
+	SortMode string `json:"sort_mode,omitempty"`
+	// FilterMode restricts which instances are shown. Empty means all of them.
+	FilterMode string `json:"filter_mode,omitempty"`
+	// Query filters instances by title or tag.
+	Query string `json:"query,omitempty"`
+	// CollapsedGroups are the repo groups which are collapsed.
+	CollapsedGroups []string `json:"collapsed_groups,omitempty"`
+}
+
 // State represents the application state that persists between sessions
 type State struct {
 	// HelpScreensSeen is a bitmask tracking which help screens have been shown
 	HelpScreensSeen uint32 `json:"help_screens_seen"`
+	// ListView is how the instance list is filtered, sorted and grouped
+	ListView ListView `json:"list_view"`
 	// Instances stores the serialized instance data as raw JSON
 	InstancesData json.RawMessage `json:"instances"`
 }
@@ -137,3 +155,14 @@ func (s *State) SetHelpScreensSeen(seen uint32) error {
 	s.HelpScreensSeen = seen
 	return SaveState(s)
 }
+
+// GetListView returns how the instance list is filtered, sorted and grouped
+func (s *State) GetListView() ListView {
+	return s.ListView
+}
+
+// SetListView updates how the instance list is filtered, sorted and grouped
+func (s *State) SetListView(view ListView) error {
+	s.ListView = view
+	return SaveState(s)
+}
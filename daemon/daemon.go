@@ -52,7 +52,7 @@ func RunDaemon(cfg *config.Config) error {
 						instance.TapEnter()
 						if err := instance.UpdateDiffStats(); err != nil {
 							if everyN.ShouldLog() {
-								log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
+								log.ForInstance(instance.Title).Warn("could not update diff stats", "error", err)
 							}
 						}
 					}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"orzbob/doctor"
+	"orzbob/log"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that orz's tools, files and cloud connection are healthy",
+	Long: `Check the versions of git, tmux and gh, that the tmux server responds, that the
+config and state files parse, that the worktree directory is writable, what
+instances left behind, and that the cloud token is valid and the control plane
+can be reached. Each problem comes with a suggested fix.
+
+With --fix, problems which can be repaired without losing work are repaired:
+broken config and state files are moved aside, permissions are corrected,
+orphaned tmux sessions are killed and stale worktree entries are pruned.
+Orphaned worktrees and branches are left to orz gc.`,
+	Args: cobra.NoArgs,
+	// Failures are reported above the error, so the usage would only bury them.
+	SilenceUsage: true,
+	RunE:         runDoctor,
+}
+
+var doctorFix bool
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair the problems which can be repaired safely")
+}
+
+var doctorIcons = map[doctor.Status]string{
+	doctor.OK:      "✓",
+	doctor.Warning: "!",
+	doctor.Failure: "✗",
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	var warnings, failures, repairable int
+	for _, result := range doctor.Run(cmd.Context(), doctor.Checks()) {
+		fmt.Printf("%s %s: %s\n", doctorIcons[result.Status], result.Name, result.Message)
+		if result.Status == doctor.OK {
+			continue
+		}
+		if doctorFix && result.Repair != nil {
+			if err := result.Repair(); err != nil {
+				fmt.Printf("    repair failed: %v\n", err)
+			} else {
+				fmt.Println("    repaired")
+				continue
+			}
+		}
+		if result.Fix != "" {
+			fmt.Printf("    fix: %s\n", result.Fix)
+		}
+		if result.Repair != nil && !doctorFix {
+			repairable++
+		}
+		if result.Status == doctor.Warning {
+			warnings++
+		} else {
+			failures++
+		}
+	}
+
+	fmt.Println()
+	if warnings == 0 && failures == 0 {
+		fmt.Println("Everything looks good")
+		return nil
+	}
+	fmt.Printf("%d failures, %d warnings\n", failures, warnings)
+	if repairable > 0 {
+		fmt.Printf("Run orz doctor --fix to repair %d of them\n", repairable)
+	}
+	if failures > 0 {
+		return fmt.Errorf("orz doctor found %d failures", failures)
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orzbob/config"
+	"orzbob/keys"
+	"orzbob/session"
+	"orzbob/session/cloud"
+	"orzbob/session/git"
+	"orzbob/session/tmux"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// commandTimeout is how long a tool or the control plane gets to answer before it's considered hung.
+	commandTimeout = 5 * time.Second
+	// tokenExpiryWarning is how long before the cloud token expires to start warning about it.
+	tokenExpiryWarning = 72 * time.Hour
+)
+
+// tool is a program orz runs.
+type tool struct {
+	name        string
+	versionFlag string
+	// min is the oldest version which works, if any.
+	min      string
+	required bool
+	install  string
+}
+
+var tools = []tool{
+	// git worktree remove, which orz uses to pause instances, was added in 2.17.
+	{name: "git", versionFlag: "--version", min: "2.17", required: true,
+		install: "install git 2.17 or newer"},
+	{name: "tmux", versionFlag: "-V", min: "2.6", required: true,
+		install: "install tmux 2.6 or newer, e.g. with `brew install tmux` or `apt install tmux`"},
+	{name: "gh", versionFlag: "--version",
+		install: "install the GitHub CLI to open pull requests on GitHub with orz pr: https://cli.github.com"},
+}
+
+// CheckTools checks that git, tmux and gh are installed in versions which work, that gh is logged in, and that
+// the default program can be found.
+func CheckTools(ctx context.Context) []Result {
+	var results []Result
+	for _, t := range tools {
+		results = append(results, checkTool(ctx, t))
+	}
+	if _, err := exec.LookPath("gh"); err == nil {
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+		if err := exec.CommandContext(ctx, "gh", "auth", "status").Run(); err != nil {
+			results = append(results, Result{Name: "gh auth", Status: Warning,
+				Message: "gh isn't logged in, so pull requests can't be opened on GitHub", Fix: "run `gh auth login`"})
+		} else {
+			results = append(results, Result{Name: "gh auth", Status: OK, Message: "logged in"})
+		}
+	}
+
+	cfg := config.LoadConfig()
+	if fields := strings.Fields(cfg.DefaultProgram); len(fields) > 0 {
+		if path, err := exec.LookPath(fields[0]); err != nil {
+			results = append(results, Result{Name: "default program", Status: Warning,
+				Message: fmt.Sprintf("%s isn't installed", fields[0]),
+				Fix:     "install it, or set default_program in the config file (see `orz debug`)"})
+		} else {
+			results = append(results, Result{Name: "default program", Status: OK, Message: path})
+		}
+	}
+	return results
+}
+
+func checkTool(ctx context.Context, t tool) Result {
+	status := Warning
+	if t.required {
+		status = Failure
+	}
+	if _, err := exec.LookPath(t.name); err != nil {
+		return Result{Name: t.name, Status: status, Message: "not installed", Fix: t.install}
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, t.name, t.versionFlag).CombinedOutput()
+	if err != nil {
+		return Result{Name: t.name, Status: status, Message: fmt.Sprintf("%s %s failed: %v", t.name, t.versionFlag, err),
+			Fix: t.install}
+	}
+	version, ok := parseVersion(string(output))
+	if !ok {
+		return Result{Name: t.name, Status: Warning,
+			Message: fmt.Sprintf("couldn't tell the version from %q", strings.TrimSpace(string(output)))}
+	}
+	if t.min != "" && !versionAtLeast(version, t.min) {
+		return Result{Name: t.name, Status: status, Message: fmt.Sprintf("version %s is too old", version),
+			Fix: t.install}
+	}
+	return Result{Name: t.name, Status: OK, Message: "version " + version}
+}
+
+// CheckTmuxServer checks that the tmux server answers, if one is running.
+func CheckTmuxServer(ctx context.Context) []Result {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		// CheckTools already reports it.
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, "tmux", "list-sessions", "-F", "#{session_name}")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return []Result{{Name: "tmux server", Status: Failure, Message: "the tmux server doesn't respond",
+			Fix: "restart it with `tmux kill-server`, which ends every tmux session"}}
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if strings.HasPrefix(message, "no server running") || strings.Contains(message, "No such file or directory") {
+			return []Result{{Name: "tmux server", Status: OK, Message: "not running, it starts with the first instance"}}
+		}
+		return []Result{{Name: "tmux server", Status: Failure, Message: message,
+			Fix: "check that the tmux socket directory (TMUX_TMPDIR or /tmp) belongs to you"}}
+	}
+	var total, ours int
+	for _, name := range strings.Fields(string(output)) {
+		total++
+		if strings.HasPrefix(name, tmux.TmuxPrefix) {
+			ours++
+		}
+	}
+	return []Result{{Name: "tmux server", Status: OK,
+		Message: fmt.Sprintf("running (sessions: %d, from orz: %d)", total, ours)}}
+}
+
+// CheckConfig checks that the config and state files can be parsed. Broken files are moved aside by the repair,
+// since orz would otherwise run on defaults and overwrite the state, losing the instances.
+func CheckConfig(ctx context.Context) []Result {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return []Result{errorResult("config", err)}
+	}
+	var results []Result
+
+	configPath := filepath.Join(dir, config.ConfigFileName)
+	var cfg config.Config
+	result := checkJSONFile(config.ConfigFileName, configPath, &cfg)
+	if result.Status == OK && len(cfg.KeyBindings) > 0 {
+		if err := keys.ApplyOverrides(cfg.KeyBindings); err != nil {
+			result = Result{Name: config.ConfigFileName, Status: Failure,
+				Message: fmt.Sprintf("key_bindings are invalid: %v", err),
+				Fix:     "correct key_bindings in " + configPath}
+		}
+	}
+	results = append(results, result)
+
+	statePath := filepath.Join(dir, config.StateFileName)
+	var state config.State
+	result = checkJSONFile(config.StateFileName, statePath, &state)
+	if result.Status == OK && len(state.InstancesData) > 0 {
+		var instances []session.InstanceData
+		if err := json.Unmarshal(state.InstancesData, &instances); err != nil {
+			result = brokenFileResult(config.StateFileName, statePath, fmt.Errorf("the instances are invalid: %w", err))
+		} else {
+			result.Message = fmt.Sprintf("%d instances", len(instances))
+		}
+	}
+	return append(results, result)
+}
+
+// checkJSONFile checks that the file at path parses into v. A missing file is fine, orz creates it.
+func checkJSONFile(name, path string, v interface{}) Result {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Result{Name: name, Status: OK, Message: "not created yet"}
+	}
+	if err != nil {
+		return Result{Name: name, Status: Failure, Message: err.Error(), Fix: "check the permissions of " + path}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return brokenFileResult(name, path, err)
+	}
+	return Result{Name: name, Status: OK, Message: path}
+}
+
+func brokenFileResult(name, path string, err error) Result {
+	broken := path + ".broken"
+	return Result{
+		Name:    name,
+		Status:  Failure,
+		Message: fmt.Sprintf("can't be parsed, so orz ignores it: %v", err),
+		Fix:     fmt.Sprintf("correct %s, or move it to %s to start over", path, broken),
+		Repair: func() error {
+			return os.Rename(path, broken)
+		},
+	}
+}
+
+// CheckWorktreeDirectory checks that the worktrees of instances can be created.
+func CheckWorktreeDirectory(ctx context.Context) []Result {
+	const name = "worktree directory"
+	dir, err := git.WorktreeDirectory()
+	if err != nil {
+		return []Result{errorResult(name, err)}
+	}
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return []Result{{Name: name, Status: OK, Message: dir + " will be created with the first instance"}}
+	}
+	if err != nil {
+		return []Result{{Name: name, Status: Failure, Message: err.Error(),
+			Fix: "check the permissions of " + filepath.Dir(dir)}}
+	}
+	if !info.IsDir() {
+		return []Result{{Name: name, Status: Failure, Message: dir + " isn't a directory",
+			Fix: "move " + dir + " out of the way"}}
+	}
+	file, err := os.CreateTemp(dir, ".doctor-")
+	if err != nil {
+		return []Result{{
+			Name:    name,
+			Status:  Failure,
+			Message: fmt.Sprintf("%s isn't writable: %v", dir, err),
+			Fix:     "run `chmod u+rwx " + dir + "`",
+			Repair: func() error {
+				return os.Chmod(dir, info.Mode().Perm()|0700)
+			},
+		}}
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return []Result{{Name: name, Status: OK, Message: dir}}
+}
+
+// CheckOrphans looks for what instances left behind. Orphaned tmux sessions are killed and stale worktree entries
+// pruned by the repair. Worktrees and branches may hold work, so they're left to orz gc.
+func CheckOrphans(ctx context.Context) []Result {
+	const name = "orphans"
+	instances, err := loadInstanceData()
+	if err != nil {
+		// Every session would look orphaned, so don't risk killing any.
+		return []Result{{Name: name, Status: Warning, Message: "skipped until the state file is fixed"}}
+	}
+	orphans, err := session.FindOrphans(instances, []string{"."})
+	if err != nil {
+		return []Result{errorResult(name, err)}
+	}
+	if len(orphans) == 0 {
+		return []Result{{Name: name, Status: OK, Message: "none found"}}
+	}
+
+	byKind := make(map[session.OrphanKind][]session.Orphan)
+	for _, orphan := range orphans {
+		byKind[orphan.Kind] = append(byKind[orphan.Kind], orphan)
+	}
+	var results []Result
+	kinds := []struct {
+		kind session.OrphanKind
+		name string
+	}{
+		{session.OrphanSession, "orphaned tmux sessions"},
+		{session.OrphanStaleEntry, "stale worktree entries"},
+		{session.OrphanWorktree, "orphaned worktrees"},
+		{session.OrphanBranch, "orphaned branches"},
+	}
+	for _, k := range kinds {
+		kind := k.kind
+		found := byKind[kind]
+		if len(found) == 0 {
+			continue
+		}
+		names := make([]string, len(found))
+		for i, orphan := range found {
+			names[i] = orphan.Name
+		}
+		result := Result{
+			Name:    k.name,
+			Status:  Warning,
+			Message: fmt.Sprintf("%d no instance uses: %s", len(found), strings.Join(names, ", ")),
+			Fix:     "review and remove them with `orz gc`",
+		}
+		if kind == session.OrphanSession || kind == session.OrphanStaleEntry {
+			result.Repair = func() error {
+				var errs []error
+				for _, orphan := range found {
+					if err := orphan.Remove(); err != nil {
+						errs = append(errs, err)
+					}
+				}
+				return errors.Join(errs...)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// loadInstanceData reads the stored instances without starting them, unlike session.Storage.
+func loadInstanceData() ([]session.InstanceData, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, config.StateFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state config.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	var instances []session.InstanceData
+	if len(state.InstancesData) > 0 {
+		if err := json.Unmarshal(state.InstancesData, &instances); err != nil {
+			return nil, err
+		}
+	}
+	return instances, nil
+}
+
+// CheckCloud checks the token saved by orz login and that the control plane can be reached and accepts it.
+func CheckCloud(ctx context.Context) []Result {
+	m := cloud.NewManager()
+	var results []Result
+
+	loggedIn := false
+	expiry, err := m.TokenExpiry()
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		results = append(results, Result{Name: "cloud token", Status: OK,
+			Message: "not logged in, which is only needed for cloud instances"})
+	case err != nil:
+		results = append(results, Result{Name: "cloud token", Status: Failure, Message: err.Error(),
+			Fix: "run `orz login` again"})
+	case time.Now().After(expiry):
+		results = append(results, Result{Name: "cloud token", Status: Failure,
+			Message: "expired on " + expiry.Format(time.RFC1123), Fix: "run `orz login` again"})
+	case time.Until(expiry) < tokenExpiryWarning:
+		loggedIn = true
+		results = append(results, Result{Name: "cloud token", Status: Warning,
+			Message: "expires in " + time.Until(expiry).Round(time.Minute).String(), Fix: "run `orz login` again"})
+	default:
+		loggedIn = true
+		results = append(results, Result{Name: "cloud token", Status: OK,
+			Message: "valid until " + expiry.Format(time.RFC1123)})
+	}
+
+	path := m.TokenPath()
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+		results = append(results, Result{
+			Name:    "cloud token",
+			Status:  Warning,
+			Message: path + " can be read by other users",
+			Fix:     "run `chmod 600 " + path + "`",
+			Repair: func() error {
+				return os.Chmod(path, 0600)
+			},
+		})
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	if err := m.Ping(pingCtx); err != nil {
+		status := Warning
+		if loggedIn {
+			status = Failure
+		}
+		return append(results, Result{Name: "control plane", Status: status, Message: err.Error(),
+			Fix: "check your network connection and ORZBOB_API_URL"})
+	}
+	results = append(results, Result{Name: "control plane", Status: OK, Message: m.APIURL() + " is reachable"})
+
+	if loggedIn {
+		validateCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+		if err := m.ValidateToken(validateCtx); errors.Is(err, cloud.ErrUnauthorized) {
+			results = append(results, Result{Name: "cloud token", Status: Failure, Message: err.Error(),
+				Fix: "run `orz login` again"})
+		} else if err != nil {
+			results = append(results, Result{Name: "cloud token", Status: Warning,
+				Message: fmt.Sprintf("couldn't be checked: %v", err)})
+		} else {
+			results = append(results, Result{Name: "cloud token", Status: OK, Message: "accepted by the control plane"})
+		}
+	}
+	return results
+}
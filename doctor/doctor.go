@@ -0,0 +1,99 @@
+// Package doctor checks that everything orz depends on is installed and healthy, and repairs what it safely can.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Status is how healthy the thing a result is about is.
+type Status int
+
+const (
+	// OK means nothing needs to be done.
+	OK Status = iota
+	// Warning means orz works, but some features may not.
+	Warning
+	// Failure means orz won't work properly until it's fixed.
+	Failure
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warning:
+		return "warning"
+	}
+	return "failure"
+}
+
+// Result is what a check found out about one thing.
+type Result struct {
+	// Name is what was checked, e.g. "tmux".
+	Name   string
+	Status Status
+	// Message says what was found.
+	Message string
+	// Fix tells the user how to fix a problem.
+	Fix string
+	// Repair fixes the problem, for problems which can be fixed without losing any work. It's run by orz doctor --fix.
+	Repair func() error
+}
+
+// Check checks one area, like the installed tools, and returns a result for each thing in it.
+type Check func(ctx context.Context) []Result
+
+// Checks returns all checks in the order they should be run.
+func Checks() []Check {
+	return []Check{
+		CheckTools,
+		CheckTmuxServer,
+		CheckConfig,
+		CheckWorktreeDirectory,
+		CheckOrphans,
+		CheckCloud,
+	}
+}
+
+// Run runs the checks and returns their results.
+func Run(ctx context.Context, checks []Check) []Result {
+	var results []Result
+	for _, check := range checks {
+		results = append(results, check(ctx)...)
+	}
+	return results
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// parseVersion finds a version like 2.39.5 or 3.3a in the output of a --version flag.
+func parseVersion(output string) (string, bool) {
+	version := versionPattern.FindString(output)
+	return version, version != ""
+}
+
+// versionAtLeast reports whether version is at least min. Both are dot separated numbers.
+func versionAtLeast(version, min string) bool {
+	have := strings.Split(version, ".")
+	want := strings.Split(min, ".")
+	for i := range want {
+		if i >= len(have) {
+			return false
+		}
+		a, _ := strconv.Atoi(have[i])
+		b, _ := strconv.Atoi(want[i])
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+// errorResult is the result of a check which couldn't be done at all.
+func errorResult(name string, err error) Result {
+	return Result{Name: name, Status: Failure, Message: fmt.Sprintf("couldn't check: %v", err)}
+}
@@ -0,0 +1,173 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orzbob/log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	tests := []struct {
+		output  string
+		version string
+		min     string
+		ok      bool
+	}{
+		{"git version 2.39.5", "2.39.5", "2.17", true},
+		{"git version 2.17.0.windows.1", "2.17.0", "2.17", true},
+		{"git version 2.9.1", "2.9.1", "2.17", false},
+		{"tmux 3.3a", "3.3", "2.6", true},
+		{"tmux 2.1", "2.1", "2.6", false},
+		{"tmux next-3.4", "3.4", "2.6", true},
+		{"gh version 2.40.1 (2023-12-13)", "2.40.1", "2", true},
+	}
+	for _, tt := range tests {
+		version, found := parseVersion(tt.output)
+		if !found || version != tt.version {
+			t.Errorf("parseVersion(%q) = %q, %v, want %q", tt.output, version, found, tt.version)
+			continue
+		}
+		if got := versionAtLeast(version, tt.min); got != tt.ok {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", version, tt.min, got, tt.ok)
+		}
+	}
+}
+
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+	return home
+}
+
+func TestCheckConfig(t *testing.T) {
+	home := setupHome(t)
+	dir := filepath.Join(home, ".orzbob")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(statePath, []byte(`{"instances": {"title": "not a list"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"),
+		[]byte(`{"key_bindings": {"no_such_action": ["x"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := CheckConfig(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Status != Failure || results[0].Repair != nil {
+		t.Errorf("invalid key bindings gave %+v, want a failure without a repair", results[0])
+	}
+	state := results[1]
+	if state.Status != Failure || state.Repair == nil {
+		t.Fatalf("invalid instances gave %+v, want a repairable failure", state)
+	}
+	if err := state.Repair(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(statePath + ".broken"); err != nil {
+		t.Errorf("the state file wasn't moved aside: %v", err)
+	}
+	if got := CheckConfig(context.Background())[1]; got.Status != OK {
+		t.Errorf("after the repair the state gave %+v, want OK", got)
+	}
+}
+
+func TestCheckCloud(t *testing.T) {
+	tests := []struct {
+		name    string
+		expires time.Duration
+		mode    os.FileMode
+		// accepted is whether the control plane accepts the token.
+		accepted bool
+		want     []Status
+	}{
+		{"valid", 30 * 24 * time.Hour, 0600, true, []Status{OK, OK, OK}},
+		{"expiring soon", time.Hour, 0600, true, []Status{Warning, OK, OK}},
+		{"expired", -time.Hour, 0600, true, []Status{Failure, OK}},
+		{"rejected", 30 * 24 * time.Hour, 0600, false, []Status{OK, OK, Failure}},
+		{"readable by others", 30 * 24 * time.Hour, 0644, true, []Status{OK, Warning, OK, OK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := setupHome(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/health":
+					w.WriteHeader(http.StatusOK)
+				case "/v1/instances":
+					if !tt.accepted {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(`{"instances": []}`))
+				}
+			}))
+			defer server.Close()
+			t.Setenv("ORZBOB_API_URL", server.URL)
+
+			tokenPath := filepath.Join(home, ".config", "orzbob", "token.json")
+			if err := os.MkdirAll(filepath.Dir(tokenPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			token, _ := json.Marshal(map[string]any{"api_token": "token", "expires_at": time.Now().Add(tt.expires)})
+			if err := os.WriteFile(tokenPath, token, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(tokenPath, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+
+			results := CheckCloud(context.Background())
+			var got []Status
+			for _, result := range results {
+				got = append(got, result.Status)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want statuses %v", results, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %+v, want statuses %v", results, tt.want)
+				}
+			}
+
+			for _, result := range results {
+				if result.Repair != nil {
+					if err := result.Repair(); err != nil {
+						t.Fatal(err)
+					}
+					info, err := os.Stat(tokenPath)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if info.Mode().Perm() != 0600 {
+						t.Errorf("token mode is %v after the repair, want 0600", info.Mode().Perm())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCloudNotLoggedIn(t *testing.T) {
+	setupHome(t)
+	t.Setenv("ORZBOB_API_URL", "http://127.0.0.1:1")
+
+	results := CheckCloud(context.Background())
+	if len(results) != 2 || results[0].Status != OK || results[1].Status != Warning {
+		t.Errorf("got %+v, want an OK token and an unreachable control plane as a warning", results)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"orzbob/app"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <title>",
+	Short: "Export an instance to a file a teammate can import",
+	Long: `Write an instance to a single archive so it can be handed to someone else.
+
+The archive holds the instance's settings and prompt, a git bundle of its
+branch since the commit it started from, and the output of the session if
+it's running. Uncommitted changes are committed first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import an instance exported with orz export",
+	Long: `Recreate an exported instance in the repository in the current directory.
+
+The branch is created from the archive and the instance is added paused, so
+resuming it sets up the worktree and starts the program. The repository
+needs the commit the branch started from; fetch it first if it's missing.
+The session's output from before the export is kept as the instance's
+transcript, which is shown when scrolling back through its output.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+var (
+	exportOutput string
+	importTitle  string
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "",
+		"File to write the export to (default: <title>.orz.tar.gz)")
+	importCmd.Flags().StringVar(&importTitle, "title", "", "Import the instance under another title")
+}
+
+// exportFileName returns the default export file name for an instance title.
+func exportFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, title)
+	return name + ".orz.tar.gz"
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	state := config.LoadState()
+	storage, err := session.NewStorage(state)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	var instance *session.Instance
+	for _, i := range instances {
+		if i.Title == args[0] {
+			instance = i
+			break
+		}
+	}
+	if instance == nil {
+		return fmt.Errorf("instance not found: %s", args[0])
+	}
+
+	output := exportOutput
+	if output == "" {
+		output = exportFileName(instance.Title)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := instance.Export(f); err != nil {
+		f.Close()
+		os.Remove(output)
+		return fmt.Errorf("failed to export %s: %w", instance.Title, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %s to %s\n", instance.Title, output)
+	return nil
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	archive, err := session.ReadExport(f)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	if importTitle != "" {
+		archive.Data.Title = importTitle
+	}
+
+	state := config.LoadState()
+	storage, err := session.NewStorage(state)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+	if len(instances) >= app.GlobalInstanceLimit {
+		return fmt.Errorf("you can't have more than %d instances", app.GlobalInstanceLimit)
+	}
+	for _, i := range instances {
+		if i.Title == archive.Data.Title {
+			return fmt.Errorf("an instance named %s already exists, use --title to import it under another name",
+				archive.Data.Title)
+		}
+	}
+
+	instance, err := archive.Import(".")
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", archive.Data.Title, err)
+	}
+	if archive.Transcript != "" {
+		if instance.Transcript, err = saveTranscript(instance.Title, archive.Transcript); err != nil {
+			return errors.Join(err, discardImport(instance))
+		}
+	}
+	if err := storage.SaveInstances(append(instances, instance)); err != nil {
+		return errors.Join(err, discardImport(instance))
+	}
+	fmt.Printf("Imported %s as a paused instance on branch %s\n", instance.Title, archive.Data.Worktree.BranchName)
+	if instance.Transcript != "" {
+		fmt.Println("Its output from before it was exported is shown above its own when scrolling back in orz.")
+	}
+	return nil
+}
+
+// saveTranscript saves the transcript of an imported instance in session.TranscriptDir and returns its path.
+func saveTranscript(title, transcript string) (string, error) {
+	dir, err := session.TranscriptDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, strings.TrimSuffix(exportFileName(title), ".orz.tar.gz")+".txt")
+	if err := os.WriteFile(path, []byte(transcript), 0644); err != nil {
+		return "", fmt.Errorf("failed to save the transcript: %w", err)
+	}
+	return path, nil
+}
+
+// discardImport removes the branch, worktree and transcript of an imported instance which couldn't be saved.
+func discardImport(instance *session.Instance) error {
+	var errs []error
+	if worktree, err := instance.GetGitWorktree(); err == nil {
+		if err := worktree.Cleanup(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove the imported branch: %w", err))
+		}
+	}
+	if instance.Transcript != "" {
+		if err := os.Remove(instance.Transcript); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
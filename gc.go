@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Show disk usage and clean up what instances left behind",
+	Long: `Report the disk usage of each instance's worktree and find orphans: worktree
+directories, orz branches and tmux sessions that no instance uses, and stale
+git worktree entries. Branches are looked for in the repositories of the
+instances and in the current one.
+
+Each orphan is removed after asking, unless --yes or --dry-run is given. The
+--worktrees, --branches, --sessions and --stale flags limit the cleanup to
+those kinds of orphans.`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+var (
+	gcDryRun    bool
+	gcYes       bool
+	gcWorktrees bool
+	gcBranches  bool
+	gcSessions  bool
+	gcStale     bool
+)
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().BoolVarP(&gcDryRun, "dry-run", "n", false, "Only report, don't remove anything")
+	gcCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "Remove orphans without asking")
+	gcCmd.Flags().BoolVar(&gcWorktrees, "worktrees", false, "Clean up orphaned worktree directories")
+	gcCmd.Flags().BoolVar(&gcBranches, "branches", false, "Clean up orphaned branches")
+	gcCmd.Flags().BoolVar(&gcSessions, "sessions", false, "Clean up orphaned tmux sessions")
+	gcCmd.Flags().BoolVar(&gcStale, "stale", false, "Prune stale git worktree entries")
+}
+
+// gcKinds returns the kinds of orphans to clean up, as chosen with flags. No flags means all of them.
+func gcKinds() map[session.OrphanKind]bool {
+	kinds := map[session.OrphanKind]bool{
+		session.OrphanWorktree:   gcWorktrees,
+		session.OrphanBranch:     gcBranches,
+		session.OrphanSession:    gcSessions,
+		session.OrphanStaleEntry: gcStale,
+	}
+	for _, chosen := range kinds {
+		if chosen {
+			return kinds
+		}
+	}
+	for kind := range kinds {
+		kinds[kind] = true
+	}
+	return kinds
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	storage, err := session.NewStorage(config.LoadState())
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+	garbage, err := session.FindGarbage(instances, []string{"."})
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tSIZE\tWORKTREE")
+	for _, usage := range garbage.Usage {
+		path := usage.Path
+		if usage.Instance.IsCloud {
+			path = "(none while in the cloud)"
+		} else if usage.Instance.Paused() {
+			path = "(none while paused)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", usage.Instance.Title, session.FormatSize(usage.Size), path)
+		total += usage.Size
+	}
+	fmt.Fprintf(w, "total\t%s\t\n", session.FormatSize(total))
+	w.Flush()
+
+	kinds := gcKinds()
+	var orphans []session.Orphan
+	for _, orphan := range garbage.Orphans {
+		if kinds[orphan.Kind] {
+			orphans = append(orphans, orphan)
+		}
+	}
+	if len(orphans) == 0 {
+		fmt.Println("\nNo orphans found")
+		return nil
+	}
+	fmt.Printf("\nFound %d orphans:\n", len(orphans))
+	for _, orphan := range orphans {
+		fmt.Printf("  %s\n", orphan)
+	}
+	if gcDryRun {
+		return nil
+	}
+
+	fmt.Println()
+	reader := bufio.NewReader(os.Stdin)
+	removeAll := gcYes
+	var failed int
+	for _, orphan := range orphans {
+		if !removeAll {
+			fmt.Printf("Remove %s? [y/N/a(ll)/q(uit)] ", orphan)
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+			case "a", "all":
+				removeAll = true
+			case "q", "quit":
+				return nil
+			default:
+				continue
+			}
+		}
+		if err := orphan.Remove(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove %s: %v\n", orphan, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Removed %s\n", orphan)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d orphans couldn't be removed", failed)
+	}
+	return nil
+}
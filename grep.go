@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern>",
+	Short: "Search the output of all instances",
+	Long: `Search the scrollback of every running instance for a pattern, and the
+transcripts of imported instances, including those which were deleted since.
+
+Matches are printed as <instance>:<line>: <text>, with context lines
+printed as <instance>-<line>- <text>, similar to grep. Matches in the
+transcript of a deleted instance are named after the transcript's file.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGrep,
+}
+
+var (
+	grepIgnoreCase bool
+	grepRegexp     bool
+	grepContext    int
+)
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "Ignore case distinctions")
+	grepCmd.Flags().BoolVarP(&grepRegexp, "regexp", "E", false, "Treat the pattern as a regular expression")
+	grepCmd.Flags().IntVarP(&grepContext, "context", "C", 0, "Print this many lines of context around each match")
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	state := config.LoadState()
+	storage, err := session.NewStorage(state)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	scrollbacks, err := session.CaptureScrollback(instances)
+	if err != nil {
+		// Report instances we couldn't read but still search the rest.
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	dir, err := session.TranscriptDir()
+	if err != nil {
+		return err
+	}
+	transcripts, err := session.CaptureTranscripts(dir, instances)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	scrollbacks = append(scrollbacks, transcripts...)
+
+	matches, err := session.Search(scrollbacks, session.SearchOptions{
+		Query:      args[0],
+		Regexp:     grepRegexp,
+		IgnoreCase: grepIgnoreCase,
+		Context:    grepContext,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, match := range matches {
+		if grepContext > 0 && i > 0 {
+			fmt.Println("--")
+		}
+		for j, line := range match.Before {
+			fmt.Printf("%s-%d- %s\n", match.Source(), match.Line-len(match.Before)+j+1, line)
+		}
+		fmt.Printf("%s:%d: %s\n", match.Source(), match.Line+1, match.Text)
+		for j, line := range match.After {
+			fmt.Printf("%s-%d- %s\n", match.Source(), match.Line+j+2, line)
+		}
+	}
+
+	if len(matches) == 0 {
+		fmt.Fprintln(os.Stderr, "No matches found")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build tools
+// +build tools
+
+// sign-release writes the signature of a release's checksums file which orz update verifies. The base64 ed25519
+// private key is read from ORZBOB_RELEASE_SIGNING_KEY. With -generate, it prints a new key pair instead; the public
+// half goes in update/release_key.pub. With -check, it reports whether the key in ORZBOB_RELEASE_SIGNING_KEY is the
+// one in update/release_key.pub. docs/release-signing.md explains who holds the key and how to rotate it.
+//
+//	go run -tags tools ./hack/sign-release build/checksums.txt
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// releaseKeyPath is the public key orz update verifies releases with, relative to the root of the repository.
+const releaseKeyPath = "update/release_key.pub"
+
+func main() {
+	generate := flag.Bool("generate", false, "Print a new key pair")
+	check := flag.Bool("check", false, "Check that the signing key matches update/release_key.pub")
+	flag.Parse()
+
+	if *generate {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("public:  %s\n", base64.StdEncoding.EncodeToString(public))
+		fmt.Printf("private: %s\n", base64.StdEncoding.EncodeToString(private))
+		return
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(os.Getenv("ORZBOB_RELEASE_SIGNING_KEY")))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		log.Fatal("ORZBOB_RELEASE_SIGNING_KEY must hold a base64 ed25519 private key")
+	}
+	public := base64.StdEncoding.EncodeToString(ed25519.PrivateKey(key).Public().(ed25519.PublicKey))
+
+	if *check {
+		released, err := os.ReadFile(releaseKeyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("signing key: %s\n", public)
+		if strings.TrimSpace(string(released)) != public {
+			log.Fatalf("%s holds %s, releases signed with this key won't verify", releaseKeyPath,
+				strings.TrimSpace(string(released)))
+		}
+		fmt.Printf("matches %s\n", releaseKeyPath)
+		return
+	}
+
+	if flag.NArg() != 1 {
+		log.Fatal("usage: sign-release <checksums file>")
+	}
+	path := flag.Arg(0)
+	checksums, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), checksums))
+	if err := os.WriteFile(path+".sig", []byte(signature+"\n"), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("signed %s with %s\n", path, public)
+}
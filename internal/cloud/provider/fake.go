@@ -101,6 +101,18 @@ func (f *FakeProvider) GetAttachURL(ctx context.Context, id string) (string, err
 	return fmt.Sprintf("ws://localhost:8080/v1/instances/%s/attach", id), nil
 }
 
+// PushBranch pretends the runner pushed its work
+func (f *FakeProvider) PushBranch(ctx context.Context, id string) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if _, exists := f.instances[id]; !exists {
+		return "", fmt.Errorf("instance not found: %s", id)
+	}
+
+	return "fake-commit-" + id, nil
+}
+
 // CreateSecret creates a fake secret
 func (f *FakeProvider) CreateSecret(ctx context.Context, name string, data map[string]string) (*Secret, error) {
 	f.mu.Lock()
@@ -2,8 +2,10 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -198,6 +200,43 @@ func (k *LocalKind) GetAttachURL(ctx context.Context, id string) (string, error)
 	return fmt.Sprintf("ws://localhost:8080/attach/%s", id), nil
 }
 
+// agentPort is the port the cloud agent of a runner pod serves attachments and pushes on.
+const agentPort = 8081
+
+// PushBranch asks the cloud agent of the pod to commit its work and push its branch
+func (k *LocalKind) PushBranch(ctx context.Context, id string) (string, error) {
+	pod, err := k.clientset.CoreV1().Pods(k.namespace).Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod: %w", err)
+	}
+	if pod.Status.PodIP == "" {
+		return "", fmt.Errorf("pod %s has no IP yet", id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("http://%s:%d/push", pod.Status.PodIP, agentPort), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the agent of pod %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Commit string `json:"commit"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode the agent's response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the agent couldn't push: %s", result.Error)
+	}
+	return result.Commit, nil
+}
+
 // CreateSecret creates a Kubernetes secret
 func (k *LocalKind) CreateSecret(ctx context.Context, name string, data map[string]string) (*Secret, error) {
 	// Convert string data to byte data
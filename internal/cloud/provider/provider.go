@@ -34,6 +34,8 @@ type Provider interface {
 	ListInstances(ctx context.Context) ([]*Instance, error)
 	DeleteInstance(ctx context.Context, id string) error
 	GetAttachURL(ctx context.Context, id string) (string, error)
+	// PushBranch has the runner commit its work and push its branch, and returns the pushed commit.
+	PushBranch(ctx context.Context, id string) (string, error)
 
 	// Secret management
 	CreateSecret(ctx context.Context, name string, data map[string]string) (*Secret, error)
@@ -0,0 +1,83 @@
+package tunnel
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// ParseAttachURL splits the attach URL of a cloud instance into the WebSocket URL to dial and the token to dial it
+// with.
+func ParseAttachURL(attachURL string) (wsURL, token string, err error) {
+	parsedURL, err := url.Parse(attachURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid attach URL: %w", err)
+	}
+
+	token = parsedURL.Query().Get("token")
+	if token == "" {
+		return "", "", fmt.Errorf("no token found in attach URL")
+	}
+
+	wsScheme := "ws"
+	if parsedURL.Scheme == "https" {
+		wsScheme = "wss"
+	}
+	parsedURL.Scheme = wsScheme
+	parsedURL.RawQuery = ""
+	return parsedURL.String(), token, nil
+}
+
+// Stream is a WebSocket connection to the terminal of a cloud instance. Reading returns the terminal's output and
+// writing sends it input.
+type Stream struct {
+	client  *Client
+	pending []byte
+	mu      sync.Mutex
+}
+
+// DialStream connects to the terminal of a cloud instance at its attach URL.
+func DialStream(attachURL string) (*Stream, error) {
+	wsURL, token, err := ParseAttachURL(attachURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClientWithToken(wsURL, token)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{client: client}, nil
+}
+
+// Read reads the output of the terminal, one message at a time.
+func (s *Stream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		messageType, data, err := s.client.conn.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		if messageType == websocket.TextMessage || messageType == websocket.BinaryMessage {
+			s.pending = data
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Write sends input to the terminal.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.client.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection. The cloud instance keeps running.
+func (s *Stream) Close() error {
+	return s.client.Close()
+}
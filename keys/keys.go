@@ -25,13 +25,30 @@ const (
 	KeyPrompt // New key for entering a prompt
 	KeyHelp   // Key for showing help screen
 	KeyCloud  // Key for creating cloud instance
+	KeySearch // Key for searching the output of all instances
+
+	// List organization keybindings
+	KeyFilter     // Key for filtering instances by title or tag
+	KeyFilterMode // Key for cycling which instances are shown
+	KeySort       // Key for cycling the sort order
+	KeyCollapse   // Key for collapsing or expanding a repo group
+	KeyTags       // Key for editing the tags of an instance
+
+	KeyGrid       // Key for showing the grid of previews
+	KeySendPrompt // Key for sending a prompt to an instance
+	KeyPalette    // Key for opening the command palette
+	KeyRename     // Key for renaming an instance
+	KeyPromptNew  // Key for creating an instance named after its prompt
+	KeyMark       // Key for marking an instance for bulk actions
+	KeyMove       // Key for sending an instance to the cloud or bringing it home
+	KeyKeepAlive  // Key for opting an instance out of being paused when idle
 
 	// Diff keybindings
 	KeyShiftUp
 	KeyShiftDown
 )
 
-// GlobalKeyStringsMap is a global, immutable map string to keybinding.
+// GlobalKeyStringsMap is a global map of key string to keybinding. It's only changed at startup by ApplyOverrides.
 var GlobalKeyStringsMap = map[string]KeyName{
 	"up":         KeyUp,
 	"k":          KeyUp,
@@ -51,9 +68,23 @@ var GlobalKeyStringsMap = map[string]KeyName{
 	"r":          KeyResume,
 	"p":          KeySubmit,
 	"?":          KeyHelp,
+	"ctrl+f":     KeySearch,
+	"/":          KeyFilter,
+	"f":          KeyFilterMode,
+	"s":          KeySort,
+	"z":          KeyCollapse,
+	"t":          KeyTags,
+	"g":          KeyGrid,
+	"i":          KeySendPrompt,
+	"ctrl+p":     KeyPalette,
+	"R":          KeyRename,
+	"P":          KeyPromptNew,
+	" ":          KeyMark,
+	"m":          KeyMove,
+	"K":          KeyKeepAlive,
 }
 
-// GlobalkeyBindings is a global, immutable map of KeyName tot keybinding.
+// GlobalkeyBindings is a global map of KeyName to keybinding. It's only changed at startup by ApplyOverrides.
 var GlobalkeyBindings = map[KeyName]key.Binding{
 	KeyUp: key.NewBinding(
 		key.WithKeys("up", "k"),
@@ -115,6 +146,62 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("C"),
 		key.WithHelp("C", "cloud"),
 	),
+	KeySearch: key.NewBinding(
+		key.WithKeys("ctrl+f"),
+		key.WithHelp("ctrl+f", "search"),
+	),
+	KeyFilter: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "filter"),
+	),
+	KeyFilterMode: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "show"),
+	),
+	KeySort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort"),
+	),
+	KeyCollapse: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "collapse group"),
+	),
+	KeyTags: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "tags"),
+	),
+	KeyGrid: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "grid"),
+	),
+	KeySendPrompt: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "send prompt"),
+	),
+	KeyPalette: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "commands"),
+	),
+	KeyRename: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "rename"),
+	),
+	KeyPromptNew: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "new from prompt"),
+	),
+	KeyMark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark"),
+	),
+	KeyMove: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "cloud/home"),
+	),
+	KeyKeepAlive: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "keep alive"),
+	),
 
 	// -- Special keybindings --
 
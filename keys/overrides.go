@@ -0,0 +1,194 @@
+package keys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// DetachAction is the name of the key which detaches from an attached session in the key_bindings config.
+const DetachAction = "detach"
+
+// DefaultDetachKey is the key which detaches from an attached session unless it's overridden.
+const DefaultDetachKey = "ctrl+q"
+
+// DetachKey is the effective key which detaches from an attached session.
+var DetachKey = DefaultDetachKey
+
+// reservedKeys are handled by the app before key bindings are looked up, so they can't be bound to an action.
+var reservedKeys = map[string]bool{
+	"ctrl+c": true,
+	"esc":    true,
+}
+
+// actionNames are the names used for each action in the key_bindings section of the config. Actions which aren't
+// listed here can't be rebound.
+var actionNames = map[KeyName]string{
+	KeyUp:         "up",
+	KeyDown:       "down",
+	KeyShiftUp:    "scroll_up",
+	KeyShiftDown:  "scroll_down",
+	KeyEnter:      "open",
+	KeyNew:        "new",
+	KeyPrompt:     "new_with_prompt",
+	KeyKill:       "kill",
+	KeyQuit:       "quit",
+	KeyTab:        "switch_tab",
+	KeyCheckout:   "checkout",
+	KeyResume:     "resume",
+	KeySubmit:     "push",
+	KeyHelp:       "help",
+	KeyCloud:      "cloud",
+	KeySearch:     "search",
+	KeyFilter:     "filter",
+	KeyFilterMode: "show",
+	KeySort:       "sort",
+	KeyCollapse:   "collapse",
+	KeyTags:       "tags",
+	KeyGrid:       "grid",
+	KeySendPrompt: "send_prompt",
+	KeyPalette:    "palette",
+	KeyRename:     "rename",
+	KeyPromptNew:  "new_from_prompt",
+	KeyMark:       "mark",
+	KeyMove:       "move",
+	KeyKeepAlive:  "keep_alive",
+}
+
+// ActionName returns the name of the action in the key_bindings config, or an empty string if it can't be rebound.
+func ActionName(name KeyName) string {
+	return actionNames[name]
+}
+
+// HelpKey returns the effective key(s) for an action as shown in the help screen and menu.
+func HelpKey(name KeyName) string {
+	return GlobalkeyBindings[name].Help().Key
+}
+
+// keySymbols are the short forms used when showing keys in help text.
+var keySymbols = map[string]string{
+	"up":         "↑",
+	"down":       "↓",
+	"left":       "←",
+	"right":      "→",
+	"enter":      "↵",
+	"shift+up":   "shift+↑",
+	"shift+down": "shift+↓",
+	" ":          "space",
+}
+
+func helpText(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if symbol, ok := keySymbols[k]; ok {
+			k = symbol
+		}
+		parts[i] = k
+	}
+	return strings.Join(parts, "/")
+}
+
+// ControlByte returns the byte a terminal sends for a ctrl+<letter> key. Keys which terminals can't tell apart from
+// other keys, such as ctrl+i (tab) and ctrl+m (enter), are rejected.
+func ControlByte(k string) (byte, error) {
+	letter, ok := strings.CutPrefix(k, "ctrl+")
+	if !ok || len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' {
+		return 0, fmt.Errorf("%q is not a ctrl+<letter> key", k)
+	}
+	switch letter {
+	case "c", "h", "i", "j", "m":
+		return 0, fmt.Errorf("%q can't be used since terminals send it for another key", k)
+	}
+	return letter[0] - 'a' + 1, nil
+}
+
+// ApplyOverrides rebinds the actions named in overrides to the given keys. Overrides are validated before anything
+// changes: unknown actions, empty bindings, reserved keys and keys bound to more than one action are all reported
+// in the returned error, and the defaults are kept.
+func ApplyOverrides(overrides map[string][]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	byAction := make(map[string]KeyName, len(actionNames))
+	for name, action := range actionNames {
+		byAction[action] = name
+	}
+
+	var problems []string
+	detachKey := DetachKey
+	bound := make(map[KeyName][]string)
+	for action, keys := range overrides {
+		if action == DetachAction {
+			if len(keys) != 1 {
+				problems = append(problems, fmt.Sprintf("%s must have exactly one key", action))
+				continue
+			}
+			if _, err := ControlByte(keys[0]); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", action, err))
+				continue
+			}
+			detachKey = keys[0]
+			continue
+		}
+
+		name, ok := byAction[action]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown action %q", action))
+			continue
+		}
+		if len(keys) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no keys", action))
+			continue
+		}
+		bound[name] = keys
+	}
+
+	// Work out the effective keys of every action and check that no key triggers two of them.
+	effective := make(map[KeyName][]string, len(actionNames))
+	for name := range actionNames {
+		if keys, ok := bound[name]; ok {
+			effective[name] = keys
+		} else {
+			effective[name] = GlobalkeyBindings[name].Keys()
+		}
+	}
+	owners := make(map[string]KeyName)
+	for name, keys := range effective {
+		for _, k := range keys {
+			if reservedKeys[k] {
+				problems = append(problems, fmt.Sprintf("%s: %q is reserved", actionNames[name], k))
+				continue
+			}
+			if other, ok := owners[k]; ok && other != name {
+				problems = append(problems,
+					fmt.Sprintf("%q is bound to both %s and %s", k, actionNames[other], actionNames[name]))
+				continue
+			}
+			owners[k] = name
+		}
+	}
+
+	if len(problems) > 0 {
+		// Map iteration order is random, so sort to keep the message stable.
+		sort.Strings(problems)
+		return fmt.Errorf("invalid key bindings, using defaults: %s", strings.Join(problems, "; "))
+	}
+
+	stringsMap := make(map[string]KeyName, len(owners))
+	for k, name := range owners {
+		stringsMap[k] = name
+	}
+	for name, keys := range bound {
+		binding := GlobalkeyBindings[name]
+		GlobalkeyBindings[name] = key.NewBinding(
+			key.WithKeys(keys...),
+			key.WithHelp(helpText(keys), binding.Help().Desc),
+		)
+	}
+	GlobalKeyStringsMap = stringsMap
+	DetachKey = detachKey
+	return nil
+}
@@ -0,0 +1,118 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// restoreBindings puts the global bindings back after a test applies overrides.
+func restoreBindings(t *testing.T) {
+	stringsMap := make(map[string]KeyName, len(GlobalKeyStringsMap))
+	for k, v := range GlobalKeyStringsMap {
+		stringsMap[k] = v
+	}
+	bindings := make(map[KeyName]key.Binding, len(GlobalkeyBindings))
+	for k, v := range GlobalkeyBindings {
+		bindings[k] = v
+	}
+	detach := DetachKey
+	t.Cleanup(func() {
+		GlobalKeyStringsMap = stringsMap
+		GlobalkeyBindings = bindings
+		DetachKey = detach
+	})
+}
+
+func TestApplyOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		wantErr   string
+	}{
+		{
+			name:      "unknown action",
+			overrides: map[string][]string{"explode": {"x"}},
+			wantErr:   `unknown action "explode"`,
+		},
+		{
+			name:      "conflict with a default",
+			overrides: map[string][]string{"kill": {"n"}},
+			wantErr:   `"n" is bound to both`,
+		},
+		{
+			name:      "reserved key",
+			overrides: map[string][]string{"quit": {"esc"}},
+			wantErr:   `"esc" is reserved`,
+		},
+		{
+			name:      "empty binding",
+			overrides: map[string][]string{"kill": {}},
+			wantErr:   "kill has no keys",
+		},
+		{
+			name:      "detach must be a control key",
+			overrides: map[string][]string{"detach": {"x"}},
+			wantErr:   "not a ctrl+<letter> key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreBindings(t)
+			err := ApplyOverrides(tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ApplyOverrides() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			// Nothing should change when the overrides are invalid.
+			if GlobalKeyStringsMap["D"] != KeyKill || DetachKey != DefaultDetachKey {
+				t.Error("invalid overrides should keep the default bindings")
+			}
+		})
+	}
+}
+
+func TestApplyOverridesRebinds(t *testing.T) {
+	restoreBindings(t)
+
+	// Swapping two keys is only valid if both are rebound together.
+	err := ApplyOverrides(map[string][]string{
+		"kill":     {"d"},
+		"checkout": {"D"},
+		"detach":   {"ctrl+b"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyOverrides() error = %v", err)
+	}
+
+	if GlobalKeyStringsMap["d"] != KeyKill {
+		t.Errorf("expected d to kill, got %v", GlobalKeyStringsMap["d"])
+	}
+	if GlobalKeyStringsMap["D"] != KeyCheckout {
+		t.Errorf("expected D to checkout, got %v", GlobalKeyStringsMap["D"])
+	}
+	if _, ok := GlobalKeyStringsMap["c"]; ok {
+		t.Error("expected c to be unbound")
+	}
+	if got := HelpKey(KeyKill); got != "d" {
+		t.Errorf("HelpKey(KeyKill) = %q, want %q", got, "d")
+	}
+	if GlobalKeyStringsMap["n"] != KeyNew {
+		t.Error("expected bindings which weren't overridden to be kept")
+	}
+	if DetachKey != "ctrl+b" {
+		t.Errorf("DetachKey = %q, want ctrl+b", DetachKey)
+	}
+}
+
+func TestControlByte(t *testing.T) {
+	if b, err := ControlByte("ctrl+q"); err != nil || b != 17 {
+		t.Errorf("ControlByte(ctrl+q) = %d, %v, want 17", b, err)
+	}
+	for _, k := range []string{"q", "ctrl+m", "ctrl+1", "alt+q"} {
+		if _, err := ControlByte(k); err == nil {
+			t.Errorf("ControlByte(%q) should fail", k)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package log
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 )
 
@@ -12,32 +17,65 @@ var (
 	WarningLog *log.Logger
 	InfoLog    *log.Logger
 	ErrorLog   *log.Logger
+	// Logger is the structured logger which the loggers above write through. Use it, or ForInstance, to log with
+	// fields.
+	Logger *slog.Logger
 )
 
-var logFileName = filepath.Join(os.TempDir(), "orzbob.log")
+const (
+	// FileName is the log file of orz. The daemon logs to DaemonFileName, so the two don't rotate each other's file.
+	FileName       = "orzbob.log"
+	DaemonFileName = "daemon.log"
+	// maxLogSize is how big a log file gets before it's rotated.
+	maxLogSize = 10 << 20
+	// maxBackups is how many rotated log files are kept next to each log file.
+	maxBackups = 3
+)
 
-var globalLogFile *os.File
+var logFileName = filepath.Join(os.TempDir(), FileName)
 
-// Initialize should be called once at the beginning of the program to set up logging.
-// defer Close() after calling this function. It sets the go log output to the file in
-// the os temp directory.
+var globalLogFile *rotatingFile
+
+// Dir returns the directory which holds the log files. It's the logs directory in the config directory, falling
+// back to the temp directory if there's no home directory. config.GetConfigDir can't be used, since the config
+// package logs through this one.
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return filepath.Join(home, ".orzbob", "logs")
+}
 
+// Initialize should be called once at the beginning of the program to set up logging.
+// defer Close() after calling this function. It writes JSON records to a log file in Dir, which
+// is rotated when it gets too big.
 func Initialize(daemon bool) {
-	f, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	name := FileName
+	if daemon {
+		name = DaemonFileName
+	}
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		dir = os.TempDir()
+	}
+	logFileName = filepath.Join(dir, name)
+
+	f, err := openRotatingFile(logFileName, maxLogSize, maxBackups)
 	if err != nil {
 		panic(fmt.Sprintf("could not open log file: %s", err))
 	}
 
-	// Set log format to include timestamp and file/line number
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-
-	fmtS := "%s"
+	Logger = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug}))
 	if daemon {
-		fmtS = "[DAEMON] %s"
+		Logger = Logger.With("process", "daemon")
 	}
-	InfoLog = log.New(f, fmt.Sprintf(fmtS, "INFO:"), log.Ldate|log.Ltime|log.Lshortfile)
-	WarningLog = log.New(f, fmt.Sprintf(fmtS, "WARNING:"), log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLog = log.New(f, fmt.Sprintf(fmtS, "ERROR:"), log.Ldate|log.Ltime|log.Lshortfile)
+	InfoLog = log.New(levelWriter{level: slog.LevelInfo}, "", 0)
+	WarningLog = log.New(levelWriter{level: slog.LevelWarn}, "", 0)
+	ErrorLog = log.New(levelWriter{level: slog.LevelError}, "", 0)
+	// Send what other packages log with the standard logger to the file too.
+	log.SetFlags(0)
+	log.SetOutput(levelWriter{level: slog.LevelInfo})
 
 	globalLogFile = f
 }
@@ -48,6 +86,32 @@ func Close() {
 	fmt.Println("wrote logs to " + logFileName)
 }
 
+// ForInstance returns a logger which adds the title of an instance to each record, so orz logs --instance can pick
+// out what happened to it.
+func ForInstance(title string) *slog.Logger {
+	if Logger == nil {
+		// Logging isn't initialized, e.g. in tests.
+		return slog.New(slog.NewJSONHandler(io.Discard, nil))
+	}
+	return Logger.With("instance", title)
+}
+
+// levelWriter turns what a *log.Logger writes into records of Logger at a level.
+type levelWriter struct {
+	level slog.Level
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	var pcs [1]uintptr
+	// Skip runtime.Callers, Write, log.(*Logger).output and Print or Printf to get to the code which logged.
+	runtime.Callers(4, pcs[:])
+	record := slog.NewRecord(time.Now(), w.level, strings.TrimSuffix(string(p), "\n"), pcs[0])
+	if err := Logger.Handler().Handle(context.Background(), record); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // Every is used to log at most once every timeout duration.
 type Every struct {
 	timeout time.Duration
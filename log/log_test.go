@@ -0,0 +1,162 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInitializeWritesRecords(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Initialize(false)
+	InfoLog.Printf("hello %s", "world")
+	ForInstance("fix login").Warn("could not update", "error", "boom")
+	ErrorLog.Print("broken")
+	Close()
+
+	path := filepath.Join(Dir(), FileName)
+	records, err := ReadRecords([]string{path}, Filter{Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %v", len(records), records)
+	}
+	if records[0].Message != "hello world" || records[0].Level != slog.LevelInfo {
+		t.Errorf("got %+v, want an info record saying hello world", records[0])
+	}
+	if !strings.HasPrefix(records[0].Source, "log_test.go:") {
+		t.Errorf("got source %q, want the line in log_test.go which logged", records[0].Source)
+	}
+	if records[1].Instance != "fix login" || records[1].Attrs["error"] != "boom" {
+		t.Errorf("got %+v, want a record about fix login with an error field", records[1])
+	}
+
+	records, err = ReadRecords([]string{path}, Filter{Instance: "fix login", Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Message != "could not update" {
+		t.Errorf("filtering by instance got %v", records)
+	}
+	records, err = ReadRecords([]string{path}, Filter{Level: slog.LevelError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Message != "broken" {
+		t.Errorf("filtering by level got %v", records)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:                "fourth\n",
+		BackupName(path, 1): "third\n",
+		BackupName(path, 2): "second\n",
+		BackupName(path, 3): "",
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(name)
+		if content == "" {
+			if err == nil {
+				t.Errorf("%s exists, want it deleted", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s holds %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+		want string
+	}{
+		{`{"time":"2026-01-02T03:04:05Z","level":"WARN","msg":"slow","instance":"a","took":2}`, true,
+			"WARN  [a] slow took=2"},
+		{`{"time":"2026-01-02T03:04:05Z","level":"INFO","msg":"up","process":"daemon",` +
+			`"source":{"function":"main","file":"/x/daemon.go","line":20}}`, true, "INFO  [daemon] up (daemon.go:20)"},
+		{`INFO:2025/01/02 03:04:05 app.go:12: an old line`, false, ""},
+		{`{"msg":"no level"}`, false, ""},
+	}
+	for _, tt := range tests {
+		record, ok := ParseRecord([]byte(tt.line))
+		if ok != tt.ok {
+			t.Errorf("ParseRecord(%s) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		// Drop the time, which is formatted in the local time zone.
+		if got := strings.SplitN(record.String(), " ", 3)[2]; got != tt.want {
+			t.Errorf("ParseRecord(%s) formats as %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "follow.log")
+	if err := os.WriteFile(path, []byte(`{"level":"INFO","msg":"old"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- Follow(ctx, []string{path}, Filter{Level: slog.LevelInfo}, func(r Record) {
+			got <- r.Message
+		})
+	}()
+
+	// Let Follow note where the file ends before appending to it.
+	time.Sleep(followInterval / 2)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"level":"DEBUG","msg":"hidden"}` + "\n" + `{"level":"INFO","msg":"new"}` + "\n" +
+		`{"level":"INFO","msg":"partial`)
+	f.Close()
+
+	select {
+	case message := <-got:
+		if message != "new" {
+			t.Errorf("followed %q, want new", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new record wasn't followed")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case message := <-got:
+		t.Errorf("followed %q, want nothing more", message)
+	default:
+	}
+}
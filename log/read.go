@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// followInterval is how often Follow looks for new records.
+const followInterval = 500 * time.Millisecond
+
+// Record is a record read back from a log file.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Instance is the title of the instance the record is about, if any.
+	Instance string
+	// Process is "daemon" for records of the daemon, and empty for orz itself.
+	Process string
+	// Source is the file and line which logged the record.
+	Source string
+	// Attrs are the other fields of the record.
+	Attrs map[string]interface{}
+}
+
+// ParseRecord parses a line of a log file. Lines which aren't JSON records, like those written before logs were
+// structured, aren't records.
+func ParseRecord(line []byte) (Record, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Record{}, false
+	}
+	var record Record
+	if s, ok := fields[slog.TimeKey].(string); ok {
+		record.Time, _ = time.Parse(time.RFC3339Nano, s)
+	}
+	if s, ok := fields[slog.LevelKey].(string); ok {
+		if err := record.Level.UnmarshalText([]byte(s)); err != nil {
+			return Record{}, false
+		}
+	} else {
+		return Record{}, false
+	}
+	record.Message, _ = fields[slog.MessageKey].(string)
+	record.Instance, _ = fields["instance"].(string)
+	record.Process, _ = fields["process"].(string)
+	if source, ok := fields[slog.SourceKey].(map[string]interface{}); ok {
+		file, _ := source["file"].(string)
+		line, _ := source["line"].(float64)
+		record.Source = fmt.Sprintf("%s:%d", filepath.Base(file), int(line))
+	}
+	for _, key := range []string{slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey, "instance", "process"} {
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		record.Attrs = fields
+	}
+	return record, true
+}
+
+// String formats the record as one line for reading in a terminal.
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s ", r.Time.Local().Format("2006-01-02 15:04:05.000"), r.Level)
+	if r.Process != "" {
+		fmt.Fprintf(&b, "[%s] ", r.Process)
+	}
+	if r.Instance != "" {
+		fmt.Fprintf(&b, "[%s] ", r.Instance)
+	}
+	b.WriteString(r.Message)
+	keys := make([]string, 0, len(r.Attrs))
+	for key := range r.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, r.Attrs[key])
+	}
+	if r.Source != "" {
+		fmt.Fprintf(&b, " (%s)", r.Source)
+	}
+	return b.String()
+}
+
+// Filter picks the records orz logs shows.
+type Filter struct {
+	// Instance only picks records about the instance with this title, if set.
+	Instance string
+	// Level is the lowest level picked.
+	Level slog.Level
+}
+
+// Match reports whether the filter picks the record.
+func (f Filter) Match(r Record) bool {
+	if f.Instance != "" && r.Instance != f.Instance {
+		return false
+	}
+	return r.Level >= f.Level
+}
+
+// Files returns the log files of orz and the daemon in Dir, including rotations.
+func Files() []string {
+	var paths []string
+	for _, name := range []string{FileName, DaemonFileName} {
+		path := filepath.Join(Dir(), name)
+		for i := maxBackups; i >= 1; i-- {
+			if _, err := os.Stat(BackupName(path, i)); err == nil {
+				paths = append(paths, BackupName(path, i))
+			}
+		}
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ReadRecords reads the records the filter picks from the files, merged in time order.
+func ReadRecords(paths []string, filter Filter) ([]Record, error) {
+	var records []Record
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if record, ok := ParseRecord(scanner.Bytes()); ok && filter.Match(record) {
+				records = append(records, record)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].Time.Before(records[b].Time)
+	})
+	return records, nil
+}
+
+// Follow calls fn with each record the filter picks which is written to the files from now on, until ctx is done.
+// Files which don't exist yet are picked up when they're created, and rotated files are read from the start again.
+func Follow(ctx context.Context, paths []string, filter Filter, fn func(Record)) error {
+	offsets := make(map[string]int64, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			offsets[path] = info.Size()
+		}
+	}
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		for _, path := range paths {
+			offset, err := readNew(path, offsets[path], filter, fn)
+			if err != nil {
+				return err
+			}
+			offsets[path] = offset
+		}
+	}
+}
+
+// readNew reads the complete lines written to the file at path since offset and returns the offset after them.
+func readNew(path string, offset int64, filter Filter, fn func(Record)) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return offset, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return offset, err
+	}
+	if info.Size() < offset {
+		// The file was rotated, so this is a new one.
+		offset = 0
+	}
+	if info.Size() == offset {
+		return offset, nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return offset, err
+	}
+	// Leave a line which is still being written for the next time.
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return offset, nil
+	}
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if record, ok := ParseRecord(line); ok && filter.Match(record) {
+			fn(record)
+		}
+	}
+	return offset + int64(end) + 1, nil
+}
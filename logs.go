@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"orzbob/log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the logs of orz and the daemon",
+	Long: `Show the logs of orz and the daemon, oldest first. The logs are JSON files in
+the logs directory of the config directory, rotated when they reach 10MB.
+
+--instance only shows what was logged about one instance, --level hides records
+below a level (debug, info, warn or error), and --follow keeps printing new
+records until interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: runLogs,
+}
+
+var (
+	logsInstance string
+	logsFollow   bool
+	logsLevel    string
+	logsLines    int
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().StringVarP(&logsInstance, "instance", "i", "", "Only show records about the instance with this title")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new records")
+	logsCmd.Flags().StringVarP(&logsLevel, "level", "l", "info", "Lowest level to show: debug, info, warn or error")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of past records to show, 0 for all")
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	level := strings.ToLower(logsLevel)
+	if level == "warning" {
+		level = "warn"
+	}
+	filter := log.Filter{Instance: logsInstance}
+	if err := filter.Level.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown level %q, use debug, info, warn or error", logsLevel)
+	}
+
+	paths := log.Files()
+	records, err := log.ReadRecords(paths, filter)
+	if err != nil {
+		return err
+	}
+	if logsLines > 0 && len(records) > logsLines {
+		records = records[len(records)-logsLines:]
+	}
+	for _, record := range records {
+		printRecord(record)
+	}
+	if !logsFollow {
+		if len(paths) == 0 {
+			fmt.Printf("No logs in %s yet\n", log.Dir())
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	return log.Follow(ctx, followPaths(), filter, printRecord)
+}
+
+// followPaths returns the log files new records are written to. Unlike log.Files, they don't have to exist yet.
+func followPaths() []string {
+	dir := log.Dir()
+	return []string{filepath.Join(dir, log.FileName), filepath.Join(dir, log.DaemonFileName)}
+}
+
+func printRecord(record log.Record) {
+	fmt.Println(record)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"orzbob/app"
 	"orzbob/config"
@@ -78,6 +79,9 @@ var (
 			// Run auto-update check after logging is initialized and before starting the app UI
 			// This is done silently and only shows output if an update is available
 			if err := runAutoUpdateCheck(); err != nil {
+				if errors.Is(err, update.ErrUnsupportedVersion) {
+					return err
+				}
 				log.ErrorLog.Printf("auto-update check failed: %v", err)
 				// Continue execution even if auto-update fails
 			}
@@ -142,6 +146,14 @@ var (
 			fmt.Printf("Current version: v%s\n", version)
 			fmt.Printf("Auto-update enabled: %t\n", cfg.EnableAutoUpdate)
 			fmt.Printf("Auto-install updates: %t\n", cfg.AutoInstallUpdates)
+			channel := cfg.UpdateChannel
+			if channel == "" {
+				channel = update.ChannelStable
+			}
+			fmt.Printf("Update channel: %s\n", channel)
+			if cfg.PinnedVersion != "" {
+				fmt.Printf("Pinned version: v%s\n", cfg.PinnedVersion)
+			}
 
 			if cfg.LastUpdateCheck > 0 {
 				lastCheck := time.Unix(cfg.LastUpdateCheck, 0)
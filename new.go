@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"orzbob/app"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/prompts"
+	"orzbob/session"
+	"orzbob/session/git"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var newCmd = &cobra.Command{
+	Use:   "new <title>",
+	Short: "Start an instance in the repository in the current directory",
+	Long: `Start an instance without opening the TUI. Its branch is named with the
+branch_template and started from HEAD, or from --base: a local or remote
+branch, tag, commit or pull request (#123 or its URL), fetched from origin
+first if needed. --continue works on an existing local or remote branch
+instead of creating one.
+
+--sparse checks out only the given directories, and the files at the root,
+which saves time and disk in large repositories. Without it, the directories
+in the repository's orzbob.sparse git config are used, if any, and --full
+checks out everything.
+
+--template sends a saved prompt template instead of --message, with its
+fields set by --set. Templates are <name>.md files in the prompts directory
+of the config directory, or in .orzbob/prompts of the repository.`,
+	Example: `  orz new "fix login" -m "Fix the login redirect loop"
+  orz new review --base '#123' -m "Review this pull request"
+  orz new hotfix --base v1.4.2
+  orz new "finish search" --continue feature/search
+  orz new "api tests" --sparse services/api,libs/auth
+  orz new "fix flaky" --template fix-test --set Test=TestLogin`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runNew,
+}
+
+var (
+	newPrompt   string
+	newBase     string
+	newBranch   string
+	newContinue string
+	newProgram  string
+	newSparse   string
+	newFull     bool
+	newTemplate string
+	newSet      []string
+)
+
+func init() {
+	rootCmd.AddCommand(newCmd)
+	newCmd.Flags().StringVarP(&newPrompt, "message", "m", "", "Prompt to send to the instance once it starts")
+	newCmd.Flags().StringVar(&newBase, "base", "", "Branch, tag, commit or pull request to start from (default: HEAD)")
+	newCmd.Flags().StringVarP(&newBranch, "branch", "b", "", "Name of the new branch (default: from branch_template)")
+	newCmd.Flags().StringVar(&newContinue, "continue", "", "Existing local or remote branch to work on")
+	newCmd.Flags().StringVarP(&newProgram, "program", "p", "", "Program to run (default: default_program)")
+	newCmd.Flags().StringVar(&newSparse, "sparse", "", "Directories to check out, separated by commas")
+	newCmd.Flags().BoolVar(&newFull, "full", false, "Check out everything, even if the repository has sparse defaults")
+	newCmd.Flags().StringVar(&newTemplate, "template", "", "Prompt template to send instead of --message")
+	newCmd.Flags().StringArrayVar(&newSet, "set", nil, "Value of a template field, as name=value")
+	newCmd.MarkFlagsMutuallyExclusive("sparse", "full")
+	newCmd.MarkFlagsMutuallyExclusive("message", "template")
+	newCmd.MarkFlagsMutuallyExclusive("continue", "base")
+	newCmd.MarkFlagsMutuallyExclusive("continue", "branch")
+}
+
+func runNew(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	title := args[0]
+	cfg := config.LoadConfig()
+	storage, err := session.NewStorage(config.LoadState())
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+	if len(instances) >= app.GlobalInstanceLimit {
+		return fmt.Errorf("you can't create more than %d instances", app.GlobalInstanceLimit)
+	}
+	for _, instance := range instances {
+		if instance.Title == title {
+			return fmt.Errorf("an instance named %s already exists", title)
+		}
+	}
+
+	opts := session.InstanceOptions{
+		Title:   title,
+		Path:    ".",
+		Program: cfg.DefaultProgram,
+		Base:    newBase,
+	}
+	if newProgram != "" {
+		opts.Program = newProgram
+	}
+	switch {
+	case newFull:
+		opts.Sparse = []string{}
+	case newSparse != "":
+		if opts.Sparse, err = git.ParseSparsePatterns(newSparse); err != nil {
+			return err
+		}
+	}
+	switch {
+	case newContinue != "":
+		opts.Branch = newContinue
+		opts.Continue = true
+	case newBranch != "":
+		if err := git.ValidateBranchName(newBranch); err != nil {
+			return err
+		}
+		if git.BranchExists(".", newBranch) {
+			return fmt.Errorf("branch %s already exists, use --continue to work on it", newBranch)
+		}
+		opts.Branch = newBranch
+	default:
+		branch, err := git.ExpandBranchTemplate(cfg.BranchTemplate, title, git.BranchTemplateUser("."), time.Now())
+		if err != nil {
+			return err
+		}
+		opts.Branch = git.UniqueBranchName(".", branch)
+	}
+
+	prompt := newPrompt
+	if newTemplate != "" {
+		if prompt, err = renderNewTemplate(opts); err != nil {
+			return err
+		}
+	}
+
+	instance, err := session.NewInstance(opts)
+	if err != nil {
+		return err
+	}
+	instance.Prompt = prompt
+	if err := instance.Start(true); err != nil {
+		return err
+	}
+	if err := storage.SaveInstances(append(instances, instance)); err != nil {
+		return err
+	}
+	if prompt != "" {
+		if err := instance.WaitReady(); err != nil {
+			return err
+		}
+		if err := instance.SendPrompt(prompt); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✅ Started %s on branch %s\n", instance.Title, instance.Branch)
+	return nil
+}
+
+// renderNewTemplate fills in the --template for the instance about to be started with opts, with the fields given
+// with --set.
+func renderNewTemplate(opts session.InstanceOptions) (string, error) {
+	values := make(map[string]string, len(newSet))
+	for _, set := range newSet {
+		name, value, ok := strings.Cut(set, "=")
+		if !ok {
+			return "", fmt.Errorf("--set %s isn't name=value", set)
+		}
+		values[name] = value
+	}
+
+	repoPath, err := git.RepoRoot(".")
+	if err != nil {
+		return "", err
+	}
+	userDir, err := prompts.UserDir()
+	if err != nil {
+		return "", err
+	}
+	templates, err := prompts.Load(userDir, repoPath)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := prompts.Find(templates, newTemplate)
+	if err != nil {
+		return "", err
+	}
+	for name := range values {
+		if !tmpl.HasField(name) {
+			return "", fmt.Errorf("template %s has no field %s", tmpl.Name, name)
+		}
+	}
+	return tmpl.Render(prompts.Context{
+		Title:  opts.Title,
+		Branch: opts.Branch,
+		Base:   opts.Base,
+		Repo:   filepath.Base(repoPath),
+		Dir:    repoPath,
+	}, values)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"orzbob/config"
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/session/git"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var prCmd = &cobra.Command{
+	Use:   "pr <title>",
+	Short: "Push an instance's branch and open a pull request",
+	Long: `Commit and push an instance's branch, then open a pull request of it on the
+forge hosting origin: GitHub with the gh CLI, GitLab with GITLAB_TOKEN, and
+Gitea or Forgejo with GITEA_TOKEN. The forge is detected from the remote URL;
+set it with 'git config orzbob.forge <github|gitlab|gitea|git>' if the host
+name doesn't say.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runPR,
+}
+
+var (
+	prTitle string
+	prBody  string
+	prBase  string
+	prDraft bool
+)
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+	prCmd.Flags().StringVarP(&prTitle, "title", "t", "", "Title of the pull request (default: the instance's title)")
+	prCmd.Flags().StringVarP(&prBody, "body", "b", "", "Body of the pull request (default: the instance's prompt)")
+	prCmd.Flags().StringVar(&prBase, "base", "", "Branch to merge into (default: origin's default branch)")
+	prCmd.Flags().BoolVarP(&prDraft, "draft", "d", false, "Open the pull request as a draft")
+}
+
+func runPR(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	storage, err := session.NewStorage(config.LoadState())
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstances()
+	if err != nil {
+		return fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	var instance *session.Instance
+	for _, i := range instances {
+		if i.Title == args[0] {
+			instance = i
+			break
+		}
+	}
+	if instance == nil {
+		return fmt.Errorf("instance not found: %s", args[0])
+	}
+	if instance.IsCloud {
+		return fmt.Errorf("%s runs in the cloud, bring it home with 'orz cloud home' first", instance.Title)
+	}
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return err
+	}
+
+	if instance.Status != session.Paused {
+		commitMsg := fmt.Sprintf("[orzbob] update from '%s' on %s", instance.Title, time.Now().Format(time.RFC822))
+		if err := worktree.PushChanges(commitMsg, false); err != nil {
+			return err
+		}
+	}
+
+	pr := git.PullRequest{Title: prTitle, Body: prBody, Base: prBase, Draft: prDraft}
+	if pr.Title == "" {
+		pr.Title = instance.Title
+	}
+	if pr.Body == "" {
+		pr.Body = instance.Prompt
+	}
+	url, err := worktree.CreatePullRequest(pr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Opened %s\n", url)
+	return nil
+}
@@ -0,0 +1,80 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orzbob/config"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxHistory is how many prompts are kept in the history of a repository. Older ones are dropped.
+const MaxHistory = 500
+
+// HistoryDir returns the directory holding the prompt history of every repository, in the config directory.
+func HistoryDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "history"), nil
+}
+
+// historyPath returns the file in dir holding the history of the repository at repoPath. It's named after the
+// repository and a hash of its path, so repositories with the same name don't share a history.
+func historyPath(dir, repoPath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(repoPath)))
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", filepath.Base(repoPath), hex.EncodeToString(sum[:6])))
+}
+
+// LoadHistory returns the prompts sent in the repository at repoPath, from the oldest to the newest. It's empty if
+// none were sent yet.
+func LoadHistory(dir, repoPath string) ([]string, error) {
+	data, err := os.ReadFile(historyPath(dir, repoPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []string
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt history: %w", err)
+	}
+	return history, nil
+}
+
+// AddHistory adds prompt to the history of the repository at repoPath as the newest one. An earlier copy of it is
+// removed, so sending the same prompt again only moves it up. Blank prompts are ignored.
+func AddHistory(dir, repoPath, prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return nil
+	}
+	history, err := LoadHistory(dir, repoPath)
+	if err != nil {
+		return err
+	}
+	kept := history[:0]
+	for _, p := range history {
+		if p != prompt {
+			kept = append(kept, p)
+		}
+	}
+	kept = append(kept, prompt)
+	if len(kept) > MaxHistory {
+		kept = kept[len(kept)-MaxHistory:]
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath(dir, repoPath), data, 0644)
+}
@@ -0,0 +1,46 @@
+package prompts
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	repo, other := "/src/orzbob", "/work/orzbob"
+
+	if history, err := LoadHistory(dir, repo); err != nil || len(history) != 0 {
+		t.Fatalf("LoadHistory() before any prompt = %v, %v", history, err)
+	}
+	for _, prompt := range []string{"fix the login", "add tests", "  ", "fix the login"} {
+		if err := AddHistory(dir, repo, prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddHistory(dir, other, "something else"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := LoadHistory(dir, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"add tests", "fix the login"}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %q, want %q", history, want)
+	}
+	if history, _ := LoadHistory(dir, other); !reflect.DeepEqual(history, []string{"something else"}) {
+		t.Errorf("a repository with the same name shares the history: %q", history)
+	}
+
+	for i := 0; i < MaxHistory+5; i++ {
+		if err := AddHistory(dir, repo, fmt.Sprintf("prompt %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, _ = LoadHistory(dir, repo)
+	if len(history) != MaxHistory || history[len(history)-1] != fmt.Sprintf("prompt %d", MaxHistory+4) {
+		t.Errorf("history has %d prompts ending with %q, want the newest %d", len(history), history[len(history)-1],
+			MaxHistory)
+	}
+}
@@ -0,0 +1,325 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"orzbob/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoDir is the directory of a repository holding the prompt templates shared through it.
+const RepoDir = ".orzbob/prompts"
+
+// UserDir returns the directory holding the user's own prompt templates, in the config directory.
+func UserDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "prompts"), nil
+}
+
+// Scopes of templates. A repository's templates override the user's templates of the same name.
+const (
+	ScopeUser = "user"
+	ScopeRepo = "repo"
+)
+
+// builtins are the variables a template can use without them being fields. They describe the instance the prompt
+// is for.
+var builtins = map[string]bool{"Title": true, "Branch": true, "Base": true, "Repo": true}
+
+// Context is what the builtin variables of a template are filled in with.
+type Context struct {
+	// Title is the title of the instance, {{.Title}}.
+	Title string
+	// Branch is the branch of the instance, {{.Branch}}.
+	Branch string
+	// Base is what the branch was started from, {{.Base}}. It's empty if it was started from HEAD.
+	Base string
+	// Repo is the name of the repository, {{.Repo}}.
+	Repo string
+	// Dir is the directory {{file "path"}} reads files from, usually the instance's worktree.
+	Dir string
+}
+
+// Field is a variable of a template which is filled in by the user.
+type Field struct {
+	Name string `yaml:"name"`
+	// Description is shown when asking for the value. It may be empty.
+	Description string `yaml:"description"`
+	// Default is the value used if none is given.
+	Default string `yaml:"default"`
+	// Optional fields may be left empty, e.g. for {{if .Notes}}...{{end}}.
+	Optional bool `yaml:"optional"`
+}
+
+// Label returns how the field is shown when asking for its value.
+func (f Field) Label() string {
+	if f.Description == "" {
+		return f.Name
+	}
+	return f.Name + ": " + f.Description
+}
+
+// Template is a named prompt with variables, written with text/template: {{.Title}}, {{.Branch}}, {{.Base}} and
+// {{.Repo}} describe the instance, {{file "path"}} inserts a file of the repository, and any other {{.Name}} is a
+// field the user fills in.
+type Template struct {
+	Name string
+	// Description says what the template is for. It may be empty.
+	Description string
+	// Scope is ScopeUser or ScopeRepo.
+	Scope string
+	// Fields are the variables filled in by the user, in the order they're declared or first used.
+	Fields []Field
+
+	tmpl *template.Template
+}
+
+// frontMatter is the optional YAML header of a template file, between lines of "---".
+type frontMatter struct {
+	Description string  `yaml:"description"`
+	Fields      []Field `yaml:"fields"`
+}
+
+// Load returns the templates in userDir and in RepoDir of the repository at repoPath, sorted by name. Each template
+// is a file named <name>.md. A file may start with a YAML header between lines of "---" with a description and
+// fields, each with a name, description, default and whether it's optional. repoPath may be empty to load only the
+// user's templates.
+func Load(userDir, repoPath string) ([]*Template, error) {
+	byName := make(map[string]*Template)
+	dirs := []struct{ dir, scope string }{{userDir, ScopeUser}}
+	if repoPath != "" {
+		dirs = append(dirs, struct{ dir, scope string }{filepath.Join(repoPath, RepoDir), ScopeRepo})
+	}
+	for _, d := range dirs {
+		if d.dir == "" {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(d.dir, "*.md"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			t, err := Parse(strings.TrimSuffix(filepath.Base(path), ".md"), data)
+			if err != nil {
+				return nil, err
+			}
+			t.Scope = d.scope
+			byName[t.Name] = t
+		}
+	}
+
+	templates := make([]*Template, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Find returns the template with the given name.
+func Find(templates []*Template, name string) (*Template, error) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	names := make([]string, len(templates))
+	for i, t := range templates {
+		names[i] = t.Name
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("there's no template %s, and no templates at all", name)
+	}
+	return nil, fmt.Errorf("there's no template %s, there are %s", name, strings.Join(names, ", "))
+}
+
+// Parse parses the contents of a template file, see Load.
+func Parse(name string, data []byte) (*Template, error) {
+	var header frontMatter
+	body := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		yamlPart, text, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			return nil, fmt.Errorf("template %s: the header has no closing ---", name)
+		}
+		if err := yaml.Unmarshal([]byte(yamlPart), &header); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		body = text
+	}
+
+	// file is replaced when rendering, once the directory it reads from is known.
+	tmpl, err := template.New(name).Option("missingkey=error").
+		Funcs(template.FuncMap{"file": func(string) (string, error) { return "", nil }}).
+		Parse(strings.TrimSpace(body))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	t := &Template{Name: name, Description: header.Description, tmpl: tmpl}
+	declared := make(map[string]bool)
+	for _, f := range header.Fields {
+		if f.Name == "" || builtins[f.Name] {
+			return nil, fmt.Errorf("template %s: invalid field name %q", name, f.Name)
+		}
+		declared[f.Name] = true
+		t.Fields = append(t.Fields, f)
+	}
+	for _, used := range variables(tmpl.Tree.Root) {
+		if !builtins[used] && !declared[used] {
+			declared[used] = true
+			t.Fields = append(t.Fields, Field{Name: used})
+		}
+	}
+	return t, nil
+}
+
+// Uses returns true if the template uses the variable, e.g. "Branch".
+func (t *Template) Uses(name string) bool {
+	for _, used := range variables(t.tmpl.Tree.Root) {
+		if used == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasField returns true if the template has a field with the given name.
+func (t *Template) HasField(name string) bool {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render fills in the template. values has the values of its fields, and fields without one get their default. It's
+// an error if a field which isn't optional is left empty.
+func (t *Template) Render(ctx Context, values map[string]string) (string, error) {
+	data := map[string]string{"Title": ctx.Title, "Branch": ctx.Branch, "Base": ctx.Base, "Repo": ctx.Repo}
+	var missing []string
+	for _, f := range t.Fields {
+		value := values[f.Name]
+		if value == "" {
+			value = f.Default
+		}
+		if value == "" && !f.Optional {
+			missing = append(missing, f.Name)
+		}
+		data[f.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %s needs a value for %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(template.FuncMap{"file": func(path string) (string, error) { return readFile(ctx.Dir, path) }})
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// readFile returns the contents of the file at path, relative to dir, which it must be inside of. Symbolic links are
+// followed before checking, so a link can't point outside of dir either.
+func readFile(dir, path string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("there's no repository to read %s from", path)
+	}
+	full := filepath.Join(dir, path)
+	if !inside(dir, full) {
+		return "", fmt.Errorf("%s is outside the repository", path)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	realFull, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return "", err
+	}
+	if !inside(realDir, realFull) {
+		return "", fmt.Errorf("%s links to a file outside the repository", path)
+	}
+	data, err := os.ReadFile(realFull)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// inside returns true if path is dir or in it, going by the paths alone.
+func inside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// variables returns the names of the top-level variables used in a template, such as Branch for {{.Branch}}, in the
+// order they're first used.
+func variables(root *parse.ListNode) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(root)
+	return names
+}
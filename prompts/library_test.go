@@ -0,0 +1,144 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	userDir := t.TempDir()
+	repo := t.TempDir()
+	writeTemplate(t, userDir, "review", "Review {{.Branch}}")
+	writeTemplate(t, userDir, "fix-test", "user version")
+	writeTemplate(t, filepath.Join(repo, RepoDir), "fix-test", `---
+description: Fix a failing test
+fields:
+  - name: Test
+    description: name of the failing test
+  - name: Package
+    default: ./...
+---
+Fix {{.Test}} in {{.Package}} on {{.Branch}}. {{.Notes}}
+`)
+
+	templates, err := Load(userDir, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 || templates[0].Name != "fix-test" || templates[1].Name != "review" {
+		t.Fatalf("Load() = %v, want fix-test and review", templates)
+	}
+	fixTest := templates[0]
+	if fixTest.Scope != ScopeRepo || fixTest.Description != "Fix a failing test" {
+		t.Errorf("the repo's fix-test didn't override the user's: %+v", fixTest)
+	}
+	want := []Field{
+		{Name: "Test", Description: "name of the failing test"},
+		{Name: "Package", Default: "./..."},
+		{Name: "Notes"},
+	}
+	if !reflect.DeepEqual(fixTest.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", fixTest.Fields, want)
+	}
+	if templates[1].Scope != ScopeUser || len(templates[1].Fields) != 0 {
+		t.Errorf("review = %+v, want a user template without fields", templates[1])
+	}
+
+	if _, err := Find(templates, "missing"); err == nil {
+		t.Error("found a missing template")
+	}
+	if userOnly, err := Load(userDir, ""); err != nil || len(userOnly) != 2 || userOnly[0].Scope != ScopeUser {
+		t.Errorf("Load() without a repo = %v, %v", userOnly, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "CONTRIBUTING.md"), []byte("Run make test.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("hunter2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"secret": outside, "guide": "CONTRIBUTING.md"} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := Context{Title: "fix login", Branch: "session/fix-login", Base: "main", Repo: "orzbob", Dir: dir}
+
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "builtins",
+			template: "{{.Title}} on {{.Branch}} from {{.Base}} in {{.Repo}}",
+			want:     "fix login on session/fix-login from main in orzbob",
+		},
+		{
+			name:     "fields and defaults",
+			template: "---\nfields:\n  - name: Package\n    default: ./...\n---\nFix {{.Test}} in {{.Package}}",
+			values:   map[string]string{"Test": "TestLogin"},
+			want:     "Fix TestLogin in ./...",
+		},
+		{
+			name:     "optional field",
+			template: "---\nfields:\n  - name: Notes\n    optional: true\n---\nGo.{{if .Notes}} {{.Notes}}{{end}}",
+			want:     "Go.",
+		},
+		{
+			name:     "file",
+			template: `Follow this: {{file "CONTRIBUTING.md"}}`,
+			want:     "Follow this: Run make test.",
+		},
+		{name: "missing field", template: "Fix {{.Test}}", wantErr: true},
+		{name: "missing file", template: `{{file "missing"}}`, wantErr: true},
+		{name: "file outside the repository", template: `{{file "../secret"}}`, wantErr: true},
+		{name: "link outside the repository", template: `{{file "secret"}}`, wantErr: true},
+		{name: "link inside the repository", template: `{{file "guide"}}`, want: "Run make test."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, []byte(tt.template))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tmpl.Render(ctx, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, content := range []string{
+		"---\ndescription: no end\nFix it",
+		"---\nfields:\n  - name: Branch\n---\n{{.Branch}}",
+		"Fix {{.Test",
+	} {
+		if _, err := Parse("bad", []byte(content)); err == nil {
+			t.Errorf("Parse(%q) succeeded", content)
+		}
+	}
+}
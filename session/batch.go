@@ -0,0 +1,185 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"orzbob/session/git"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// batchTitleLength is the longest title derived from a task's prompt, the same as the longest title the TUI accepts.
+const batchTitleLength = 32
+
+// BatchTask is an instance to start with orz batch.
+type BatchTask struct {
+	// Title is the title of the instance. If it's empty, it's derived from the prompt.
+	Title string `yaml:"title"`
+	// Prompt is sent to the program once it starts.
+	Prompt string `yaml:"prompt"`
+	// Program is the program to run. If it's empty, the default program is run.
+	Program string `yaml:"program"`
+	// Base is the branch, tag, commit or pull request the instance's branch is started from, see InstanceOptions.
+	Base string `yaml:"base"`
+	// Cloud runs the instance on a cloud runner instead of locally.
+	Cloud bool `yaml:"cloud"`
+	// Tier is the tier of the cloud runner: small, medium or large.
+	Tier string `yaml:"tier"`
+}
+
+// Batch is a list of tasks read from a manifest.
+type Batch struct {
+	// Concurrency is how many instances are started at the same time, or 0 if the manifest doesn't say.
+	Concurrency int `yaml:"concurrency"`
+	// Defaults has the program, base and tier of tasks which don't set them. If it's a cloud task, all tasks are.
+	Defaults BatchTask   `yaml:"defaults"`
+	Tasks    []BatchTask `yaml:"tasks"`
+}
+
+// ParseBatch reads the tasks of a manifest named name. A manifest ending in .md or .markdown is a Markdown document
+// whose unchecked checklist items are the prompts of the tasks. Anything else is YAML: either a list of tasks or a
+// mapping with tasks, defaults and concurrency. defaults fills in what the manifest's defaults leave empty.
+func ParseBatch(name string, data []byte, defaults BatchTask) (*Batch, error) {
+	var batch Batch
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		batch.Tasks = parseChecklist(data)
+	default:
+		if err := parseBatchYAML(data, &batch); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+	}
+	if len(batch.Tasks) == 0 {
+		return nil, fmt.Errorf("%s has no tasks", name)
+	}
+	if batch.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", batch.Concurrency)
+	}
+	batch.Defaults.applyDefaults(defaults)
+	for i := range batch.Tasks {
+		batch.Tasks[i].applyDefaults(batch.Defaults)
+		if err := batch.Tasks[i].validate(); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+	}
+	return &batch, nil
+}
+
+func parseBatchYAML(data []byte, batch *Batch) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	if len(node.Content) == 0 {
+		return nil
+	}
+	if node.Content[0].Kind == yaml.SequenceNode {
+		return node.Content[0].Decode(&batch.Tasks)
+	}
+	// Reject unknown keys, so a misspelled field isn't silently ignored.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(batch)
+}
+
+func (t *BatchTask) applyDefaults(defaults BatchTask) {
+	if t.Program == "" {
+		t.Program = defaults.Program
+	}
+	if t.Base == "" {
+		t.Base = defaults.Base
+	}
+	if t.Tier == "" {
+		t.Tier = defaults.Tier
+	}
+	t.Cloud = t.Cloud || defaults.Cloud
+	t.Title = strings.TrimSpace(t.Title)
+	t.Prompt = strings.TrimSpace(t.Prompt)
+}
+
+func (t *BatchTask) validate() error {
+	if t.Title == "" && t.Prompt == "" {
+		return fmt.Errorf("a task needs a title or a prompt")
+	}
+	switch t.Tier {
+	case "", "small", "medium", "large":
+	default:
+		return fmt.Errorf("unknown tier %s, it's one of small, medium and large", t.Tier)
+	}
+	if t.Tier != "" && !t.Cloud {
+		return fmt.Errorf("tier %s is only for cloud tasks, add cloud: true", t.Tier)
+	}
+	return nil
+}
+
+// checklistItem matches a Markdown checklist item, e.g. "- [ ] Add tests", with its indentation, mark and text.
+var checklistItem = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\](?:\s+(.*))?$`)
+
+// parseChecklist returns a task for each unchecked item of a Markdown checklist. Lines indented below an item, such
+// as details or nested items, are part of its prompt. Checked items are done and are skipped.
+func parseChecklist(data []byte) []BatchTask {
+	var tasks []BatchTask
+	var lines []string
+	// indent is the indentation of the current item, or -1 outside of items.
+	indent, checked := -1, false
+	finish := func() {
+		if indent >= 0 && !checked {
+			if prompt := strings.TrimSpace(strings.Join(lines, "\n")); prompt != "" {
+				tasks = append(tasks, BatchTask{Prompt: prompt})
+			}
+		}
+		lines, indent = nil, -1
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent >= 0 && (strings.TrimSpace(line) == "" || lineIndent > indent) {
+			// Part of the current item, minus the indentation of its text.
+			lines = append(lines, strings.TrimPrefix(line, strings.Repeat(" ", min(lineIndent, indent+2))))
+			continue
+		}
+		finish()
+		if match := checklistItem.FindStringSubmatch(line); match != nil {
+			indent, checked = len(match[1]), match[2] != " "
+			lines = []string{match[3]}
+		}
+	}
+	finish()
+	return tasks
+}
+
+// AssignTitles derives the titles of the tasks which have none from their prompts, see git.TitleFromPrompt, and
+// makes them unique among each other and the titles in taken with a suffix " 2", " 3", ... Titles given in the
+// manifest are kept, and it's an error if they're taken.
+func (b *Batch) AssignTitles(taken map[string]bool) error {
+	used := make(map[string]bool, len(taken))
+	for title := range taken {
+		used[title] = true
+	}
+	for _, task := range b.Tasks {
+		if task.Title == "" {
+			continue
+		}
+		if used[task.Title] {
+			return fmt.Errorf("%s is the title of another task or instance", task.Title)
+		}
+		used[task.Title] = true
+	}
+	for i := range b.Tasks {
+		task := &b.Tasks[i]
+		if task.Title != "" {
+			continue
+		}
+		title := git.TitleFromPrompt(task.Prompt, batchTitleLength)
+		unique := title
+		for n := 2; used[unique]; n++ {
+			suffix := fmt.Sprintf(" %d", n)
+			unique = git.TruncateTitle(title, batchTitleLength-len(suffix)) + suffix
+		}
+		task.Title = unique
+		used[unique] = true
+	}
+	return nil
+}
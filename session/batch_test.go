@@ -0,0 +1,125 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		data     string
+		defaults BatchTask
+		want     *Batch
+		wantErr  bool
+	}{
+		{
+			name: "list",
+			file: "tasks.yaml",
+			data: `
+- title: fix login
+  prompt: Fix the login redirect loop
+- prompt: Add billing tests
+  program: aider
+  base: "#12"
+  cloud: true
+  tier: large
+`,
+			defaults: BatchTask{Program: "claude"},
+			want: &Batch{Defaults: BatchTask{Program: "claude"}, Tasks: []BatchTask{
+				{Title: "fix login", Prompt: "Fix the login redirect loop", Program: "claude"},
+				{Prompt: "Add billing tests", Program: "aider", Base: "#12", Cloud: true, Tier: "large"},
+			}},
+		},
+		{
+			name: "mapping with defaults",
+			file: "tasks.yml",
+			data: `
+concurrency: 2
+defaults:
+  base: main
+  cloud: true
+tasks:
+  - prompt: one
+  - prompt: two
+    base: develop
+`,
+			defaults: BatchTask{Base: "ignored", Tier: "medium"},
+			want: &Batch{
+				Concurrency: 2,
+				Defaults:    BatchTask{Base: "main", Cloud: true, Tier: "medium"},
+				Tasks: []BatchTask{
+					{Prompt: "one", Base: "main", Cloud: true, Tier: "medium"},
+					{Prompt: "two", Base: "develop", Cloud: true, Tier: "medium"},
+				},
+			},
+		},
+		{
+			name: "checklist",
+			file: "plan.md",
+			data: `# Plan
+
+Some context which isn't a task.
+
+- [ ] Fix the login redirect loop
+  It happens after the session expires.
+  - [ ] add a regression test
+- [x] Already done
+  with details
+* [ ] Add billing tests
+
+- plain list item
+- [ ]
+`,
+			want: &Batch{Tasks: []BatchTask{
+				{Prompt: "Fix the login redirect loop\nIt happens after the session expires.\n- [ ] add a regression test"},
+				{Prompt: "Add billing tests"},
+			}},
+		},
+		{name: "unknown field", file: "tasks.yaml", data: "tasks:\n  - promt: typo\n", wantErr: true},
+		{name: "unknown tier", file: "tasks.yaml", data: "- prompt: x\n  cloud: true\n  tier: huge\n", wantErr: true},
+		{name: "tier of a local task", file: "tasks.yaml", data: "- prompt: x\n  tier: small\n", wantErr: true},
+		{name: "empty task", file: "tasks.yaml", data: "- program: claude\n", wantErr: true},
+		{name: "no tasks", file: "tasks.yaml", data: "concurrency: 2\n", wantErr: true},
+		{name: "no unchecked items", file: "plan.md", data: "- [x] done\n", wantErr: true},
+		{name: "negative concurrency", file: "tasks.yaml", data: "concurrency: -1\ntasks: [{prompt: x}]\n",
+			wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBatch(tt.file, []byte(tt.data), tt.defaults)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBatch() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignTitles(t *testing.T) {
+	batch := &Batch{Tasks: []BatchTask{
+		{Prompt: "Fix the login redirect loop"},
+		{Title: "mine", Prompt: "anything"},
+		{Prompt: "Fix the login redirect loop"},
+		{Prompt: "Fix the login redirect loop"},
+	}}
+	if err := batch.AssignTitles(map[string]bool{"fix login redirect loop 2": true}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range batch.Tasks {
+		got = append(got, task.Title)
+	}
+	want := []string{"fix login redirect loop", "mine", "fix login redirect loop 3", "fix login redirect loop 4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("titles = %q, want %q", got, want)
+	}
+
+	taken := &Batch{Tasks: []BatchTask{{Title: "mine"}, {Title: "mine"}}}
+	if err := taken.AssignTitles(nil); err == nil {
+		t.Error("two tasks got the same title")
+	}
+}
@@ -0,0 +1,86 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrUnauthorized is returned when the control plane rejects the saved token.
+var ErrUnauthorized = errors.New("the control plane rejected the token")
+
+// TokenPath returns the path of the file holding the token saved by orz login.
+func (m *Manager) TokenPath() string {
+	return m.tokenPath
+}
+
+// APIURL returns the URL of the control plane.
+func (m *Manager) APIURL() string {
+	return m.apiURL
+}
+
+// TokenExpiry returns when the saved token expires. Unlike loadToken, it doesn't fail for an expired token. The
+// error is os.ErrNotExist if there is no token.
+func (m *Manager) TokenExpiry() (time.Time, error) {
+	data, err := os.ReadFile(m.tokenPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var token struct {
+		APIToken  string    `json:"api_token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %s: %w", m.tokenPath, err)
+	}
+	if token.APIToken == "" {
+		return time.Time{}, fmt.Errorf("%s holds no token", m.tokenPath)
+	}
+	return token.ExpiresAt, nil
+}
+
+// Ping checks that the control plane answers its health check.
+func (m *Manager) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.apiURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", m.apiURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s is unhealthy: %s", m.apiURL, resp.Status)
+	}
+	return nil
+}
+
+// ValidateToken checks that the control plane accepts the saved token. It returns ErrUnauthorized if it doesn't.
+func (m *Manager) ValidateToken(ctx context.Context) error {
+	token, err := m.loadToken()
+	if err != nil {
+		return fmt.Errorf("not authenticated: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", m.apiURL+"/v1/instances", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", m.apiURL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	}
+	return fmt.Errorf("API error: %s", resp.Status)
+}
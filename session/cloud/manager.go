@@ -89,16 +89,29 @@ func (m *Manager) ListInstances(ctx context.Context) ([]CloudInstance, error) {
 	return response.Instances, nil
 }
 
+// CreateOptions are the settings of a new cloud instance. Only the tier is required.
+type CreateOptions struct {
+	Tier    string `json:"tier"`
+	Program string `json:"program,omitempty"`
+	RepoURL string `json:"repo_url,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	// Prompt is sent to the program once it starts.
+	Prompt string `json:"prompt,omitempty"`
+}
+
 // CreateInstance creates a new cloud instance
 func (m *Manager) CreateInstance(ctx context.Context, tier string) (*CloudInstance, error) {
+	return m.CreateInstanceWithOptions(ctx, CreateOptions{Tier: tier})
+}
+
+// CreateInstanceWithOptions creates a new cloud instance which runs a program on a branch of a repository
+func (m *Manager) CreateInstanceWithOptions(ctx context.Context, opts CreateOptions) (*CloudInstance, error) {
 	token, err := m.loadToken()
 	if err != nil {
 		return nil, fmt.Errorf("not authenticated: %w", err)
 	}
 
-	reqBody, _ := json.Marshal(map[string]string{
-		"tier": tier,
-	})
+	reqBody, _ := json.Marshal(opts)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", m.apiURL+"/v1/instances", bytes.NewReader(reqBody))
 	if err != nil {
@@ -156,6 +169,40 @@ func (m *Manager) DeleteInstance(ctx context.Context, instanceID string) error {
 	return nil
 }
 
+// PushInstance has a cloud instance commit its work and push its branch, and returns the pushed commit
+func (m *Manager) PushInstance(ctx context.Context, instanceID string) (string, error) {
+	token, err := m.loadToken()
+	if err != nil {
+		return "", fmt.Errorf("not authenticated: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.apiURL+"/v1/instances/"+instanceID+"/push", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to push instance: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error: %s", string(body))
+	}
+
+	var pushed struct {
+		Commit string `json:"commit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pushed); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return pushed.Commit, nil
+}
+
 // GetInstanceWithAttachURL fetches instance details including fresh attach URL
 func (m *Manager) GetInstanceWithAttachURL(ctx context.Context, instanceID string) (*CloudInstance, error) {
 	token, err := m.loadToken()
@@ -0,0 +1,89 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"orzbob/session"
+)
+
+// ErrNotPushed is returned when a cloud instance isn't brought home because its work couldn't be pushed.
+var ErrNotPushed = errors.New("its work couldn't be pushed")
+
+// DefaultTier is the tier of cloud instances when none is chosen or configured.
+const DefaultTier = "small"
+
+// SendToCloud moves a local instance to a new cloud instance of the given tier, which runs the same program on the
+// instance's branch. The instance keeps its title and history. It stays local if the cloud instance can't be
+// created.
+func (m *Manager) SendToCloud(ctx context.Context, instance *session.Instance, tier string) error {
+	return m.SendToCloudWithOptions(ctx, instance, CreateOptions{Tier: tier})
+}
+
+// SendToCloudWithOptions moves a local instance to a new cloud instance like SendToCloud. The program, repository and
+// branch of opts are the instance's, the rest is taken from opts.
+func (m *Manager) SendToCloudWithOptions(ctx context.Context, instance *session.Instance, opts CreateOptions) error {
+	return m.SendToCloudLocking(ctx, instance, opts, &sync.Mutex{})
+}
+
+// SendToCloudLocking is SendToCloudWithOptions for instances of the same repository which are sent at the same time.
+// gitLock is held while the branch is pushed and the worktree removed, so only creating the cloud instance runs
+// concurrently and git never finds the repository locked.
+func (m *Manager) SendToCloudLocking(ctx context.Context, instance *session.Instance, opts CreateOptions,
+	gitLock sync.Locker) error {
+	gitLock.Lock()
+	repoURL, branch, err := instance.PushForCloud()
+	gitLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	opts.Program = instance.Program
+	opts.RepoURL = repoURL
+	opts.Branch = branch
+	created, err := m.CreateInstanceWithOptions(ctx, opts)
+	if err != nil {
+		return err
+	}
+	tier := opts.Tier
+	if created.Tier != "" {
+		tier = created.Tier
+	}
+
+	gitLock.Lock()
+	err = instance.MoveToCloud(created.ID, created.AttachURL, tier, created.Status)
+	gitLock.Unlock()
+	if err != nil {
+		// Don't leave a second copy running in the cloud while the local one keeps going.
+		if deleteErr := m.DeleteInstance(ctx, created.ID); deleteErr != nil {
+			err = fmt.Errorf("%v (failed to delete cloud instance %s: %v)", err, created.ID, deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// BringHome moves a cloud instance which was sent from here back to a local worktree on its branch and deletes the
+// cloud instance. The cloud instance commits and pushes its work first, and it stays in the cloud if it can't, so
+// the work isn't lost.
+func (m *Manager) BringHome(ctx context.Context, instance *session.Instance) error {
+	if _, err := m.PushInstance(ctx, instance.CloudInstanceID); err != nil {
+		return fmt.Errorf("%s stays in the cloud, %w: %v", instance.Title, ErrNotPushed, err)
+	}
+	return m.BringHomeUnpushed(ctx, instance)
+}
+
+// BringHomeUnpushed moves a cloud instance home like BringHome, with only what it pushed so far. Work it didn't push
+// is lost when it's deleted.
+func (m *Manager) BringHomeUnpushed(ctx context.Context, instance *session.Instance) error {
+	id := instance.CloudInstanceID
+	if err := instance.MoveHome(); err != nil {
+		return err
+	}
+	if err := m.DeleteInstance(ctx, id); err != nil {
+		return fmt.Errorf("%s is back home, but cloud instance %s couldn't be deleted: %w", instance.Title, id, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orzbob/log"
+	"orzbob/session"
+	"orzbob/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestSendToCloudAndBringHome(t *testing.T) {
+	for _, bin := range []string{"git", "tmux"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is not installed", bin)
+		}
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	origin := filepath.Join(dir, "origin.git")
+	runGit(t, "init", "-q", "--bare", origin)
+	runGit(t, "init", "-q", "-b", "main", repo)
+	runGit(t, "-C", repo, "commit", "-q", "--allow-empty", "-m", "initial")
+	runGit(t, "-C", repo, "remote", "add", "origin", origin)
+
+	worktree, _, err := git.NewGitWorktree(repo, "move me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := session.FromInstanceData(session.InstanceData{
+		Title:   "move me",
+		Path:    repo,
+		Status:  session.Paused,
+		Program: "sh",
+		Worktree: session.GitWorktreeData{
+			RepoPath:      worktree.GetRepoPath(),
+			WorktreePath:  worktree.GetWorktreePath(),
+			SessionName:   "move me",
+			BranchName:    worktree.GetBranchName(),
+			BaseCommitSHA: worktree.GetBaseCommitSHA(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { instance.Kill() })
+
+	var created CreateOptions
+	var deleted []string
+	pushFails := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/instances/abc/push":
+			if pushFails {
+				w.WriteHeader(http.StatusBadGateway)
+				_, _ = w.Write([]byte(`{"error":"Failed to push the work of the instance: rejected"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"commit": "pushed"})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/instances":
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(CloudInstance{ID: "abc", Status: "Pending", AttachURL: "http://x?token=t"})
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/instances/"):
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v1/instances/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	tokenPath := filepath.Join(dir, "token.json")
+	token, _ := json.Marshal(map[string]any{"api_token": "token", "expires_at": time.Now().Add(time.Hour)})
+	if err := os.WriteFile(tokenPath, token, 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager()
+	m.apiURL = server.URL
+	m.tokenPath = tokenPath
+
+	if err := m.SendToCloud(context.Background(), instance, "small"); err != nil {
+		t.Fatal(err)
+	}
+	want := CreateOptions{Tier: "small", Program: "sh", RepoURL: origin, Branch: "session/move-me"}
+	if created != want {
+		t.Errorf("created cloud instance with %+v, want %+v", created, want)
+	}
+	if !instance.IsCloud || instance.CloudInstanceID != "abc" || instance.CloudTier != "small" {
+		t.Errorf("instance wasn't moved to the cloud: %+v", instance.ToInstanceData())
+	}
+	if instance.Title != "move me" {
+		t.Errorf("title changed to %s", instance.Title)
+	}
+
+	// The cloud instance pushes a commit to the branch.
+	cloudClone := filepath.Join(dir, "cloud")
+	runGit(t, "clone", "-q", "-b", "session/move-me", origin, cloudClone)
+	runGit(t, "-C", cloudClone, "commit", "-q", "--allow-empty", "-m", "from the cloud")
+	runGit(t, "-C", cloudClone, "push", "-q", "origin", "session/move-me")
+	pushed := runGit(t, "-C", cloudClone, "rev-parse", "HEAD")
+
+	// It stays in the cloud while its work can't be pushed.
+	pushFails = true
+	if err := m.BringHome(context.Background(), instance); !errors.Is(err, ErrNotPushed) {
+		t.Fatalf("bringing it home without pushing its work returned %v, want ErrNotPushed", err)
+	}
+	if !instance.IsCloud || len(deleted) != 0 {
+		t.Fatalf("instance was brought home without its work, deleted %v", deleted)
+	}
+	pushFails = false
+
+	if err := m.BringHome(context.Background(), instance); err != nil {
+		t.Fatal(err)
+	}
+	if instance.IsCloud || instance.CloudInstanceID != "" || instance.Paused() {
+		t.Errorf("instance wasn't brought home: %+v", instance.ToInstanceData())
+	}
+	if got := runGit(t, "-C", repo, "rev-parse", "session/move-me"); got != pushed {
+		t.Errorf("local branch is at %s, want the cloud's commit %s", got, pushed)
+	}
+	if len(deleted) != 1 || deleted[0] != "abc" {
+		t.Errorf("deleted cloud instances %v, want [abc]", deleted)
+	}
+}
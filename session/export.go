@@ -0,0 +1,229 @@
+package session
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"orzbob/session/git"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportVersion is the version of the export archive format. Archives from newer versions are refused.
+const exportVersion = 1
+
+// Names of the files in an export archive.
+const (
+	exportManifestName   = "instance.json"
+	exportBundleName     = "branch.bundle"
+	exportTranscriptName = "transcript.txt"
+)
+
+// exportManifest is the instance.json file of an export archive.
+type exportManifest struct {
+	Version  int          `json:"version"`
+	Instance InstanceData `json:"instance"`
+}
+
+// Export writes a gzipped tar archive of the instance which can be imported into another clone of its repository. It
+// holds the instance data with its prompt, a git bundle of the branch since its base commit and its scrollback as a
+// transcript, with the transcript it was imported with. Uncommitted changes are committed first so they're part of
+// the bundle.
+func (i *Instance) Export(w io.Writer) error {
+	if !i.started {
+		return fmt.Errorf("cannot export instance that has not been started")
+	}
+	if i.IsCloud {
+		return fmt.Errorf("cannot export cloud instance %s", i.Title)
+	}
+
+	var transcript string
+	if !i.Paused() {
+		commitMsg := fmt.Sprintf("[orzbob] update from '%s' on %s (exported)", i.Title, time.Now().Format(time.RFC822))
+		if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+			return err
+		}
+	}
+	lines, err := i.ScrollbackLines()
+	if err != nil {
+		return fmt.Errorf("failed to capture transcript: %w", err)
+	}
+	if len(lines) > 0 {
+		transcript = strings.Join(lines, "\n") + "\n"
+	}
+
+	dir, err := os.MkdirTemp("", "orz-export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	bundlePath := filepath.Join(dir, exportBundleName)
+	hasBundle, err := i.gitWorktree.CreateBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(exportManifest{Version: exportVersion, Instance: i.ToInstanceData()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeTarFile(tw, exportManifestName, strings.NewReader(string(manifest)), int64(len(manifest))); err != nil {
+		return err
+	}
+	if hasBundle {
+		f, err := os.Open(bundlePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, exportBundleName, f, info.Size()); err != nil {
+			return err
+		}
+	}
+	if transcript != "" {
+		if err := writeTarFile(tw, exportTranscriptName, strings.NewReader(transcript), int64(len(transcript))); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, r io.Reader, size int64) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ExportArchive is an export archive read back by ReadExport. Data can be changed before importing, e.g. to give the
+// instance another title. Close removes the files unpacked from the archive.
+type ExportArchive struct {
+	Data InstanceData
+	// Transcript is the scrollback of the instance when it was exported, or empty if it had none. Import doesn't save
+	// it, the instance's Transcript has to be set to where it's saved.
+	Transcript string
+
+	dir        string
+	bundlePath string
+}
+
+// ReadExport unpacks an archive written by Export.
+func ReadExport(r io.Reader) (a *ExportArchive, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not an orz export: %w", err)
+	}
+	defer gz.Close()
+
+	dir, err := os.MkdirTemp("", "orz-import-")
+	if err != nil {
+		return nil, err
+	}
+	a = &ExportArchive{dir: dir}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	var manifest *exportManifest
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		switch header.Name {
+		case exportManifestName:
+			manifest = &exportManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", exportManifestName, err)
+			}
+		case exportBundleName:
+			a.bundlePath = filepath.Join(dir, exportBundleName)
+			f, err := os.Create(a.bundlePath)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack %s: %w", exportBundleName, err)
+			}
+		case exportTranscriptName:
+			transcript, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", exportTranscriptName, err)
+			}
+			a.Transcript = string(transcript)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("not an orz export: %s is missing", exportManifestName)
+	}
+	if manifest.Version > exportVersion {
+		return nil, fmt.Errorf("the export was made by a newer version of orz (format %d), please update",
+			manifest.Version)
+	}
+	a.Data = manifest.Instance
+	return a, nil
+}
+
+// Import recreates the exported instance in the repository at repoPath: its branch is created from the bundle and
+// it's returned paused, so resuming it sets up the worktree and starts the program.
+func (a *ExportArchive) Import(repoPath string) (*Instance, error) {
+	data := a.Data
+	if data.Worktree.BranchName == "" {
+		return nil, fmt.Errorf("the export of %s has no branch", data.Title)
+	}
+	worktree, err := git.NewGitWorktreeFromBundle(repoPath, data.Title, data.Worktree.BranchName,
+		data.Worktree.BaseCommitSHA, a.bundlePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Path = worktree.GetRepoPath()
+	data.Status = Paused
+	// The transcript file of the exported instance is on the machine it was exported from.
+	data.Transcript = ""
+	// Only where the worktree lives changes, what it was started from and checks out is kept.
+	data.Worktree.RepoPath = worktree.GetRepoPath()
+	data.Worktree.WorktreePath = worktree.GetWorktreePath()
+	data.Worktree.SessionName = data.Title
+	data.Worktree.BranchName = worktree.GetBranchName()
+	data.Worktree.BaseCommitSHA = worktree.GetBaseCommitSHA()
+	return FromInstanceData(data)
+}
+
+// Close removes the files unpacked from the archive.
+func (a *ExportArchive) Close() error {
+	return os.RemoveAll(a.dir)
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"bytes"
+	"orzbob/log"
+	"orzbob/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestExportImport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	runGit(t, "init", "-q", "-b", "main", repo)
+	runGit(t, "-C", repo, "commit", "-q", "--allow-empty", "-m", "initial")
+	// The teammate's clone has the base commit but not the session's branch.
+	clone := filepath.Join(dir, "clone")
+	runGit(t, "clone", "-q", repo, clone)
+
+	worktree, _, err := git.NewGitWorktree(repo, "fix login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "fix.txt"), []byte("fixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.CommitChanges("fix"); err != nil {
+		t.Fatal(err)
+	}
+	head := runGit(t, "-C", repo, "rev-parse", worktree.GetBranchName())
+	transcript := filepath.Join(dir, "transcript.txt")
+	if err := os.WriteFile(transcript, []byte("$ make test\nok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	created := true
+	instance, err := FromInstanceData(InstanceData{
+		Title:   "fix login",
+		Path:    repo,
+		Status:  Paused,
+		Program: "claude",
+		Prompt:  "fix the login form",
+		Tags:    []string{"auth"},
+		// It was imported itself, and its transcript is passed on although it's paused.
+		Transcript: transcript,
+		Worktree: GitWorktreeData{
+			RepoPath:       worktree.GetRepoPath(),
+			WorktreePath:   worktree.GetWorktreePath(),
+			SessionName:    "fix login",
+			BranchName:     worktree.GetBranchName(),
+			BaseCommitSHA:  worktree.GetBaseCommitSHA(),
+			BaseRef:        "main",
+			SparsePatterns: []string{"services/api"},
+			CreatedBranch:  &created,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := instance.Export(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() *ExportArchive {
+		a, err := ReadExport(bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { a.Close() })
+		return a
+	}
+
+	a := read()
+	a.Data.Title = "fix login 2"
+	imported, err := a.Import(clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runGit(t, "-C", clone, "rev-parse", "refs/heads/session/fix-login"); got != head {
+		t.Errorf("imported branch is at %s, want %s", got, head)
+	}
+	if !imported.Paused() {
+		t.Error("expected the imported instance to be paused")
+	}
+	if imported.Title != "fix login 2" || imported.Prompt != "fix the login form" || imported.Program != "claude" ||
+		len(imported.Tags) != 1 {
+		t.Errorf("instance data wasn't kept: %+v", imported.ToInstanceData())
+	}
+	kept := imported.ToInstanceData().Worktree
+	if kept.BaseRef != "main" || !reflect.DeepEqual(kept.SparsePatterns, []string{"services/api"}) ||
+		kept.CreatedBranch == nil || !*kept.CreatedBranch {
+		t.Errorf("worktree data wasn't kept: %+v", kept)
+	}
+	if a.Transcript != "$ make test\nok\n" || imported.Transcript != "" {
+		t.Errorf("transcript %q was imported as %q", a.Transcript, imported.Transcript)
+	}
+	imported.Transcript = transcript
+	if lines, err := imported.ScrollbackLines(); err != nil || !imported.HasScrollback() ||
+		!reflect.DeepEqual(lines, []string{"$ make test", "ok"}) {
+		t.Errorf("the paused instance's scrollback is %q (%v), want its transcript", lines, err)
+	}
+	cloneRoot, _ := filepath.EvalSymlinks(clone)
+	if got, _ := filepath.EvalSymlinks(imported.Path); got != cloneRoot {
+		t.Errorf("imported instance path is %s, want %s", imported.Path, clone)
+	}
+
+	if _, err := read().Import(clone); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("importing a branch twice should fail, got %v", err)
+	}
+}
+
+func TestReadExportRejectsOtherFiles(t *testing.T) {
+	if _, err := ReadExport(strings.NewReader("not an archive")); err == nil {
+		t.Error("expected an error reading something which isn't an archive")
+	}
+}
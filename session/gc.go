@@ -0,0 +1,254 @@
+package session
+
+import (
+	"fmt"
+	"io/fs"
+	"orzbob/session/git"
+	"orzbob/session/tmux"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// OrphanKind is the kind of thing left behind without an instance.
+type OrphanKind string
+
+const (
+	// OrphanWorktree is a directory in the worktree directory which no instance uses.
+	OrphanWorktree OrphanKind = "worktree"
+	// OrphanBranch is a branch created by orz which no instance uses.
+	OrphanBranch OrphanKind = "branch"
+	// OrphanSession is a tmux session started by orz which no instance uses.
+	OrphanSession OrphanKind = "tmux session"
+	// OrphanStaleEntry is a git worktree entry whose directory is gone.
+	OrphanStaleEntry OrphanKind = "stale worktree entry"
+)
+
+// Orphan is something left behind by orz that no instance uses any more, e.g. after a crash.
+type Orphan struct {
+	Kind OrphanKind
+	// Name is the path, branch, session or entry.
+	Name string
+	// Repo is the repository of branches and stale entries.
+	Repo string
+	// Size is the disk usage of worktrees.
+	Size int64
+}
+
+func (o Orphan) String() string {
+	switch o.Kind {
+	case OrphanWorktree:
+		return fmt.Sprintf("%s %s (%s)", o.Kind, o.Name, FormatSize(o.Size))
+	case OrphanBranch, OrphanStaleEntry:
+		return fmt.Sprintf("%s %s in %s", o.Kind, o.Name, o.Repo)
+	}
+	return fmt.Sprintf("%s %s", o.Kind, o.Name)
+}
+
+// Remove deletes the orphan.
+func (o Orphan) Remove() error {
+	switch o.Kind {
+	case OrphanWorktree:
+		return os.RemoveAll(o.Name)
+	case OrphanBranch:
+		// A branch can't be deleted while a worktree entry has it checked out, even if the worktree is gone.
+		if err := git.PruneWorktrees(o.Repo); err != nil {
+			return err
+		}
+		return git.DeleteBranch(o.Repo, o.Name)
+	case OrphanSession:
+		return tmux.KillSession(o.Name)
+	case OrphanStaleEntry:
+		// git can only prune every stale entry of a repository at once, which is what we want anyway.
+		return git.PruneWorktrees(o.Repo)
+	}
+	return fmt.Errorf("unknown orphan kind %q", o.Kind)
+}
+
+// WorktreeUsage is the disk usage of the worktree of an instance.
+type WorktreeUsage struct {
+	Instance *Instance
+	Path     string
+	Size     int64
+}
+
+// Garbage is what FindGarbage found: how much disk the worktrees of instances use, and what was left behind.
+type Garbage struct {
+	Usage   []WorktreeUsage
+	Orphans []Orphan
+}
+
+// FindGarbage measures the worktrees of the instances and looks for orphans like FindOrphans does.
+func FindGarbage(instances []*Instance, repoPaths []string) (*Garbage, error) {
+	garbage := &Garbage{}
+	data := make([]InstanceData, 0, len(instances))
+	for _, instance := range instances {
+		data = append(data, instance.ToInstanceData())
+		if instance.gitWorktree == nil || instance.gitWorktree.GetRepoPath() == "" {
+			continue
+		}
+		path := instance.gitWorktree.GetWorktreePath()
+		size, err := DiskUsage(path)
+		if err != nil {
+			return nil, err
+		}
+		garbage.Usage = append(garbage.Usage, WorktreeUsage{Instance: instance, Path: path, Size: size})
+	}
+	sort.SliceStable(garbage.Usage, func(a, b int) bool {
+		return garbage.Usage[a].Size > garbage.Usage[b].Size
+	})
+
+	orphans, err := FindOrphans(data, repoPaths)
+	if err != nil {
+		return nil, err
+	}
+	garbage.Orphans = orphans
+	return garbage, nil
+}
+
+// FindOrphans looks for worktree directories, branches and tmux sessions none of the stored instances use, and for
+// stale git worktree entries. Branches and entries are looked for in the repositories of the instances and in
+// repoPaths. Orphans are in an order they can be removed in. Unlike loading the instances, it doesn't start anything.
+func FindOrphans(instances []InstanceData, repoPaths []string) ([]Orphan, error) {
+	var orphans []Orphan
+	usedPaths := make(map[string]bool)
+	usedBranches := make(map[string]bool)
+	usedSessions := make(map[string]bool)
+	repos := make(map[string]bool)
+	for _, path := range repoPaths {
+		if root, err := git.RepoRoot(path); err == nil {
+			repos[root] = true
+		}
+	}
+
+	for _, instance := range instances {
+		usedSessions[tmux.SessionName(instance.Title)] = true
+		repo := instance.Worktree.RepoPath
+		if repo == "" {
+			continue
+		}
+		repos[repo] = true
+		usedBranches[repo+"\x00"+instance.Worktree.BranchName] = true
+		usedPaths[instance.Worktree.WorktreePath] = true
+	}
+
+	worktreeDir, err := git.WorktreeDirectory()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(worktreeDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read worktree directory: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(worktreeDir, entry.Name())
+		if !entry.IsDir() || usedPaths[path] {
+			continue
+		}
+		size, err := DiskUsage(path)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, Orphan{Kind: OrphanWorktree, Name: path, Size: size})
+	}
+
+	sortedRepos := make([]string, 0, len(repos))
+	for repo := range repos {
+		sortedRepos = append(sortedRepos, repo)
+	}
+	sort.Strings(sortedRepos)
+	for _, repo := range sortedRepos {
+		if _, err := os.Stat(repo); err != nil {
+			// The repository was moved or deleted along with its branches.
+			continue
+		}
+		stale, err := git.StaleWorktrees(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range stale {
+			orphans = append(orphans, Orphan{Kind: OrphanStaleEntry, Name: entry, Repo: repo})
+		}
+		branches, err := git.SessionBranches(repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range branches {
+			if !usedBranches[repo+"\x00"+branch] {
+				orphans = append(orphans, Orphan{Kind: OrphanBranch, Name: branch, Repo: repo})
+			}
+		}
+	}
+
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sessions {
+		if !usedSessions[name] {
+			orphans = append(orphans, Orphan{Kind: OrphanSession, Name: name})
+		}
+	}
+	return orphans, nil
+}
+
+// DiskUsage returns the total size of the files under path. A missing path uses nothing.
+func DiskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				// Count what can be read rather than failing on a file removed while walking.
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// FormatSize formats a number of bytes for display, e.g. 12K or 1.5G.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	units := "KMGTP"
+	i := -1
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[i])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[i])
+}
+
+// DiskUsage returns the disk usage of the worktree of the instance, as last set by SetDiskUsage.
+func (i *Instance) DiskUsage() int64 {
+	return i.diskUsage
+}
+
+// SetDiskUsage sets the disk usage of the worktree of the instance.
+func (i *Instance) SetDiskUsage(size int64) {
+	i.diskUsage = size
+}
+
+// WorktreePath returns the path of the worktree of the instance, or an empty string if it has none.
+func (i *Instance) WorktreePath() string {
+	if i.gitWorktree == nil {
+		return ""
+	}
+	return i.gitWorktree.GetWorktreePath()
+}
@@ -0,0 +1,127 @@
+package session
+
+import (
+	"orzbob/log"
+	"orzbob/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1536, "1.5K"},
+		{20 * 1024 * 1024, "20M"},
+		{3 * 1024 * 1024 * 1024 / 2, "1.5G"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestFindGarbage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	runGit(t, "init", "-q", "-b", "main", repo)
+	runGit(t, "-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "--allow-empty", "-m", "initial")
+
+	kept, _, err := git.NewGitWorktree(repo, "kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kept.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(kept.GetWorktreePath(), "data"), make([]byte, 300), 0644); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := FromInstanceData(InstanceData{
+		Title:  "kept",
+		Status: Paused,
+		Worktree: GitWorktreeData{
+			RepoPath:     kept.GetRepoPath(),
+			WorktreePath: kept.GetWorktreePath(),
+			BranchName:   kept.GetBranchName(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An instance whose worktree directory was deleted without telling git, and whose state is gone.
+	gone, _, err := git.NewGitWorktree(repo, "gone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gone.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(gone.GetWorktreePath()); err != nil {
+		t.Fatal(err)
+	}
+	// A worktree directory git doesn't know about.
+	leftover := filepath.Join(filepath.Dir(kept.GetWorktreePath()), "leftover")
+	if err := os.MkdirAll(leftover, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(leftover, "file"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	garbage, err := FindGarbage([]*Instance{instance}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(garbage.Usage) != 1 || garbage.Usage[0].Instance != instance || garbage.Usage[0].Size < 300 {
+		t.Errorf("unexpected usage %+v", garbage.Usage)
+	}
+	found := make(map[OrphanKind][]Orphan)
+	for _, orphan := range garbage.Orphans {
+		found[orphan.Kind] = append(found[orphan.Kind], orphan)
+	}
+	if got := found[OrphanWorktree]; len(got) != 1 || got[0].Name != leftover || got[0].Size != 100 {
+		t.Errorf("orphaned worktrees = %+v, want %s", got, leftover)
+	}
+	if got := found[OrphanBranch]; len(got) != 1 || got[0].Name != "session/gone" {
+		t.Errorf("orphaned branches = %+v, want session/gone", got)
+	}
+	if got := found[OrphanStaleEntry]; len(got) != 1 || !strings.Contains(got[0].Name, "gone") {
+		t.Fatalf("stale entries = %+v, want the one of gone", got)
+	}
+
+	for _, orphan := range garbage.Orphans {
+		if orphan.Kind == OrphanSession {
+			// Other orz sessions may be running on this machine.
+			continue
+		}
+		if err := orphan.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	garbage, err = FindGarbage([]*Instance{instance}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, orphan := range garbage.Orphans {
+		if orphan.Kind != OrphanSession {
+			t.Errorf("%s is left after removing the orphans", orphan)
+		}
+	}
+}
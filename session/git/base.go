@@ -0,0 +1,111 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// pullRequestPattern matches the ways a pull request can be named as a base: #123, pr:123, pr/123, or the URL of
+// a pull request on GitHub or Gitea, or a merge request on GitLab.
+var pullRequestPattern = regexp.MustCompile(`^(?:#|pr[:/])(\d+)$|/(?:pull|pulls|merge_requests)/(\d+)/?$`)
+
+// pullRequestNumber returns the number of the pull request named by base, if it names one.
+func pullRequestNumber(base string) (int, bool) {
+	match := pullRequestPattern.FindStringSubmatch(strings.ToLower(base))
+	if match == nil {
+		return 0, false
+	}
+	digits := match[1] + match[2]
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// ResolveBase fetches what base names from origin and returns the commit it points to. base is a local or remote
+// branch, a tag, a commit SHA or a pull request (see pullRequestNumber). Names which exist locally and aren't remote
+// branches are used as they are, so no network is needed for them.
+func ResolveBase(repoPath, base string) (string, error) {
+	if n, ok := pullRequestNumber(base); ok {
+		return fetchPullRequest(repoPath, n)
+	}
+
+	if remoteBranch, ok := strings.CutPrefix(base, "origin/"); ok {
+		// Bring the remote branch up to date. It may be a local branch named origin/..., so a failure isn't fatal.
+		_, _ = RunGitCommand(repoPath, "fetch", "--quiet", "origin",
+			"+refs/heads/"+remoteBranch+":refs/remotes/origin/"+remoteBranch)
+	}
+	if sha, err := revParseCommit(repoPath, base); err == nil {
+		return sha, nil
+	}
+
+	// Fetch into a ref of its own rather than reading FETCH_HEAD, which instances started at the same time share.
+	ref := fmt.Sprintf("refs/orzbob/fetch/%d-%d", os.Getpid(), fetchCount.Add(1))
+	if _, err := RunGitCommand(repoPath, "fetch", "--quiet", "--no-write-fetch-head", "origin",
+		"+"+base+":"+ref); err != nil {
+		return "", fmt.Errorf("%s is no branch, tag or commit here or on origin: %w", base, err)
+	}
+	defer RunGitCommand(repoPath, "update-ref", "-d", ref)
+	return revParseCommit(repoPath, ref)
+}
+
+// fetchCount numbers the refs ResolveBase fetches into.
+var fetchCount atomic.Int64
+
+// fetchPullRequest fetches the head of pull request n from origin and returns its commit.
+func fetchPullRequest(repoPath string, n int) (string, error) {
+	g := &GitWorktree{repoPath: repoPath}
+	forge, err := g.Forge()
+	if err != nil {
+		return "", err
+	}
+	ref, err := forge.PullRequestRef(n)
+	if err != nil {
+		return "", err
+	}
+	// Keep the head under refs/orzbob, so it isn't garbage collected while the instance is based on it.
+	local := fmt.Sprintf("refs/orzbob/pull/%d", n)
+	if _, err := RunGitCommand(repoPath, "fetch", "--quiet", "origin", "+"+ref+":"+local); err != nil {
+		return "", fmt.Errorf("failed to fetch pull request %d from %s: %w", n, forge.Name(), err)
+	}
+	return revParseCommit(repoPath, local)
+}
+
+func revParseCommit(repoPath, ref string) (string, error) {
+	output, err := RunGitCommand(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%s is not a commit: %w", ref, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// SetBase makes SetupNewWorktree start the branch from base instead of HEAD, see ResolveBase. It's recorded as the
+// base ref of the worktree.
+func (g *GitWorktree) SetBase(base string) {
+	g.baseRef = base
+}
+
+// GetBaseRef returns what the branch was started from, as it was given, or "" if it was started from HEAD or
+// continues an existing branch.
+func (g *GitWorktree) GetBaseRef() string {
+	return g.baseRef
+}
+
+// ensureBranch makes sure the branch exists locally, creating it from the branch of the same name on origin if it
+// only exists there.
+func (g *GitWorktree) ensureBranch() error {
+	if _, err := revParseCommit(g.repoPath, "refs/heads/"+g.branchName); err == nil {
+		return nil
+	}
+	remoteRef := "refs/remotes/origin/" + g.branchName
+	if _, err := g.runGitCommand(g.repoPath, "fetch", "--quiet", "origin",
+		"+refs/heads/"+g.branchName+":"+remoteRef); err != nil {
+		return fmt.Errorf("branch %s doesn't exist here or on origin: %w", g.branchName, err)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "branch", "--track", g.branchName, "origin/"+g.branchName); err != nil {
+		return fmt.Errorf("failed to create branch %s from origin: %w", g.branchName, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPullRequestNumber(t *testing.T) {
+	tests := []struct {
+		base string
+		n    int
+		ok   bool
+	}{
+		{"#123", 123, true},
+		{"pr:7", 7, true},
+		{"PR/42", 42, true},
+		{"https://github.com/owner/repo/pull/15", 15, true},
+		{"https://gitlab.com/group/repo/-/merge_requests/9/", 9, true},
+		{"https://codeberg.org/owner/repo/pulls/3", 3, true},
+		{"main", 0, false},
+		{"origin/pr/12-fix", 0, false},
+		{"v1.2.3", 0, false},
+		{"#abc", 0, false},
+	}
+	for _, tt := range tests {
+		n, ok := pullRequestNumber(tt.base)
+		if n != tt.n || ok != tt.ok {
+			t.Errorf("pullRequestNumber(%q) = %d, %v, want %d, %v", tt.base, n, ok, tt.n, tt.ok)
+		}
+	}
+}
+
+// commitOn makes an empty commit on top of HEAD on a new branch of the repository, and returns its SHA.
+func commitOn(t *testing.T, repo, branch, message string) string {
+	t.Helper()
+	output, err := RunGitCommand(repo, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", message, "-p", "HEAD", "HEAD^{tree}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := strings.TrimSpace(output)
+	if _, err := RunGitCommand(repo, "branch", branch, sha); err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+func TestResolveBase(t *testing.T) {
+	g, _ := setupRenameRepo(t, "base")
+	repo := g.repoPath
+	if _, err := RunGitCommand(repo, "config", ForgeConfigKey, ForgeGitHub); err != nil {
+		t.Fatal(err)
+	}
+
+	local := commitOn(t, repo, "local", "local")
+	if _, err := RunGitCommand(repo, "tag", "v1.0.0", local); err != nil {
+		t.Fatal(err)
+	}
+	remoteOnly := commitOn(t, repo, "remote-only", "remote only")
+	pr := commitOn(t, repo, "pr-head", "pull request")
+	for _, refspec := range []string{"remote-only:refs/heads/remote-only", "pr-head:refs/pull/5/head"} {
+		if _, err := RunGitCommand(repo, "push", "-q", "origin", refspec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, branch := range []string{"remote-only", "pr-head"} {
+		if _, err := RunGitCommand(repo, "branch", "-D", branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"local", local},
+		{"v1.0.0", local},
+		{local[:10], local},
+		{"remote-only", remoteOnly},
+		{"origin/remote-only", remoteOnly},
+		{"#5", pr},
+		{"https://github.com/owner/repo/pull/5", pr},
+	}
+	for _, tt := range tests {
+		got, err := ResolveBase(repo, tt.base)
+		if err != nil {
+			t.Errorf("ResolveBase(%q) failed: %v", tt.base, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveBase(%q) = %s, want %s", tt.base, got, tt.want)
+		}
+	}
+
+	if _, err := ResolveBase(repo, "missing"); err == nil {
+		t.Error("a missing base was resolved")
+	}
+	if refs, err := RunGitCommand(repo, "for-each-ref", "refs/orzbob/fetch"); err != nil || refs != "" {
+		t.Errorf("fetched refs were left behind: %q (%v)", refs, err)
+	}
+	if _, err := RunGitCommand(repo, "config", ForgeConfigKey, ForgeGit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveBase(repo, "#5"); err == nil {
+		t.Error("a pull request was resolved on a remote without pull requests")
+	}
+}
+
+func TestSetupFromBase(t *testing.T) {
+	g, _ := setupRenameRepo(t, "first")
+	repo := g.repoPath
+	tip := commitOn(t, repo, "feature", "feature")
+	if _, err := RunGitCommand(repo, "push", "-q", "origin", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(repo, "branch", "-D", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	started, _, err := NewGitWorktreeWithBranch(repo, "started", "session/started")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started.SetBase("origin/feature")
+	if err := started.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if started.GetBaseCommitSHA() != tip || started.GetBaseRef() != "origin/feature" {
+		t.Errorf("started from %s (%s), want %s (origin/feature)", started.GetBaseCommitSHA(), started.GetBaseRef(),
+			tip)
+	}
+	if head, err := RunGitCommand(started.GetWorktreePath(), "rev-parse", "HEAD"); err != nil ||
+		strings.TrimSpace(head) != tip {
+		t.Errorf("the worktree is at %q (%v), want %s", head, err, tip)
+	}
+
+	// Continuing the branch creates it from origin, since it only exists there.
+	continued, _, err := NewGitWorktreeWithBranch(repo, "continued", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := continued.SetupFromExistingBranch(); err != nil {
+		t.Fatal(err)
+	}
+	current, err := RunGitCommand(continued.GetWorktreePath(), "branch", "--show-current")
+	if err != nil || strings.TrimSpace(current) != "feature" {
+		t.Errorf("the worktree has %q (%v) checked out, want feature", current, err)
+	}
+	if upstream, err := RunGitCommand(repo, "rev-parse", "--abbrev-ref", "feature@{upstream}"); err != nil ||
+		strings.TrimSpace(upstream) != "origin/feature" {
+		t.Errorf("feature tracks %q (%v), want origin/feature", upstream, err)
+	}
+	if continued.GetBaseCommitSHA() != tip {
+		t.Errorf("the continued branch is based on %s, want its tip %s", continued.GetBaseCommitSHA(), tip)
+	}
+}
+
+func TestCreateBranchWithoutWorktree(t *testing.T) {
+	g, _ := setupRenameRepo(t, "first")
+	repo := g.repoPath
+	tip := commitOn(t, repo, "feature", "feature")
+
+	cloud, _, err := NewGitWorktreeWithBranch(repo, "cloud", "session/cloud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud.SetBase("feature")
+	if err := cloud.CreateBranch(); err != nil {
+		t.Fatal(err)
+	}
+	if sha, err := revParseCommit(repo, "refs/heads/session/cloud"); err != nil || sha != tip {
+		t.Errorf("session/cloud is at %q (%v), want %s", sha, err, tip)
+	}
+	if cloud.GetBaseCommitSHA() != tip || !cloud.CreatedBranch() {
+		t.Errorf("based on %s, created %v, want %s and true", cloud.GetBaseCommitSHA(), cloud.CreatedBranch(), tip)
+	}
+	if _, err := os.Stat(cloud.GetWorktreePath()); !os.IsNotExist(err) {
+		t.Errorf("a worktree was created at %s", cloud.GetWorktreePath())
+	}
+
+	// The worktree is checked out from the branch once it comes here.
+	if err := cloud.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.GetBaseCommitSHA() != tip {
+		t.Errorf("setting up the worktree moved the base to %s, want %s", cloud.GetBaseCommitSHA(), tip)
+	}
+}
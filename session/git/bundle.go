@@ -0,0 +1,89 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CommitChanges commits any uncommitted changes in the worktree without pushing them. Changes inside submodules are
+// committed in the submodules first, so the commit records where they are now.
+func (g *GitWorktree) CommitChanges(commitMessage string) error {
+	isDirty, err := g.IsDirty()
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if !isDirty {
+		return nil
+	}
+	if err := g.commitSubmodules(commitMessage); err != nil {
+		return err
+	}
+	if _, err := g.runGitCommand(g.worktreePath, g.stageArgs(".")...); err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	if _, err := g.runGitCommand(g.worktreePath, "commit", "-m", commitMessage, "--no-verify"); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return nil
+}
+
+// CreateBundle writes a git bundle with the commits of the branch since its base commit to path. It returns false
+// without writing anything if the branch has no commits of its own, since git can't bundle nothing.
+func (g *GitWorktree) CreateBundle(path string) (bool, error) {
+	if g.baseCommitSHA == "" {
+		return false, fmt.Errorf("the base commit of branch %s is unknown", g.branchName)
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-list", "--count", g.baseCommitSHA+".."+g.branchName)
+	if err != nil {
+		return false, fmt.Errorf("failed to count the commits of branch %s: %w", g.branchName, err)
+	}
+	if strings.TrimSpace(output) == "0" {
+		return false, nil
+	}
+	if _, err := g.runGitCommand(g.repoPath, "bundle", "create", path, g.branchName, "^"+g.baseCommitSHA); err != nil {
+		return false, fmt.Errorf("failed to bundle branch %s: %w", g.branchName, err)
+	}
+	return true, nil
+}
+
+// NewGitWorktreeFromBundle creates the branch of an exported session in the repository at repoPath and returns a
+// worktree for it, which isn't set up yet. The branch is fetched from the bundle at bundlePath, or starts at the base
+// commit if bundlePath is empty because the branch had no commits of its own.
+func NewGitWorktreeFromBundle(repoPath, sessionName, branchName, baseCommitSHA, bundlePath string) (*GitWorktree,
+	error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	repoPath, err = findGitRepoRoot(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := RunGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branchName); err == nil {
+		return nil, fmt.Errorf("branch %s already exists in %s", branchName, repoPath)
+	}
+	// The bundle only has the commits since the base commit, so the base has to be here already.
+	if _, err := RunGitCommand(repoPath, "cat-file", "-e", baseCommitSHA+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("base commit %s is missing from %s, fetch it first", baseCommitSHA, repoPath)
+	}
+
+	if bundlePath != "" {
+		if _, err := RunGitCommand(repoPath, "bundle", "verify", "--quiet", bundlePath); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		ref := "refs/heads/" + branchName
+		if _, err := RunGitCommand(repoPath, "fetch", "--quiet", bundlePath, ref+":"+ref); err != nil {
+			return nil, fmt.Errorf("failed to fetch branch %s from the bundle: %w", branchName, err)
+		}
+	} else if _, err := RunGitCommand(repoPath, "branch", branchName, baseCommitSHA); err != nil {
+		return nil, fmt.Errorf("failed to create branch %s: %w", branchName, err)
+	}
+
+	worktreePath, err := newWorktreePath(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	return NewGitWorktreeFromStorage(repoPath, worktreePath, sessionName, branchName, baseCommitSHA), nil
+}
@@ -26,13 +26,14 @@ func (g *GitWorktree) Diff() *DiffStats {
 	stats := &DiffStats{}
 
 	// -N stages untracked files (intent to add), including them in the diff
-	_, err := g.runGitCommand(g.worktreePath, "add", "-N", ".")
+	_, err := g.runGitCommand(g.worktreePath, g.stageArgs("-N", ".")...)
 	if err != nil {
 		stats.Error = err
 		return stats
 	}
 
-	content, err := g.runGitCommand(g.worktreePath, "--no-pager", "diff", g.GetBaseCommitSHA())
+	// Changes in submodules are shown as the changes of their files, rather than as changed commit hashes.
+	content, err := g.runGitCommand(g.worktreePath, "--no-pager", "diff", "--submodule=diff", g.GetBaseCommitSHA())
 	if err != nil {
 		stats.Error = err
 		return stats
@@ -0,0 +1,384 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ForgeConfigKey is the git config key which overrides the forge detected from the remote URL, e.g. for a
+// self-hosted GitLab whose host name doesn't say so: git config orzbob.forge gitlab
+const ForgeConfigKey = "orzbob.forge"
+
+const (
+	ForgeGitHub = "github"
+	ForgeGitLab = "gitlab"
+	ForgeGitea  = "gitea"
+	ForgeGit    = "git"
+
+	// forgeAPITimeout is how long a forge's API gets to answer a request.
+	forgeAPITimeout = 30 * time.Second
+)
+
+// ErrPullRequestsUnsupported is returned when pull requests are opened on a remote which isn't hosted on a known
+// forge.
+var ErrPullRequestsUnsupported = errors.New("the remote isn't on a forge orz knows, so pull requests can't be opened")
+
+// PullRequest is a pull request (or merge request, on GitLab) to open.
+type PullRequest struct {
+	// Branch is the branch with the changes.
+	Branch string
+	// Base is the branch the changes should be merged into.
+	Base  string
+	Title string
+	Body  string
+	Draft bool
+}
+
+// Forge is where the origin remote of a repository is hosted. Pushing only needs git, while linking to branches and
+// opening pull requests depend on the forge.
+type Forge interface {
+	// Name is the name of the forge shown to the user.
+	Name() string
+	// Push pushes the branch from the repository at dir to origin and sets it as its upstream.
+	Push(dir, branch string) error
+	// BranchURL returns the web page of the branch.
+	BranchURL(branch string) (string, error)
+	// CreatePullRequest opens a pull request from the repository at dir and returns its URL.
+	CreatePullRequest(dir string, pr PullRequest) (string, error)
+	// PullRequestRef returns the ref on origin which holds the head of pull request n.
+	PullRequestRef(n int) (string, error)
+}
+
+// remote is the parsed URL of a git remote.
+type remote struct {
+	// host is the host name without a port. It's empty for remotes on the local file system.
+	host string
+	// path is the path of the repository on the host without .git, e.g. owner/repo.
+	path string
+	// web is the URL of the repository's web page, e.g. https://github.com/owner/repo.
+	web string
+}
+
+// parseRemote parses the URL of a remote, which is either a URL like https://host/owner/repo.git or
+// ssh://git@host:22/owner/repo.git, or scp-like like git@host:owner/repo.git.
+func parseRemote(rawURL string) (remote, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		// scp-like syntax, unless it's a local path.
+		colon := strings.Index(rawURL, ":")
+		if colon < 0 || strings.Contains(rawURL[:colon], "/") {
+			return remote{path: rawURL}, nil
+		}
+		host := rawURL[:colon]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path := trimRepoPath(rawURL[colon+1:])
+		return remote{host: host, path: path, web: "https://" + host + "/" + path}, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return remote{}, fmt.Errorf("failed to parse remote URL %q: %w", rawURL, err)
+	}
+	if u.Scheme == "file" {
+		return remote{path: u.Path}, nil
+	}
+	path := trimRepoPath(u.Path)
+	web := "https://" + u.Hostname() + "/" + path
+	if u.Scheme == "http" || u.Scheme == "https" {
+		// Keep the port, which is the web server's port as well.
+		web = u.Scheme + "://" + u.Host + "/" + path
+	}
+	return remote{host: u.Hostname(), path: path, web: web}, nil
+}
+
+func trimRepoPath(path string) string {
+	return strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// detectForge returns the kind of forge hosting the remote, from the override if it's set and from the host name
+// otherwise. Self-hosted instances are recognized if their host name mentions the forge.
+func detectForge(r remote, override string) (string, error) {
+	switch override {
+	case ForgeGitHub, ForgeGitLab, ForgeGitea, ForgeGit:
+		return override, nil
+	case "forgejo":
+		return ForgeGitea, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown forge %q in %s, use %s, %s, %s or %s", override, ForgeConfigKey,
+			ForgeGitHub, ForgeGitLab, ForgeGitea, ForgeGit)
+	}
+
+	host := strings.ToLower(r.host)
+	switch {
+	case host == "":
+		return ForgeGit, nil
+	case strings.Contains(host, "github"):
+		return ForgeGitHub, nil
+	case strings.Contains(host, "gitlab"):
+		return ForgeGitLab, nil
+	case host == "codeberg.org" || strings.Contains(host, "gitea") || strings.Contains(host, "forgejo"):
+		return ForgeGitea, nil
+	}
+	return ForgeGit, nil
+}
+
+// newForge returns the forge for the remote URL.
+func newForge(remoteURL, override string) (Forge, error) {
+	r, err := parseRemote(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := detectForge(r, override)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case ForgeGitHub:
+		return githubForge{remote: r}, nil
+	case ForgeGitLab:
+		return gitlabForge{remote: r, apiURL: apiBase(r.web, r.path), token: firstEnv("GITLAB_TOKEN", "GL_TOKEN")}, nil
+	case ForgeGitea:
+		return giteaForge{remote: r, apiURL: apiBase(r.web, r.path), token: firstEnv("GITEA_TOKEN", "FORGEJO_TOKEN")},
+			nil
+	}
+	return plainForge{remote: r}, nil
+}
+
+// Forge returns the forge hosting the origin remote of the repository.
+func (g *GitWorktree) Forge() (Forge, error) {
+	remoteURL, err := g.RemoteURL()
+	if err != nil {
+		return nil, err
+	}
+	// git config exits with 1 if the key isn't set.
+	override, _ := g.runGitCommand(g.repoPath, "config", "--get", ForgeConfigKey)
+	return newForge(remoteURL, strings.ToLower(strings.TrimSpace(override)))
+}
+
+// apiBase returns the scheme and host of the web page of a repository, where the forge's API is served.
+func apiBase(web, path string) string {
+	return strings.TrimSuffix(web, "/"+path)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// gitPush pushes the branch to origin with git alone. If the repository has submodules, it refuses to push commits
+// which refer to submodule commits their remotes don't have.
+func gitPush(dir, branch string) error {
+	args := []string{"push", "-u", "origin", branch}
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); err == nil {
+		args = append(args, "--recurse-submodules=check")
+	}
+	if _, err := RunGitCommand(dir, args...); err != nil {
+		return fmt.Errorf("failed to push branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// plainForge is a remote on a host orz doesn't know. Branches are pushed with git, and there are no web pages or
+// pull requests.
+type plainForge struct {
+	remote remote
+}
+
+func (f plainForge) Name() string { return "git" }
+
+func (f plainForge) Push(dir, branch string) error { return gitPush(dir, branch) }
+
+func (f plainForge) BranchURL(branch string) (string, error) {
+	return "", fmt.Errorf("the branch has no web page, origin isn't on a forge orz knows. Set %s if it is",
+		ForgeConfigKey)
+}
+
+func (f plainForge) CreatePullRequest(dir string, pr PullRequest) (string, error) {
+	return "", ErrPullRequestsUnsupported
+}
+
+func (f plainForge) PullRequestRef(n int) (string, error) {
+	return "", ErrPullRequestsUnsupported
+}
+
+// githubForge is a remote on GitHub. Pull requests are opened with the gh CLI.
+type githubForge struct {
+	remote remote
+}
+
+func (f githubForge) Name() string { return "GitHub" }
+
+func (f githubForge) Push(dir, branch string) error { return gitPush(dir, branch) }
+
+func (f githubForge) BranchURL(branch string) (string, error) {
+	return f.remote.web + "/tree/" + branch, nil
+}
+
+func (f githubForge) PullRequestRef(n int) (string, error) {
+	return fmt.Sprintf("refs/pull/%d/head", n), nil
+}
+
+func (f githubForge) CreatePullRequest(dir string, pr PullRequest) (string, error) {
+	if err := checkGHCLI(); err != nil {
+		return "", err
+	}
+	args := []string{"pr", "create", "--head", pr.Branch, "--base", pr.Base, "--title", pr.Title, "--body", pr.Body}
+	if pr.Draft {
+		args = append(args, "--draft")
+	}
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request: %s (%w)", output, err)
+	}
+	// gh prints the URL of the pull request last.
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// gitlabForge is a remote on GitLab. Merge requests are opened through its API with the token in GITLAB_TOKEN.
+type gitlabForge struct {
+	remote remote
+	apiURL string
+	token  string
+}
+
+func (f gitlabForge) Name() string { return "GitLab" }
+
+func (f gitlabForge) Push(dir, branch string) error { return gitPush(dir, branch) }
+
+func (f gitlabForge) BranchURL(branch string) (string, error) {
+	return f.remote.web + "/-/tree/" + branch, nil
+}
+
+func (f gitlabForge) PullRequestRef(n int) (string, error) {
+	return fmt.Sprintf("refs/merge-requests/%d/head", n), nil
+}
+
+func (f gitlabForge) CreatePullRequest(dir string, pr PullRequest) (string, error) {
+	if f.token == "" {
+		return "", fmt.Errorf("set GITLAB_TOKEN to a token with the api scope to open merge requests")
+	}
+	title := pr.Title
+	if pr.Draft {
+		title = "Draft: " + title
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", f.apiURL, url.PathEscape(f.remote.path))
+	var created struct {
+		WebURL string `json:"web_url"`
+	}
+	err := postJSON(endpoint, map[string]string{"PRIVATE-TOKEN": f.token}, map[string]string{
+		"source_branch": pr.Branch,
+		"target_branch": pr.Base,
+		"title":         title,
+		"description":   pr.Body,
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return created.WebURL, nil
+}
+
+// giteaForge is a remote on Gitea or Forgejo, such as Codeberg. Pull requests are opened through the API with the
+// token in GITEA_TOKEN or FORGEJO_TOKEN.
+type giteaForge struct {
+	remote remote
+	apiURL string
+	token  string
+}
+
+func (f giteaForge) Name() string { return "Gitea" }
+
+func (f giteaForge) Push(dir, branch string) error { return gitPush(dir, branch) }
+
+func (f giteaForge) BranchURL(branch string) (string, error) {
+	return f.remote.web + "/src/branch/" + branch, nil
+}
+
+func (f giteaForge) PullRequestRef(n int) (string, error) {
+	return fmt.Sprintf("refs/pull/%d/head", n), nil
+}
+
+func (f giteaForge) CreatePullRequest(dir string, pr PullRequest) (string, error) {
+	if f.token == "" {
+		return "", fmt.Errorf("set GITEA_TOKEN or FORGEJO_TOKEN to a token with write access to open pull requests")
+	}
+	title := pr.Title
+	if pr.Draft {
+		title = "WIP: " + title
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/pulls", f.apiURL, f.remote.path)
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := postJSON(endpoint, map[string]string{"Authorization": "token " + f.token}, map[string]string{
+		"head":  pr.Branch,
+		"base":  pr.Base,
+		"title": title,
+		"body":  pr.Body,
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return created.HTMLURL, nil
+}
+
+// postJSON posts body as JSON to the endpoint of a forge's API and decodes the response into out.
+func postJSON(endpoint string, headers map[string]string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	client := &http.Client{Timeout: forgeAPITimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// openBrowser opens the URL in the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Run()
+}
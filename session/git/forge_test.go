@@ -0,0 +1,206 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRemoteAndDetectForge(t *testing.T) {
+	tests := []struct {
+		url      string
+		override string
+		forge    string
+		branch   string
+	}{
+		{"git@github.com:owner/repo.git", "", "GitHub", "https://github.com/owner/repo/tree/session/x"},
+		{"https://github.com/owner/repo", "", "GitHub", "https://github.com/owner/repo/tree/session/x"},
+		{"ssh://git@github.example.com:2222/owner/repo.git", "", "GitHub",
+			"https://github.example.com/owner/repo/tree/session/x"},
+		{"https://gitlab.com/group/subgroup/repo.git", "", "GitLab",
+			"https://gitlab.com/group/subgroup/repo/-/tree/session/x"},
+		{"git@codeberg.org:owner/repo.git", "", "Gitea", "https://codeberg.org/owner/repo/src/branch/session/x"},
+		{"http://git.example.com:3000/owner/repo.git", "forgejo", "Gitea",
+			"http://git.example.com:3000/owner/repo/src/branch/session/x"},
+		{"git@code.example.com:group/repo.git", "gitlab", "GitLab",
+			"https://code.example.com/group/repo/-/tree/session/x"},
+		{"git@code.example.com:owner/repo.git", "", "git", ""},
+		{"git@github.com:owner/repo.git", "git", "git", ""},
+		{"/srv/git/repo.git", "", "git", ""},
+		{"file:///srv/git/repo.git", "", "git", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			forge, err := newForge(tt.url, tt.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if forge.Name() != tt.forge {
+				t.Errorf("forge is %s, want %s", forge.Name(), tt.forge)
+			}
+			branchURL, err := forge.BranchURL("session/x")
+			if tt.branch == "" {
+				if err == nil {
+					t.Errorf("got branch URL %s from a plain git remote", branchURL)
+				}
+				if _, err := forge.CreatePullRequest("", PullRequest{}); !errors.Is(err, ErrPullRequestsUnsupported) {
+					t.Errorf("CreatePullRequest() = %v, want ErrPullRequestsUnsupported", err)
+				}
+				return
+			}
+			if err != nil || branchURL != tt.branch {
+				t.Errorf("branch URL is %q (%v), want %q", branchURL, err, tt.branch)
+			}
+		})
+	}
+
+	if _, err := newForge("git@github.com:owner/repo.git", "bitbucket"); err == nil {
+		t.Error("an unknown forge override was accepted")
+	}
+}
+
+func TestCreatePullRequestThroughAPI(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		path  string
+		auth  [2]string
+		title string
+		want  map[string]string
+	}{
+		{
+			name: "GitLab", kind: ForgeGitLab, path: "/api/v4/projects/group%2Frepo/merge_requests",
+			auth: [2]string{"PRIVATE-TOKEN", "secret"}, title: "Draft: fix login",
+			want: map[string]string{"source_branch": "session/fix", "target_branch": "main", "description": "body"},
+		},
+		{
+			name: "Gitea", kind: ForgeGitea, path: "/api/v1/repos/group/repo/pulls",
+			auth: [2]string{"Authorization", "token secret"}, title: "WIP: fix login",
+			want: map[string]string{"head": "session/fix", "base": "main", "body": "body"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != tt.path {
+					t.Errorf("request to %s, want %s", r.URL.EscapedPath(), tt.path)
+				}
+				if got := r.Header.Get(tt.auth[0]); got != tt.auth[1] {
+					t.Errorf("%s header is %q, want %q", tt.auth[0], got, tt.auth[1])
+				}
+				var body map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if body["title"] != tt.title {
+					t.Errorf("title is %q, want %q", body["title"], tt.title)
+				}
+				for key, value := range tt.want {
+					if body[key] != value {
+						t.Errorf("%s is %q, want %q", key, body[key], value)
+					}
+				}
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"web_url":  "https://example.com/mr/1",
+					"html_url": "https://example.com/mr/1",
+				})
+			}))
+			defer server.Close()
+
+			t.Setenv("GITLAB_TOKEN", "secret")
+			t.Setenv("GITEA_TOKEN", "secret")
+			forge, err := newForge(server.URL+"/group/repo.git", tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			url, err := forge.CreatePullRequest("", PullRequest{Branch: "session/fix", Base: "main",
+				Title: "fix login", Body: "body", Draft: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if url != "https://example.com/mr/1" {
+				t.Errorf("got URL %s", url)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequestNeedsToken(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("GL_TOKEN", "")
+	forge, err := newForge("git@gitlab.com:group/repo.git", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := forge.CreatePullRequest("", PullRequest{}); err == nil || !strings.Contains(err.Error(), "GITLAB_TOKEN") {
+		t.Errorf("CreatePullRequest() = %v, want an error about GITLAB_TOKEN", err)
+	}
+}
+
+func TestPushChangesOnlyNeedsGit(t *testing.T) {
+	g, origin := setupRenameRepo(t, "push")
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without gh on the PATH, a GitHub remote is still pushed to.
+	t.Setenv("PATH", filepath.Dir(gitPath))
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "test")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+	if _, err := g.runGitCommand(g.repoPath, "config", ForgeConfigKey, ForgeGitHub); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(g.worktreePath, "file.txt"), []byte("change"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.PushChanges("update", false); err != nil {
+		t.Fatal(err)
+	}
+	log, err := RunGitCommand(origin, "log", "--format=%s", g.branchName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(log, "update\n") {
+		t.Errorf("expected the commit on origin, got %q", log)
+	}
+}
+
+func TestCommitChangesWithoutRemote(t *testing.T) {
+	g, origin := setupRenameRepo(t, "pause")
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "test")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+	// Pausing commits with CommitChanges, which needs neither a forge nor origin.
+	if _, err := g.runGitCommand(g.repoPath, "remote", "remove", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(g.worktreePath, "file.txt"), []byte("change"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.CommitChanges("paused"); err != nil {
+		t.Fatal(err)
+	}
+	if log, err := RunGitCommand(g.repoPath, "log", "--format=%s", g.branchName); err != nil ||
+		!strings.HasPrefix(log, "paused\n") {
+		t.Errorf("expected the commit on the branch, got %q (%v)", log, err)
+	}
+	if _, err := RunGitCommand(origin, "rev-parse", "--verify", g.branchName); err == nil {
+		t.Error("the branch was pushed")
+	}
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// sessionBranchPrefix is the prefix of the branches orz names after instances, unless a branch template is set.
+const sessionBranchPrefix = "session/"
+
+// SessionBranches returns the branches of the repository which orz created: those named like orz names instance
+// branches, and those checked out in a worktree in the worktree directory.
+func SessionBranches(repoPath string) ([]string, error) {
+	output, err := RunGitCommand(repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads/"+sessionBranchPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	seen := make(map[string]bool)
+	var branches []string
+	add := func(branch string) {
+		if branch != "" && !seen[branch] {
+			seen[branch] = true
+			branches = append(branches, branch)
+		}
+	}
+	for _, branch := range strings.Split(strings.TrimSpace(output), "\n") {
+		add(branch)
+	}
+
+	worktreeDir, err := getWorktreeDirectory()
+	if err != nil {
+		return nil, err
+	}
+	output, err = RunGitCommand(repoPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	var path string
+	for _, line := range strings.Split(output, "\n") {
+		if p, ok := strings.CutPrefix(line, "worktree "); ok {
+			path = p
+		} else if ref, ok := strings.CutPrefix(line, "branch refs/heads/"); ok &&
+			strings.HasPrefix(path, worktreeDir+string(filepath.Separator)) {
+			add(ref)
+		}
+	}
+	return branches, nil
+}
+
+// DeleteBranch deletes a branch of the repository, whether or not it was merged.
+func DeleteBranch(repoPath, branch string) error {
+	if _, err := RunGitCommand(repoPath, "branch", "-D", branch); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// StaleWorktrees returns the worktree entries of the repository whose directory is gone, as reported by git.
+func StaleWorktrees(repoPath string) ([]string, error) {
+	output, err := RunGitCommand(repoPath, "worktree", "prune", "--dry-run", "--verbose")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale worktrees: %w", err)
+	}
+	var stale []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if entry, ok := strings.CutPrefix(line, "Removing "); ok {
+			stale = append(stale, entry)
+		}
+	}
+	return stale, nil
+}
+
+// PruneWorktrees removes the stale worktree entries of the repository.
+func PruneWorktrees(repoPath string) error {
+	if _, err := RunGitCommand(repoPath, "worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// RepoRoot returns the root of the repository containing path.
+func RepoRoot(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return findGitRepoRoot(absPath)
+}
@@ -0,0 +1,199 @@
+package git
+
+import (
+	"fmt"
+	"orzbob/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// skipSmudge keeps git-lfs from downloading LFS files while a worktree is checked out. They're checked out from the
+// repository's LFS store with git lfs checkout afterwards instead.
+var skipSmudge = []string{"GIT_LFS_SKIP_SMUDGE=1"}
+
+// submodule is a submodule of the repository as listed in .gitmodules.
+type submodule struct {
+	name string
+	path string
+}
+
+// submodules returns the submodules listed in the .gitmodules file of the worktree, if it has one.
+func (g *GitWorktree) submodules() []submodule {
+	if _, err := os.Stat(filepath.Join(g.worktreePath, ".gitmodules")); err != nil {
+		return nil
+	}
+	output, err := g.runGitCommand(g.worktreePath, "config", "--file", ".gitmodules", "--get-regexp",
+		`^submodule\..*\.path$`)
+	if err != nil {
+		// git config exits with 1 if no key matches.
+		return nil
+	}
+	var subs []submodule
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		key, path, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "submodule."), ".path")
+		if !inSparseCone(path+"/", g.sparsePatterns) {
+			// Submodules outside a sparse checkout aren't checked out.
+			continue
+		}
+		subs = append(subs, submodule{name: name, path: path})
+	}
+	return subs
+}
+
+// commonGitDir returns the git directory shared by the repository and all its worktrees.
+func (g *GitWorktree) commonGitDir() (string, error) {
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(output)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(g.repoPath, dir)
+	}
+	return dir, nil
+}
+
+// updateSubmodules initializes and checks out the submodules of the worktree. A submodule the repository has
+// already cloned is used as a reference, so its objects are shared through alternates instead of downloaded again.
+func (g *GitWorktree) updateSubmodules() error {
+	subs := g.submodules()
+	if len(subs) == 0 {
+		return nil
+	}
+	gitDir, err := g.commonGitDir()
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		args := []string{"submodule", "update", "--init", "--recursive"}
+		if reference := filepath.Join(gitDir, "modules", sub.name); isDir(reference) {
+			args = append(args, "--reference", reference)
+		}
+		args = append(args, "--", sub.path)
+		if _, err := runGitCommandWithEnv(skipSmudge, g.worktreePath, args...); err != nil {
+			return fmt.Errorf("failed to check out submodule %s: %w", sub.path, err)
+		}
+	}
+	return nil
+}
+
+// submoduleCheckedOut returns true if the submodule at dir is checked out. git commands run in a submodule which
+// isn't would run in the superproject instead.
+func submoduleCheckedOut(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// usesLFS returns true if any .gitattributes file in the worktree's HEAD stores files with Git LFS.
+func (g *GitWorktree) usesLFS() bool {
+	_, err := g.runGitCommand(g.worktreePath, "grep", "--quiet", "-e", "filter=lfs", "HEAD", "--",
+		":(glob)**/.gitattributes")
+	return err == nil
+}
+
+// checkoutLFS replaces the LFS pointer files of the worktree with their contents from the repository's LFS store,
+// which all worktrees share. Files whose contents aren't in the store yet stay pointers until git lfs pull.
+func (g *GitWorktree) checkoutLFS() error {
+	if !g.usesLFS() {
+		return nil
+	}
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		log.WarningLog.Printf("%s uses Git LFS, but git-lfs isn't installed, so LFS files are left as pointers",
+			g.repoPath)
+		return nil
+	}
+	if _, err := g.runGitCommand(g.worktreePath, "lfs", "checkout"); err != nil {
+		return fmt.Errorf("failed to check out LFS files: %w", err)
+	}
+	return nil
+}
+
+// checkoutModules checks out the submodules and LFS files of a worktree which was just added.
+func (g *GitWorktree) checkoutModules() error {
+	if err := g.updateSubmodules(); err != nil {
+		return err
+	}
+	return g.checkoutLFS()
+}
+
+// commitSubmodules commits the changes made inside the submodules of the worktree, so the superproject can record
+// their new commits.
+func (g *GitWorktree) commitSubmodules(commitMessage string) error {
+	for _, sub := range g.submodules() {
+		dir := filepath.Join(g.worktreePath, sub.path)
+		if !submoduleCheckedOut(dir) {
+			continue
+		}
+		status, err := g.runGitCommand(dir, "status", "--porcelain")
+		if err != nil || strings.TrimSpace(status) == "" {
+			continue
+		}
+		if _, err := g.runGitCommand(dir, "add", "-A"); err != nil {
+			return fmt.Errorf("failed to stage changes in submodule %s: %w", sub.path, err)
+		}
+		if _, err := g.runGitCommand(dir, "commit", "-m", commitMessage, "--no-verify"); err != nil {
+			return fmt.Errorf("failed to commit changes in submodule %s: %w", sub.path, err)
+		}
+		if err := g.keepSubmoduleCommit(sub, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepSubmoduleCommit copies the commit checked out in the submodule at dir into the repository's clone of the
+// submodule. The worktree's clone of it is deleted with the worktree when it's paused, so without this, a commit
+// which wasn't pushed would be gone when it's resumed. It's kept under refs/orzbob/<branch> and found again through
+// the alternates updateSubmodules sets up.
+func (g *GitWorktree) keepSubmoduleCommit(sub submodule, dir string) error {
+	gitDir, err := g.commonGitDir()
+	if err != nil {
+		return err
+	}
+	store := filepath.Join(gitDir, "modules", sub.name)
+	if !isDir(store) {
+		log.WarningLog.Printf("submodule %s isn't checked out in %s, so its commits are only kept once pushed",
+			sub.path, g.repoPath)
+		return nil
+	}
+	if _, err := g.runGitCommand(dir, "push", "--quiet", "--force", store, "HEAD:refs/orzbob/"+g.branchName); err != nil {
+		return fmt.Errorf("failed to keep the commit of submodule %s: %w", sub.path, err)
+	}
+	return nil
+}
+
+// pushSubmodules pushes the submodule commits the branch points to which their remotes don't have yet, to a branch
+// named like the worktree's, so the pushed branch never refers to commits nobody else can fetch. git push
+// --recurse-submodules=on-demand can't do this, since submodules are checked out on a detached HEAD.
+func (g *GitWorktree) pushSubmodules() error {
+	for _, sub := range g.submodules() {
+		dir := filepath.Join(g.worktreePath, sub.path)
+		if !submoduleCheckedOut(dir) {
+			continue
+		}
+		output, err := g.runGitCommand(g.worktreePath, "rev-parse", "HEAD:"+sub.path)
+		if err != nil {
+			continue
+		}
+		commit := strings.TrimSpace(output)
+		if remotes, err := g.runGitCommand(dir, "branch", "--remotes", "--contains", commit); err == nil &&
+			strings.TrimSpace(remotes) != "" {
+			continue
+		}
+		if _, err := g.runGitCommand(dir, "push", "--quiet", "origin", commit+":refs/heads/"+g.branchName); err != nil {
+			return fmt.Errorf("failed to push submodule %s: %w", sub.path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupSubmoduleRepo creates a repository with a bare origin whose lib directory is a submodule with a bare origin
+// of its own, and returns the repository and the origin of the submodule.
+func setupSubmoduleRepo(t *testing.T) (*GitWorktree, string) {
+	t.Helper()
+	g, _ := setupRenameRepo(t, "first")
+	repo := g.repoPath
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "test")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+	// Submodules on the local file system are refused by default since git 2.38.1.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	dir := filepath.Dir(repo)
+	libOrigin := filepath.Join(dir, "lib.git")
+	lib := filepath.Join(dir, "lib")
+	for _, step := range []struct {
+		dir  string
+		args []string
+	}{
+		{dir, []string{"init", "-q", "--bare", "-b", "main", libOrigin}},
+		{dir, []string{"init", "-q", "-b", "main", lib}},
+		{lib, []string{"commit", "-q", "--allow-empty", "-m", "lib"}},
+		{lib, []string{"push", "-q", libOrigin, "main"}},
+		{repo, []string{"submodule", "add", "-q", libOrigin, "lib"}},
+		{repo, []string{"commit", "-q", "-m", "add lib"}},
+	} {
+		if _, err := RunGitCommand(step.dir, step.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g, libOrigin
+}
+
+func TestSubmoduleWorktree(t *testing.T) {
+	g, libOrigin := setupSubmoduleRepo(t)
+	repo := g.repoPath
+
+	wt, _, err := NewGitWorktree(repo, "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(wt.GetWorktreePath(), "lib")
+	if !submoduleCheckedOut(lib) {
+		t.Fatal("the submodule wasn't checked out")
+	}
+	// The submodule borrows the objects of the repository's clone of it.
+	gitDir, err := RunGitCommand(lib, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alternates, err := os.ReadFile(filepath.Join(strings.TrimSpace(gitDir), "objects", "info", "alternates"))
+	if err != nil || !strings.Contains(string(alternates), filepath.Join(".git", "modules", "lib")) {
+		t.Errorf("expected the submodule to use the repository's objects, got alternates %q (%v)", alternates, err)
+	}
+
+	// A change inside the submodule shows up in the diff as the change of its file.
+	if err := os.WriteFile(filepath.Join(lib, "lib.go"), []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(lib, "add", "-N", "."); err != nil {
+		t.Fatal(err)
+	}
+	stats := wt.Diff()
+	if stats.Error != nil {
+		t.Fatal(stats.Error)
+	}
+	if !strings.Contains(stats.Content, "+package lib") || stats.Added != 1 {
+		t.Errorf("expected the submodule's file in the diff, got %d added lines:\n%s", stats.Added, stats.Content)
+	}
+
+	// Pushing commits the change in the submodule, records it in the branch and pushes both.
+	if err := wt.PushChanges("change lib", false); err != nil {
+		t.Fatal(err)
+	}
+	libCommit, err := RunGitCommand(wt.GetWorktreePath(), "rev-parse", wt.GetBranchName()+":lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(libOrigin, "cat-file", "-e", strings.TrimSpace(libCommit)+"^{commit}"); err != nil {
+		t.Errorf("the submodule commit the branch points to wasn't pushed: %v", err)
+	}
+	if dirty, err := wt.IsDirty(); err != nil || dirty {
+		t.Errorf("expected a clean worktree after pushing, got dirty %v (%v)", dirty, err)
+	}
+}
+
+func TestCommitChangesKeepsSubmoduleCommits(t *testing.T) {
+	g, libOrigin := setupSubmoduleRepo(t)
+	wt, _, err := NewGitWorktree(g.repoPath, "paused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(wt.GetWorktreePath(), "lib")
+	if err := os.WriteFile(filepath.Join(lib, "lib.go"), []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Committing for a pause or an export records the submodule's change without pushing anything.
+	if err := wt.CommitChanges("pause"); err != nil {
+		t.Fatal(err)
+	}
+	output, err := RunGitCommand(wt.GetWorktreePath(), "rev-parse", wt.GetBranchName()+":lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	libCommit := strings.TrimSpace(output)
+	if _, err := RunGitCommand(libOrigin, "cat-file", "-e", libCommit+"^{commit}"); err == nil {
+		t.Error("the submodule commit was pushed")
+	}
+
+	// The commit outlives the worktree, as when the session is paused and resumed.
+	if err := wt.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := RunGitCommand(lib, "rev-parse", "HEAD"); err != nil || strings.TrimSpace(head) != libCommit {
+		t.Errorf("the resumed submodule is at %q (%v), want %s", head, err, libCommit)
+	}
+	if _, err := os.Stat(filepath.Join(lib, "lib.go")); err != nil {
+		t.Errorf("the submodule's change is gone after resuming: %v", err)
+	}
+}
+
+func TestUsesLFS(t *testing.T) {
+	g, _ := setupRenameRepo(t, "lfs")
+	if g.usesLFS() {
+		t.Fatal("a repository without .gitattributes uses LFS")
+	}
+
+	attributes := filepath.Join(g.worktreePath, "assets", ".gitattributes")
+	if err := os.MkdirAll(filepath.Dir(attributes), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(attributes, []byte("*.png filter=lfs diff=lfs merge=lfs -text\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(g.worktreePath, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(g.worktreePath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "-m", "lfs"); err != nil {
+		t.Fatal(err)
+	}
+	if !g.usesLFS() {
+		t.Error("LFS attributes in a subdirectory weren't found")
+	}
+}
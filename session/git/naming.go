@@ -0,0 +1,156 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultBranchTemplate is the branch template used when none is configured. It names the branch after the title,
+// as orz always has.
+const DefaultBranchTemplate = "session/{slug}"
+
+// titleWords is the most words TitleFromPrompt keeps.
+const titleWords = 5
+
+// fillerWords are dropped when deriving a title from a prompt, since they make titles longer without telling
+// instances apart.
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true, "please": true, "can": true, "could": true, "would": true, "you": true,
+	"i": true, "we": true, "me": true, "my": true, "our": true, "to": true, "for": true, "of": true, "and": true,
+	"in": true, "on": true, "with": true, "that": true, "this": true, "it": true, "is": true, "so": true,
+	"when": true, "should": true, "be": true, "want": true, "need": true, "let's": true, "lets": true,
+}
+
+// TitleFromPrompt derives a short instance title from the first line of a prompt by keeping its first few
+// significant words. The title is at most maxLen bytes, cut at a word boundary where possible.
+func TitleFromPrompt(prompt string, maxLen int) string {
+	var line string
+	for _, l := range strings.Split(prompt, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			line = l
+			break
+		}
+	}
+
+	var all, significant []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	}) {
+		word = strings.Trim(word, "'-")
+		if word == "" {
+			continue
+		}
+		all = append(all, word)
+		if !fillerWords[word] {
+			significant = append(significant, word)
+		}
+	}
+	words := significant
+	if len(words) == 0 {
+		words = all
+	}
+
+	var title string
+	for i, word := range words {
+		if i == titleWords {
+			break
+		}
+		next := word
+		if title != "" {
+			next = title + " " + word
+		}
+		if len(next) > maxLen {
+			if title == "" {
+				// A single long word is cut rather than dropped.
+				title = TruncateTitle(word, maxLen)
+			}
+			break
+		}
+		title = next
+	}
+	return title
+}
+
+// TruncateTitle returns the longest prefix of title which is at most maxLen bytes long, without cutting a character
+// in half.
+func TruncateTitle(title string, maxLen int) string {
+	runes := []rune(title)
+	for len(string(runes)) > maxLen {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
+
+// BranchTemplateUser returns the name used for {user} in branch templates: the git user name of the repository,
+// falling back to the login name.
+func BranchTemplateUser(repoPath string) string {
+	if output, err := RunGitCommand(repoPath, "config", "user.name"); err == nil {
+		if user := sanitizeBranchName(strings.TrimSpace(output)); user != "" {
+			return user
+		}
+	}
+	if user := sanitizeBranchName(os.Getenv("USER")); user != "" {
+		return user
+	}
+	return "user"
+}
+
+// ExpandBranchTemplate builds a branch name from a template. {slug} is replaced by the title in branch name form,
+// {user} by user and {date} by the date as YYYY-MM-DD. The result must be a valid branch name.
+func ExpandBranchTemplate(template, title, user string, now time.Time) (string, error) {
+	if template == "" {
+		template = DefaultBranchTemplate
+	}
+	slug := slugFor(title)
+	if slug == "" {
+		return "", fmt.Errorf("cannot make a branch name from title %q", title)
+	}
+	name := strings.NewReplacer(
+		"{slug}", slug,
+		"{user}", user,
+		"{date}", now.Format("2006-01-02"),
+	).Replace(template)
+	if err := ValidateBranchName(name); err != nil {
+		return "", fmt.Errorf("invalid branch template %q: %w", template, err)
+	}
+	return name, nil
+}
+
+// slugFor returns a title in the form used in branch names. Unlike sanitizeBranchName, slashes are replaced so the
+// title stays a single path component.
+func slugFor(title string) string {
+	return sanitizeBranchName(strings.ReplaceAll(title, "/", " "))
+}
+
+// ValidateBranchName returns an error if name isn't a valid git branch name.
+func ValidateBranchName(name string) error {
+	if output, err := exec.Command("git", "check-ref-format", "--branch", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("%q is not a valid branch name: %s", name, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// BranchExists returns true if the branch exists locally or on origin in the repository at repoPath, as far as the
+// last fetch knows.
+func BranchExists(repoPath, name string) bool {
+	for _, ref := range []string{"refs/heads/" + name, "refs/remotes/origin/" + name} {
+		if _, err := RunGitCommand(repoPath, "rev-parse", "--verify", "--quiet", ref); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueBranchName returns name if no branch has it yet, or else name with the lowest suffix -2, -3, ... which
+// is free.
+func UniqueBranchName(repoPath, name string) string {
+	unique := name
+	for n := 2; BranchExists(repoPath, unique); n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	return unique
+}
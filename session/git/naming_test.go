@@ -0,0 +1,128 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTitleFromPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		prompt   string
+		maxLen   int
+		expected string
+	}{
+		{
+			name:     "filler words are dropped",
+			prompt:   "Please fix the login redirect bug when the session expires",
+			maxLen:   32,
+			expected: "fix login redirect bug session",
+		},
+		{
+			name:     "only the first line is used",
+			prompt:   "\n  Add dark mode\nIt should follow the system setting",
+			maxLen:   32,
+			expected: "add dark mode",
+		},
+		{
+			name:     "cut at a word boundary",
+			prompt:   "refactor authentication middleware configuration",
+			maxLen:   32,
+			expected: "refactor authentication",
+		},
+		{
+			name:     "only filler words",
+			prompt:   "Can you do it?",
+			maxLen:   32,
+			expected: "do",
+		},
+		{
+			name:     "long single word",
+			prompt:   "supercalifragilistic",
+			maxLen:   10,
+			expected: "supercalif",
+		},
+		{
+			name:     "empty prompt",
+			prompt:   "  ",
+			maxLen:   32,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TitleFromPrompt(tt.prompt, tt.maxLen); got != tt.expected {
+				t.Errorf("TitleFromPrompt(%q) = %q, want %q", tt.prompt, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandBranchTemplate(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		template string
+		title    string
+		expected string
+		wantErr  bool
+	}{
+		{"", "Fix login", "session/fix-login", false},
+		{"{user}/{date}-{slug}", "fix a/b", "jane/2026-03-14-fix-a-b", false},
+		{"{slug}..{user}", "fix", "", true},
+		{"{slug}", "!!!", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ExpandBranchTemplate(tt.template, tt.title, "jane", now)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ExpandBranchTemplate(%q, %q) = %q, %v, want %q", tt.template, tt.title, got, err, tt.expected)
+		}
+	}
+}
+
+func TestRenamedBranch(t *testing.T) {
+	tests := []struct {
+		branch, oldName, newName, expected string
+	}{
+		{"session/old-title", "old title", "new title", "session/new-title"},
+		{"jane/2026-03-14-old-title", "old title", "New", "jane/2026-03-14-new"},
+		{"feature/unrelated", "old title", "new title", "feature/unrelated"},
+	}
+	for _, tt := range tests {
+		if got := renamedBranch(tt.branch, tt.oldName, tt.newName); got != tt.expected {
+			t.Errorf("renamedBranch(%q) = %q, want %q", tt.branch, got, tt.expected)
+		}
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		maxLen   int
+		expected string
+	}{
+		{"short", 32, "short"},
+		{"fix login redirect loop", 9, "fix login"},
+		// "é" is two bytes long, so it's dropped rather than cut in half.
+		{"café crème", 4, "caf"},
+		{"café crème", 5, "café"},
+	}
+	for _, tt := range tests {
+		if got := TruncateTitle(tt.title, tt.maxLen); got != tt.expected {
+			t.Errorf("TruncateTitle(%q, %d) = %q, want %q", tt.title, tt.maxLen, got, tt.expected)
+		}
+	}
+}
+
+func TestUniqueBranchName(t *testing.T) {
+	g, _ := setupRenameRepo(t, "taken")
+	if got := UniqueBranchName(g.repoPath, "session/free"); got != "session/free" {
+		t.Errorf("expected a free name to be kept, got %s", got)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "branch", "session/taken-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := UniqueBranchName(g.repoPath, "session/taken"); got != "session/taken-3" {
+		t.Errorf("expected the first free suffix, got %s", got)
+	}
+}
@@ -0,0 +1,57 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteURL returns the URL of the origin remote of the repository.
+func (g *GitWorktree) RemoteURL() (string, error) {
+	output, err := g.runGitCommand(g.repoPath, "remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("the repository has no origin remote: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// PushBranch pushes the branch to origin without committing anything. Unlike PushChanges, it works while the
+// worktree is removed.
+func (g *GitWorktree) PushBranch() error {
+	if _, err := g.runGitCommand(g.repoPath, "push", "--quiet", "-u", "origin", g.branchName); err != nil {
+		return fmt.Errorf("failed to push branch %s: %w", g.branchName, err)
+	}
+	return nil
+}
+
+// PullBranch fast-forwards the local branch to the branch on origin, e.g. to pick up what a cloud instance pushed.
+// It fails if the two have diverged, rather than losing commits on either side.
+func (g *GitWorktree) PullBranch() error {
+	ref := "refs/remotes/origin/" + g.branchName
+	if _, err := g.runGitCommand(g.repoPath, "fetch", "--quiet", "origin",
+		"+refs/heads/"+g.branchName+":"+ref); err != nil {
+		return fmt.Errorf("failed to fetch branch %s: %w", g.branchName, err)
+	}
+
+	local := "refs/heads/" + g.branchName
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", local); err != nil {
+		// The branch was deleted locally, so take it as it is on origin.
+		_, err := g.runGitCommand(g.repoPath, "branch", g.branchName, ref)
+		return err
+	}
+	if _, err := g.runGitCommand(g.repoPath, "merge-base", "--is-ancestor", ref, local); err == nil {
+		// Nothing new on origin.
+		return nil
+	}
+	if _, err := g.runGitCommand(g.repoPath, "merge-base", "--is-ancestor", local, ref); err != nil {
+		return fmt.Errorf("branch %s has diverged from origin, merge them by hand first", g.branchName)
+	}
+	if checkedOut, err := g.IsBranchCheckedOut(); err != nil {
+		return err
+	} else if checkedOut {
+		return fmt.Errorf("branch %s is checked out, please switch to a different branch", g.branchName)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "update-ref", local, ref); err != nil {
+		return fmt.Errorf("failed to update branch %s: %w", g.branchName, err)
+	}
+	return nil
+}
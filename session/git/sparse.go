@@ -0,0 +1,102 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// SparseConfigKey is the git config key holding the directories new worktrees of a repository check out by default,
+// e.g. git config --add orzbob.sparse services/api. It can be given several times, and each value can hold several
+// directories separated by spaces.
+const SparseConfigKey = "orzbob.sparse"
+
+// ParseSparsePatterns splits directories separated by white space or commas into cone patterns, relative to the root of
+// the repository. An empty list means the whole repository is checked out.
+func ParseSparsePatterns(s string) ([]string, error) {
+	patterns := []string{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		dir := path.Clean(strings.Trim(field, "/"))
+		if dir == "." {
+			continue
+		}
+		if dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, fmt.Errorf("sparse directory %s is outside the repository", field)
+		}
+		patterns = append(patterns, dir)
+	}
+	return patterns, nil
+}
+
+// SparseDefaults returns the directories new worktrees of the repository at repoPath check out, from SparseConfigKey.
+// It's empty if the whole repository is checked out.
+func SparseDefaults(repoPath string) ([]string, error) {
+	// git config exits with 1 if the key isn't set.
+	output, _ := RunGitCommand(repoPath, "config", "--get-all", SparseConfigKey)
+	patterns, err := ParseSparsePatterns(output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SparseConfigKey, err)
+	}
+	return patterns, nil
+}
+
+// SetSparse makes Setup check out only the given directories, and whatever is at the root of the repository, with a
+// cone mode sparse checkout. An empty list checks out everything.
+func (g *GitWorktree) SetSparse(patterns []string) {
+	g.sparsePatterns = patterns
+}
+
+// GetSparsePatterns returns the directories the worktree checks out, or nothing if it checks out everything.
+func (g *GitWorktree) GetSparsePatterns() []string {
+	return g.sparsePatterns
+}
+
+// addWorktree runs git worktree add with args, which are the arguments after the path, and checks out the
+// submodules and LFS files of the new worktree. If the worktree is sparse, it's added without a checkout, made sparse
+// and then checked out, so files outside the cone are never written.
+func (g *GitWorktree) addWorktree(args ...string) error {
+	if len(g.sparsePatterns) == 0 {
+		if _, err := runGitCommandWithEnv(skipSmudge, g.repoPath,
+			append([]string{"worktree", "add", g.worktreePath}, args...)...); err != nil {
+			return err
+		}
+		return g.checkoutModules()
+	}
+	if _, err := g.runGitCommand(g.repoPath,
+		append([]string{"worktree", "add", "--no-checkout", g.worktreePath}, args...)...); err != nil {
+		return err
+	}
+	// The sparse checkout is configured for this worktree only, the repository and other worktrees keep theirs.
+	if _, err := g.runGitCommand(g.worktreePath,
+		append([]string{"sparse-checkout", "set", "--cone", "--"}, g.sparsePatterns...)...); err != nil {
+		return fmt.Errorf("failed to set up sparse checkout of %s: %w", strings.Join(g.sparsePatterns, ", "), err)
+	}
+	if _, err := runGitCommandWithEnv(skipSmudge, g.worktreePath, "checkout", "--quiet"); err != nil {
+		return fmt.Errorf("failed to check out sparse worktree: %w", err)
+	}
+	return g.checkoutModules()
+}
+
+// stageArgs returns the arguments of git add for args. A sparse worktree also stages files outside its cone, such as
+// ones the program created there, which git add refuses otherwise.
+func (g *GitWorktree) stageArgs(args ...string) []string {
+	if len(g.sparsePatterns) == 0 {
+		return append([]string{"add"}, args...)
+	}
+	return append([]string{"add", "--sparse"}, args...)
+}
+
+// inSparseCone returns true if file, relative to the root of the repository, is checked out by cone patterns. In
+// cone mode the files at the root are always checked out.
+func inSparseCone(file string, patterns []string) bool {
+	if len(patterns) == 0 || !strings.Contains(file, "/") {
+		return true
+	}
+	for _, dir := range patterns {
+		if strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
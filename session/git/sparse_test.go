@@ -0,0 +1,133 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSparsePatterns(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"", []string{}, false},
+		{"services/api", []string{"services/api"}, false},
+		{"services/api, libs/auth/", []string{"services/api", "libs/auth"}, false},
+		{"/docs  web//app\nlibs", []string{"docs", "web/app", "libs"}, false},
+		{". ./", []string{}, false},
+		{"../other", nil, true},
+		{"services/../../other", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSparsePatterns(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSparsePatterns(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSparsePatterns(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSparseWorktree(t *testing.T) {
+	g, origin := setupRenameRepo(t, "full")
+	repo := g.repoPath
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "test")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+	for _, file := range []string{"README", "api/main.go", "api/handlers/h.go", "web/index.html"} {
+		path := filepath.Join(repo, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := RunGitCommand(repo, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(repo, "commit", "-q", "-m", "files"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunGitCommand(repo, "config", "--add", SparseConfigKey, "api"); err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := SparseDefaults(repo)
+	if err != nil || !reflect.DeepEqual(defaults, []string{"api"}) {
+		t.Fatalf("SparseDefaults() = %q, %v, want [api]", defaults, err)
+	}
+
+	sparse, _, err := NewGitWorktree(repo, "sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse.SetSparse(defaults)
+	if err := sparse.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles := func(g *GitWorktree) {
+		t.Helper()
+		for file, want := range map[string]bool{"README": true, "api/handlers/h.go": true, "web/index.html": false} {
+			_, err := os.Stat(filepath.Join(g.GetWorktreePath(), file))
+			if got := err == nil; got != want {
+				t.Errorf("%s checked out = %v, want %v", file, got, want)
+			}
+		}
+	}
+	checkFiles(sparse)
+	// The repository itself stays full.
+	if sparseCheckout, err := RunGitCommand(repo, "config", "--get", "core.sparseCheckout"); err == nil {
+		t.Errorf("the sparse checkout leaked into the repository: core.sparseCheckout is %s", sparseCheckout)
+	}
+
+	// Changes inside and outside the cone show up in the diff and are pushed, and nothing outside counts as deleted.
+	for _, file := range []string{"api/new.go", "web/new.html"} {
+		path := filepath.Join(sparse.GetWorktreePath(), file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("new\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := sparse.Diff()
+	if stats.Error != nil {
+		t.Fatal(stats.Error)
+	}
+	if stats.Added != 2 || stats.Removed != 0 {
+		t.Errorf("diff has %d added and %d removed lines, want 2 and 0:\n%s", stats.Added, stats.Removed,
+			stats.Content)
+	}
+	if err := sparse.PushChanges("sparse changes", false); err != nil {
+		t.Fatal(err)
+	}
+	files, err := RunGitCommand(origin, "ls-tree", "-r", "--name-only", sparse.GetBranchName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(files); !reflect.DeepEqual(got,
+		[]string{"README", "api/handlers/h.go", "api/main.go", "api/new.go", "web/index.html", "web/new.html"}) {
+		t.Errorf("origin has %q", got)
+	}
+
+	// Pausing and resuming recreates the same sparse worktree from the stored patterns.
+	if err := sparse.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewGitWorktreeFromStorage(repo, sparse.GetWorktreePath(), "sparse", sparse.GetBranchName(),
+		sparse.GetBaseCommitSHA())
+	resumed.SetSparse(sparse.GetSparsePatterns())
+	if err := resumed.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(resumed)
+}
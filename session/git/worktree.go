@@ -17,6 +17,11 @@ func getWorktreeDirectory() (string, error) {
 	return filepath.Join(configDir, "worktrees"), nil
 }
 
+// WorktreeDirectory returns the directory which holds the worktrees of all instances.
+func WorktreeDirectory() (string, error) {
+	return getWorktreeDirectory()
+}
+
 // GitWorktree manages git worktree operations for a session
 type GitWorktree struct {
 	// Path to the repository
@@ -29,6 +34,12 @@ type GitWorktree struct {
 	branchName string
 	// Base commit hash for the worktree
 	baseCommitSHA string
+	// baseRef is what the branch was started from, as it was given, if it wasn't HEAD
+	baseRef string
+	// sparsePatterns are the directories a sparse worktree checks out, or nil for a full worktree
+	sparsePatterns []string
+	// createdBranch is true if the branch was created for the session rather than an existing branch it continues
+	createdBranch bool
 }
 
 func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string) *GitWorktree {
@@ -41,11 +52,31 @@ func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName
 	}
 }
 
+// branchNameFor returns the branch name used for a session.
+func branchNameFor(sessionName string) string {
+	return fmt.Sprintf("session/%s", sanitizeBranchName(sessionName))
+}
+
+// newWorktreePath returns a unique path for the worktree of a session.
+func newWorktreePath(sessionName string) (string, error) {
+	worktreeDir, err := getWorktreeDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	worktreePath := filepath.Join(worktreeDir, sanitizeBranchName(sessionName))
+	return worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano()), nil
+}
+
 // NewGitWorktree creates a new GitWorktree instance
 func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
-	sanitizedName := sanitizeBranchName(sessionName)
-	branchName := fmt.Sprintf("session/%s", sanitizedName)
+	return NewGitWorktreeWithBranch(repoPath, sessionName, branchNameFor(sessionName))
+}
 
+// NewGitWorktreeWithBranch creates a new GitWorktree instance which uses the given branch name instead of one
+// derived from the session name.
+func NewGitWorktreeWithBranch(repoPath string, sessionName string, branchName string) (tree *GitWorktree,
+	branchname string, err error) {
 	// Convert repoPath to absolute path
 	absPath, err := filepath.Abs(repoPath)
 	if err != nil {
@@ -59,14 +90,11 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 		return nil, "", err
 	}
 
-	worktreeDir, err := getWorktreeDirectory()
+	worktreePath, err := newWorktreePath(sessionName)
 	if err != nil {
 		return nil, "", err
 	}
 
-	worktreePath := filepath.Join(worktreeDir, sanitizedName)
-	worktreePath = worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano())
-
 	return &GitWorktree{
 		repoPath:     repoPath,
 		sessionName:  sessionName,
@@ -85,6 +113,23 @@ func (g *GitWorktree) GetBranchName() string {
 	return g.branchName
 }
 
+// CreatedBranch returns true if the branch was created for the session, so it's the session's to rename. It's false
+// for an existing branch the session continues, such as a pull request.
+func (g *GitWorktree) CreatedBranch() bool {
+	return g.createdBranch
+}
+
+// SetCreatedBranch records whether the branch was created for the session, see CreatedBranch.
+func (g *GitWorktree) SetCreatedBranch(created bool) {
+	g.createdBranch = created
+}
+
+// IsSessionBranch returns true if branch is the one a session of this name used to create, before branches were
+// named with the branch_template and whether the branch was created was recorded.
+func IsSessionBranch(branch, sessionName string) bool {
+	return branch == branchNameFor(sessionName)
+}
+
 // GetRepoPath returns the path to the repository
 func (g *GitWorktree) GetRepoPath() string {
 	return g.repoPath
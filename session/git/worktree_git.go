@@ -3,6 +3,7 @@ package git
 import (
 	"fmt"
 	"orzbob/log"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -15,8 +16,16 @@ func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error)
 // RunGitCommand executes a git command on the specified path and returns its output
 // This is an exported version of runGitCommand for use by other packages
 func RunGitCommand(path string, args ...string) (string, error) {
+	return runGitCommandWithEnv(nil, path, args...)
+}
+
+// runGitCommandWithEnv is RunGitCommand with extra environment variables, such as GIT_LFS_SKIP_SMUDGE=1.
+func runGitCommandWithEnv(env []string, path string, args ...string) (string, error) {
 	baseArgs := []string{"-C", path}
 	cmd := exec.Command("git", append(baseArgs, args...)...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -26,53 +35,32 @@ func RunGitCommand(path string, args ...string) (string, error) {
 	return string(output), nil
 }
 
-// PushChanges commits and pushes changes in the worktree to the remote branch
+// PushChanges commits and pushes changes in the worktree to the remote branch, and opens the branch in the browser
+// if open is set. Only git is needed unless the branch is opened. The submodule commits the branch needs are pushed
+// to the remotes of the submodules as well.
 func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
-	if err := checkGHCLI(); err != nil {
+	forge, err := g.Forge()
+	if err != nil {
 		return err
 	}
 
-	// Check if there are any changes to commit
-	isDirty, err := g.IsDirty()
-	if err != nil {
-		return fmt.Errorf("failed to check for changes: %w", err)
-	}
-
-	if isDirty {
-		// Stage all changes
-		if _, err := g.runGitCommand(g.worktreePath, "add", "."); err != nil {
-			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to stage changes: %w", err)
-		}
-
-		// Create commit
-		if _, err := g.runGitCommand(g.worktreePath, "commit", "-m", commitMessage, "--no-verify"); err != nil {
-			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to commit changes: %w", err)
-		}
-	}
-
-	// First push the branch to remote to ensure it exists
-	pushCmd := exec.Command("gh", "repo", "sync", "--source", "-b", g.branchName)
-	pushCmd.Dir = g.worktreePath
-	if err := pushCmd.Run(); err != nil {
-		// If sync fails, try creating the branch on remote first
-		gitPushCmd := exec.Command("git", "push", "-u", "origin", g.branchName)
-		gitPushCmd.Dir = g.worktreePath
-		if pushOutput, pushErr := gitPushCmd.CombinedOutput(); pushErr != nil {
-			log.ErrorLog.Print(pushErr)
-			return fmt.Errorf("failed to push branch: %s (%w)", pushOutput, pushErr)
-		}
-	}
-
-	// Now sync with remote
-	syncCmd := exec.Command("gh", "repo", "sync", "-b", g.branchName)
-	syncCmd.Dir = g.worktreePath
-	if output, err := syncCmd.CombinedOutput(); err != nil {
+	if err := g.CommitChanges(commitMessage); err != nil {
 		log.ErrorLog.Print(err)
-		return fmt.Errorf("failed to sync changes: %s (%w)", output, err)
+		return err
 	}
 
+	if err := g.pushSubmodules(); err != nil {
+		log.ErrorLog.Print(err)
+		return err
+	}
+	if err := forge.Push(g.worktreePath, g.branchName); err != nil {
+		log.ErrorLog.Print(err)
+		return err
+	}
+
+	if !open {
+		return nil
+	}
 	// Open the branch in the browser
 	if err := g.OpenBranchURL(); err != nil {
 		// Just log the error but don't fail the push operation
@@ -102,15 +90,42 @@ func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
 
 // OpenBranchURL opens the branch URL in the default browser
 func (g *GitWorktree) OpenBranchURL() error {
-	// Check if GitHub CLI is available
-	if err := checkGHCLI(); err != nil {
+	forge, err := g.Forge()
+	if err != nil {
 		return err
 	}
-
-	cmd := exec.Command("gh", "browse", "--branch", g.branchName)
-	cmd.Dir = g.worktreePath
-	if err := cmd.Run(); err != nil {
+	branchURL, err := forge.BranchURL(g.branchName)
+	if err != nil {
+		return err
+	}
+	if err := openBrowser(branchURL); err != nil {
 		return fmt.Errorf("failed to open branch URL: %w", err)
 	}
 	return nil
 }
+
+// CreatePullRequest pushes the branch and opens a pull request of it on the forge hosting origin. The pull request
+// is against the default branch of origin if pr has no base. It returns the URL of the pull request.
+func (g *GitWorktree) CreatePullRequest(pr PullRequest) (string, error) {
+	forge, err := g.Forge()
+	if err != nil {
+		return "", err
+	}
+	if err := forge.Push(g.repoPath, g.branchName); err != nil {
+		return "", err
+	}
+	pr.Branch = g.branchName
+	if pr.Base == "" {
+		pr.Base = g.DefaultBranch()
+	}
+	return forge.CreatePullRequest(g.repoPath, pr)
+}
+
+// DefaultBranch returns the default branch of origin, or main if origin doesn't say.
+func (g *GitWorktree) DefaultBranch() string {
+	output, err := g.runGitCommand(g.repoPath, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return "main"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(output), "origin/")
+}
@@ -31,7 +31,8 @@ func (g *GitWorktree) Setup() error {
 	return g.SetupNewWorktree()
 }
 
-// SetupFromExistingBranch creates a worktree from an existing branch
+// SetupFromExistingBranch creates a worktree from an existing branch, which is taken from origin if it only exists
+// there
 func (g *GitWorktree) SetupFromExistingBranch() error {
 	// Ensure worktrees directory exists
 	worktreesDir := filepath.Join(g.repoPath, "worktrees")
@@ -42,8 +43,20 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 	// Clean up any existing worktree first
 	_, _ = g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath) // Ignore error if worktree doesn't exist
 
+	if err := g.ensureBranch(); err != nil {
+		return err
+	}
+	if g.baseCommitSHA == "" {
+		// The branch is continued, so the diff shows what's done from here on.
+		sha, err := revParseCommit(g.repoPath, "refs/heads/"+g.branchName)
+		if err != nil {
+			return err
+		}
+		g.baseCommitSHA = sha
+	}
+
 	// Create a new worktree from the existing branch
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", g.worktreePath, g.branchName); err != nil {
+	if err := g.addWorktree(g.branchName); err != nil {
 		return fmt.Errorf("failed to create worktree from branch %s: %w", g.branchName, err)
 	}
 
@@ -55,7 +68,7 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 	return nil
 }
 
-// SetupNewWorktree creates a new worktree from HEAD
+// SetupNewWorktree creates a new worktree from HEAD, or from the base set with SetBase
 func (g *GitWorktree) SetupNewWorktree() error {
 	// Ensure worktrees directory exists
 	worktreesDir := filepath.Join(g.repoPath, "worktrees")
@@ -77,25 +90,19 @@ func (g *GitWorktree) SetupNewWorktree() error {
 		return fmt.Errorf("failed to cleanup existing branch: %w", err)
 	}
 
-	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	headCommit, err := g.baseCommit()
 	if err != nil {
-		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
-			strings.Contains(err.Error(), "fatal: not a valid object name") ||
-			strings.Contains(err.Error(), "fatal: HEAD: not a valid object name") {
-			return fmt.Errorf("this appears to be a brand new repository: please create an initial commit before creating an instance")
-		}
-		return fmt.Errorf("failed to get HEAD commit hash: %w", err)
+		return err
 	}
-	headCommit := strings.TrimSpace(string(output))
 	g.baseCommitSHA = headCommit
 
-	// Create a new worktree from the HEAD commit
+	// Create a new worktree from the base commit
 	// Otherwise, we'll inherit uncommitted changes from the previous worktree.
 	// This way, we can start the worktree with a clean slate.
-	// TODO: we might want to give an option to use main/master instead of the current branch.
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
+	if err := g.addWorktree("-b", g.branchName, headCommit); err != nil {
 		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
 	}
+	g.createdBranch = true
 
 	// Copy untracked files from main repo to worktree
 	if err := g.copyUntrackedFiles(); err != nil {
@@ -105,6 +112,38 @@ func (g *GitWorktree) SetupNewWorktree() error {
 	return nil
 }
 
+// CreateBranch creates the branch from HEAD, or from the base set with SetBase, without a worktree. It's for
+// sessions which don't run here, e.g. in the cloud. Setup checks the branch out once the session comes here.
+func (g *GitWorktree) CreateBranch() error {
+	commit, err := g.baseCommit()
+	if err != nil {
+		return err
+	}
+	if _, err := g.runGitCommand(g.repoPath, "branch", g.branchName, commit); err != nil {
+		return fmt.Errorf("failed to create branch %s from commit %s: %w", g.branchName, commit, err)
+	}
+	g.baseCommitSHA = commit
+	g.createdBranch = true
+	return nil
+}
+
+// baseCommit returns the commit a new branch starts from: the base set with SetBase, or HEAD.
+func (g *GitWorktree) baseCommit() (string, error) {
+	if g.baseRef != "" {
+		return ResolveBase(g.repoPath, g.baseRef)
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
+			strings.Contains(err.Error(), "fatal: not a valid object name") ||
+			strings.Contains(err.Error(), "fatal: HEAD: not a valid object name") {
+			return "", fmt.Errorf("this appears to be a brand new repository: please create an initial commit before creating an instance")
+		}
+		return "", fmt.Errorf("failed to get HEAD commit hash: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
 // Cleanup removes the worktree and associated branch
 func (g *GitWorktree) Cleanup() error {
 	var errs []error
@@ -186,7 +225,7 @@ func (g *GitWorktree) copyUntrackedFiles() error {
 
 	untrackedFiles := strings.Split(strings.TrimSpace(output), "\n")
 	for _, file := range untrackedFiles {
-		if file == "" {
+		if file == "" || !inSparseCone(file, g.sparsePatterns) {
 			continue
 		}
 
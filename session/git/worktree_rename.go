@@ -0,0 +1,190 @@
+package git
+
+import (
+	"fmt"
+	"orzbob/log"
+	"os"
+	"strings"
+)
+
+// undoStack collects the undo functions of completed steps so they can be rolled back in reverse order.
+type undoStack []func() error
+
+func (u *undoStack) push(undo func() error) {
+	*u = append(*u, undo)
+}
+
+// run undoes every step, newest first, and returns the errors of any which couldn't be undone.
+func (u undoStack) run() error {
+	var errs []error
+	for i := len(u) - 1; i >= 0; i-- {
+		if err := u[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	msg := "failed to roll back:"
+	for _, err := range errs {
+		msg += "\n  - " + err.Error()
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// Rename renames the branch and moves the worktree directory to match a new session name. Only a branch created for
+// the session is renamed, an existing branch it continues keeps its name. If the branch was pushed, the remote branch
+// is renamed too. then is called last, e.g. to save the change, and may be nil. Either every step, then included,
+// succeeds or the worktree is left as it was.
+func (g *GitWorktree) Rename(sessionName string, then func() error) (err error) {
+	var undos undoStack
+	defer func() {
+		if err != nil {
+			if undoErr := undos.run(); undoErr != nil {
+				err = fmt.Errorf("%v (%v)", err, undoErr)
+			}
+		}
+	}()
+
+	oldSessionName := g.sessionName
+	g.sessionName = sessionName
+	undos.push(func() error {
+		g.sessionName = oldSessionName
+		return nil
+	})
+
+	oldBranch := g.branchName
+	newBranch := oldBranch
+	if g.createdBranch {
+		newBranch = renamedBranch(oldBranch, oldSessionName, sessionName)
+	}
+	var remoteSHA string
+	if newBranch != oldBranch {
+		// Look up the remote branch before renaming, since the rename changes what's pushed.
+		if remoteSHA, err = g.remoteBranchSHA(oldBranch); err != nil {
+			return err
+		}
+		// The remote branch is only moved if it holds nothing but what was pushed from here. Otherwise someone else
+		// pushed to it, and deleting it would lose their work.
+		if remoteSHA != "" && !g.hasCommit(oldBranch, remoteSHA) {
+			return fmt.Errorf("origin/%s has commits which aren't in %s, pull them before renaming", oldBranch,
+				oldBranch)
+		}
+		if err = g.renameBranch(newBranch); err != nil {
+			return err
+		}
+		undos.push(func() error { return g.renameBranch(oldBranch) })
+	}
+
+	oldPath := g.worktreePath
+	newPath, err := newWorktreePath(sessionName)
+	if err != nil {
+		return err
+	}
+	if err = g.moveWorktree(newPath); err != nil {
+		return err
+	}
+	undos.push(func() error { return g.moveWorktree(oldPath) })
+
+	// The remote goes last since it's the only step which can fail for reasons outside of this machine.
+	if remoteSHA != "" {
+		if err = g.moveRemoteBranch(remoteSHA, oldBranch, newBranch); err != nil {
+			return err
+		}
+		undos.push(func() error { return g.moveRemoteBranch(remoteSHA, newBranch, oldBranch) })
+	}
+
+	if then != nil {
+		return then()
+	}
+	return nil
+}
+
+// renamedBranch returns the branch name for a renamed session. The part of the branch made from the old session name
+// is replaced, so branches made from a template keep their shape. A branch which isn't named after the session, such
+// as an existing branch the session was started from, keeps its name.
+func renamedBranch(branch, oldSessionName, newSessionName string) string {
+	oldSlug := slugFor(oldSessionName)
+	idx := strings.LastIndex(branch, oldSlug)
+	if oldSlug == "" || idx < 0 {
+		return branch
+	}
+	return branch[:idx] + slugFor(newSessionName) + branch[idx+len(oldSlug):]
+}
+
+// hasCommit returns true if sha is a commit which branch contains.
+func (g *GitWorktree) hasCommit(branch, sha string) bool {
+	_, err := g.runGitCommand(g.repoPath, "merge-base", "--is-ancestor", sha, "refs/heads/"+branch)
+	return err == nil
+}
+
+// renameBranch renames the local branch. Git updates the HEAD of the worktree if the branch is checked out there.
+func (g *GitWorktree) renameBranch(newName string) error {
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+newName); err == nil {
+		return fmt.Errorf("branch %s already exists", newName)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "branch", "-m", g.branchName, newName); err != nil {
+		return fmt.Errorf("failed to rename branch %s to %s: %w", g.branchName, newName, err)
+	}
+	g.branchName = newName
+	return nil
+}
+
+// remoteBranchSHA returns the commit of the branch on origin, or an empty string if it hasn't been pushed or there's
+// no origin.
+func (g *GitWorktree) remoteBranchSHA(branch string) (string, error) {
+	remotes, err := g.runGitCommand(g.repoPath, "remote")
+	if err != nil {
+		return "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	hasOrigin := false
+	for _, remote := range strings.Fields(remotes) {
+		hasOrigin = hasOrigin || remote == "origin"
+	}
+	if !hasOrigin {
+		return "", nil
+	}
+
+	output, err := g.runGitCommand(g.repoPath, "ls-remote", "--heads", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up remote branch %s: %w", branch, err)
+	}
+	if fields := strings.Fields(output); len(fields) > 0 {
+		return fields[0], nil
+	}
+	return "", nil
+}
+
+// moveRemoteBranch renames a branch on origin. The commit which was on the remote is pushed under the new name, so
+// unpushed local commits stay unpushed. The local branch then tracks the new remote branch.
+func (g *GitWorktree) moveRemoteBranch(sha, from, to string) error {
+	if _, err := g.runGitCommand(g.repoPath, "push", "origin", sha+":refs/heads/"+to); err != nil {
+		return fmt.Errorf("failed to push remote branch %s: %w", to, err)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "push", "origin", "--delete", from); err != nil {
+		// Leave the remote as it was.
+		if _, cleanupErr := g.runGitCommand(g.repoPath, "push", "origin", "--delete", to); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
+		}
+		return fmt.Errorf("failed to delete remote branch %s: %w", from, err)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "branch", "--set-upstream-to=origin/"+to, g.branchName); err != nil {
+		// The rename itself worked, so just log that the local branch doesn't track it.
+		log.WarningLog.Printf("failed to set upstream of %s to origin/%s: %v", g.branchName, to, err)
+	}
+	return nil
+}
+
+// moveWorktree moves the worktree directory. A paused instance has no worktree directory, so only the path which
+// it will be created at is changed.
+func (g *GitWorktree) moveWorktree(newPath string) error {
+	if _, err := os.Stat(g.worktreePath); err == nil {
+		if _, err := g.runGitCommand(g.repoPath, "worktree", "move", g.worktreePath, newPath); err != nil {
+			return fmt.Errorf("failed to move worktree to %s: %w", newPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check worktree path: %w", err)
+	}
+	g.worktreePath = newPath
+	return nil
+}
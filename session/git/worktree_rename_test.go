@@ -0,0 +1,213 @@
+package git
+
+import (
+	"errors"
+	"orzbob/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupRenameRepo creates a repository with one commit and a bare origin, and starts a worktree for the session.
+func setupRenameRepo(t *testing.T, sessionName string) (*GitWorktree, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	// Setup logs the untracked files it copies.
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	origin := filepath.Join(dir, "origin.git")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", repo},
+		{"init", "-q", "--bare", origin},
+		{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"-C", repo, "remote", "add", "origin", origin},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, output)
+		}
+	}
+
+	g, _, err := NewGitWorktree(repo, sessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	return g, origin
+}
+
+func TestRename(t *testing.T) {
+	g, origin := setupRenameRepo(t, "old title")
+	if _, err := g.runGitCommand(g.repoPath, "push", "-q", "origin", g.branchName); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := g.worktreePath
+
+	if err := g.Rename("New Title", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.branchName != "session/new-title" {
+		t.Errorf("expected the branch to be renamed, got %s", g.branchName)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("expected the old worktree directory to be gone, got %v", err)
+	}
+	if !strings.Contains(filepath.Base(g.worktreePath), "new-title") {
+		t.Errorf("expected the worktree directory to be named after the title, got %s", g.worktreePath)
+	}
+	if current, err := g.runGitCommand(g.worktreePath, "branch", "--show-current"); err != nil ||
+		strings.TrimSpace(current) != g.branchName {
+		t.Errorf("expected the moved worktree to have %s checked out, got %q (%v)", g.branchName, current, err)
+	}
+
+	remote, err := RunGitCommand(origin, "branch", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(remote, "session/new-title") || strings.Contains(remote, "session/old-title") {
+		t.Errorf("expected the remote branch to be renamed, got %q", remote)
+	}
+}
+
+func TestRenameRollsBack(t *testing.T) {
+	g, _ := setupRenameRepo(t, "old title")
+	if _, err := g.runGitCommand(g.repoPath, "branch", "session/taken"); err != nil {
+		t.Fatal(err)
+	}
+	oldBranch, oldPath := g.branchName, g.worktreePath
+
+	if err := g.Rename("taken", nil); err == nil {
+		t.Fatal("expected renaming to an existing branch to fail")
+	}
+	if g.branchName != oldBranch || g.worktreePath != oldPath || g.sessionName != "old title" {
+		t.Errorf("expected the worktree to be unchanged, got %s %s %s", g.branchName, g.worktreePath, g.sessionName)
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Errorf("expected the worktree directory to still exist: %v", err)
+	}
+}
+
+func TestRenameRollsBackAfterThen(t *testing.T) {
+	g, origin := setupRenameRepo(t, "old title")
+	if _, err := g.runGitCommand(g.repoPath, "push", "-q", "origin", g.branchName); err != nil {
+		t.Fatal(err)
+	}
+	oldBranch, oldPath := g.branchName, g.worktreePath
+
+	if err := g.Rename("new title", func() error { return errors.New("disk full") }); err == nil {
+		t.Fatal("expected the rename to fail when saving it fails")
+	}
+	if g.branchName != oldBranch || g.worktreePath != oldPath || g.sessionName != "old title" {
+		t.Errorf("expected the worktree to be unchanged, got %s %s %s", g.branchName, g.worktreePath, g.sessionName)
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Errorf("expected the worktree directory to be moved back: %v", err)
+	}
+	remote, err := RunGitCommand(origin, "branch", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(remote, oldBranch) || strings.Contains(remote, "session/new-title") {
+		t.Errorf("expected the remote branch to be renamed back, got %q", remote)
+	}
+}
+
+func TestRenameKeepsContinuedBranch(t *testing.T) {
+	g, origin := setupRenameRepo(t, "setup")
+	// A pull request branch which happens to contain the session's name is continued.
+	if _, err := g.runGitCommand(g.repoPath, "branch", "feature/fix-login"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "push", "-q", "origin", "feature/fix-login"); err != nil {
+		t.Fatal(err)
+	}
+	continued, _, err := NewGitWorktreeWithBranch(g.repoPath, "fix login", "feature/fix-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := continued.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if continued.CreatedBranch() || !g.CreatedBranch() {
+		t.Fatalf("CreatedBranch() = %v for a continued branch and %v for a new one", continued.CreatedBranch(),
+			g.CreatedBranch())
+	}
+
+	if err := continued.Rename("login redirect", nil); err != nil {
+		t.Fatal(err)
+	}
+	if continued.branchName != "feature/fix-login" {
+		t.Errorf("the continued branch was renamed to %s", continued.branchName)
+	}
+	if !strings.Contains(filepath.Base(continued.worktreePath), "login-redirect") {
+		t.Errorf("expected the worktree directory to still be renamed, got %s", continued.worktreePath)
+	}
+	remote, err := RunGitCommand(origin, "branch", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(remote, "feature/fix-login") {
+		t.Errorf("the remote branch of the pull request was deleted: %q", remote)
+	}
+}
+
+func TestRenameKeepsForeignRemoteCommits(t *testing.T) {
+	g, origin := setupRenameRepo(t, "old title")
+	// Someone else pushed a commit to the branch which isn't here.
+	tree, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD^{tree}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := g.runGitCommand(g.repoPath, "-c", "user.name=other", "-c", "user.email=other@example.com",
+		"commit-tree", strings.TrimSpace(tree), "-p", "HEAD", "-m", "their work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.runGitCommand(g.repoPath, "push", "-q", "origin",
+		strings.TrimSpace(foreign)+":refs/heads/"+g.branchName); err != nil {
+		t.Fatal(err)
+	}
+	oldBranch := g.branchName
+
+	if err := g.Rename("new title", nil); err == nil {
+		t.Fatal("expected renaming a branch with commits only on the remote to fail")
+	}
+	if g.branchName != oldBranch {
+		t.Errorf("expected the branch to be unchanged, got %s", g.branchName)
+	}
+	remote, err := RunGitCommand(origin, "branch", "--list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(remote, oldBranch) {
+		t.Errorf("the remote branch with someone else's commit was deleted: %q", remote)
+	}
+}
+
+func TestUndoStackOrder(t *testing.T) {
+	var order []int
+	var undos undoStack
+	for i := 0; i < 3; i++ {
+		undos.push(func() error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	if err := undos.run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != 2 || order[2] != 0 {
+		t.Errorf("expected undos to run newest first, got %v", order)
+	}
+}
@@ -0,0 +1,34 @@
+package session
+
+import "time"
+
+// IdlePolicy decides when local instances are paused for being idle, so they stop holding a worktree and an agent
+// process. Pausing commits their work, so nothing is lost.
+type IdlePolicy struct {
+	// After is how long an instance has to be ready without output before it's paused. Zero disables the policy.
+	After time.Duration
+}
+
+// ShouldPause returns true if the instance has been ready and idle for long enough to be paused at now. Instances
+// which opted out with KeepAlive, cloud instances and ones waiting for input are never paused.
+func (p IdlePolicy) ShouldPause(i *Instance, now time.Time) bool {
+	if p.After <= 0 || !i.started || i.IsCloud || i.KeepAlive || i.Status != Ready {
+		return false
+	}
+	return !i.LastActivity.IsZero() && now.Sub(i.LastActivity) >= p.After
+}
+
+// MarkActive records that the instance produced output at now.
+func (i *Instance) MarkActive(now time.Time) {
+	i.LastActivity = now
+}
+
+// AutoPause pauses an idle instance like Pause, but leaves the clipboard alone and marks the instance as paused
+// for being idle.
+func (i *Instance) AutoPause() error {
+	if err := i.pause(); err != nil {
+		return err
+	}
+	i.AutoPaused = true
+	return nil
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdlePolicyShouldPause(t *testing.T) {
+	now := time.Now()
+	idle := now.Add(-3 * time.Hour)
+	tests := []struct {
+		name     string
+		policy   IdlePolicy
+		instance *Instance
+		want     bool
+	}{
+		{"idle long enough", IdlePolicy{After: 2 * time.Hour},
+			&Instance{started: true, Status: Ready, LastActivity: idle}, true},
+		{"not idle long enough", IdlePolicy{After: 4 * time.Hour},
+			&Instance{started: true, Status: Ready, LastActivity: idle}, false},
+		{"disabled", IdlePolicy{},
+			&Instance{started: true, Status: Ready, LastActivity: idle}, false},
+		{"opted out", IdlePolicy{After: time.Hour},
+			&Instance{started: true, Status: Ready, LastActivity: idle, KeepAlive: true}, false},
+		{"waiting for input", IdlePolicy{After: time.Hour},
+			&Instance{started: true, Status: WaitingForInput, LastActivity: idle}, false},
+		{"already paused", IdlePolicy{After: time.Hour},
+			&Instance{started: true, Status: Paused, LastActivity: idle}, false},
+		{"cloud", IdlePolicy{After: time.Hour},
+			&Instance{started: true, Status: Ready, LastActivity: idle, IsCloud: true}, false},
+		{"never active", IdlePolicy{After: time.Hour},
+			&Instance{started: true, Status: Ready}, false},
+		{"not started", IdlePolicy{After: time.Hour},
+			&Instance{Status: Ready, LastActivity: idle}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.ShouldPause(tt.instance, now); got != tt.want {
+				t.Errorf("ShouldPause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"io"
+	"log/slog"
 	"orzbob/log"
 	"orzbob/session/git"
 	"orzbob/session/tmux"
@@ -53,6 +55,17 @@ type Instance struct {
 	AutoYes bool
 	// Prompt is the initial prompt to pass to the instance on startup
 	Prompt string
+	// Tags are user-defined labels used to filter the instance list.
+	Tags []string
+	// LastActivity is the last time the instance produced output, or was started or resumed.
+	LastActivity time.Time
+	// KeepAlive opts the instance out of being paused when it's idle.
+	KeepAlive bool
+	// AutoPaused is true if the instance was paused because it was idle. It's cleared when it's resumed.
+	AutoPaused bool
+	// Transcript is the file holding the output of the instance from before it was imported, if it has one. It's
+	// shown above the output of the instance's pane, and kept when the instance is killed so it can still be searched.
+	Transcript string
 
 	// Cloud instance fields
 	// IsCloud indicates if this is a cloud instance
@@ -68,6 +81,8 @@ type Instance struct {
 
 	// DiffStats stores the current git diff statistics
 	diffStats *git.DiffStats
+	// diskUsage is the size of the worktree in bytes, measured in the background.
+	diskUsage int64
 
 	// The below fields are initialized upon calling Start().
 
@@ -76,6 +91,11 @@ type Instance struct {
 	tmuxSession *tmux.TmuxSession
 	// gitWorktree is the git worktree for the instance.
 	gitWorktree *git.GitWorktree
+	// base, continueBranch and sparse are the Base, Continue and Sparse options, used when the instance is first
+	// started.
+	base           string
+	continueBranch bool
+	sparse         []string
 }
 
 // ToInstanceData converts an Instance to its serializable form
@@ -91,6 +111,12 @@ func (i *Instance) ToInstanceData() InstanceData {
 		UpdatedAt:       time.Now(),
 		Program:         i.Program,
 		AutoYes:         i.AutoYes,
+		Tags:            i.Tags,
+		Prompt:          i.Prompt,
+		LastActivity:    i.LastActivity,
+		KeepAlive:       i.KeepAlive,
+		AutoPaused:      i.AutoPaused,
+		Transcript:      i.Transcript,
 		IsCloud:         i.IsCloud,
 		CloudInstanceID: i.CloudInstanceID,
 		AttachURL:       i.AttachURL,
@@ -100,12 +126,16 @@ func (i *Instance) ToInstanceData() InstanceData {
 
 	// Only include worktree data if gitWorktree is initialized
 	if i.gitWorktree != nil {
+		createdBranch := i.gitWorktree.CreatedBranch()
 		data.Worktree = GitWorktreeData{
-			RepoPath:      i.gitWorktree.GetRepoPath(),
-			WorktreePath:  i.gitWorktree.GetWorktreePath(),
-			SessionName:   i.Title,
-			BranchName:    i.gitWorktree.GetBranchName(),
-			BaseCommitSHA: i.gitWorktree.GetBaseCommitSHA(),
+			RepoPath:       i.gitWorktree.GetRepoPath(),
+			WorktreePath:   i.gitWorktree.GetWorktreePath(),
+			SessionName:    i.Title,
+			BranchName:     i.gitWorktree.GetBranchName(),
+			BaseCommitSHA:  i.gitWorktree.GetBaseCommitSHA(),
+			BaseRef:        i.gitWorktree.GetBaseRef(),
+			SparsePatterns: i.gitWorktree.GetSparsePatterns(),
+			CreatedBranch:  &createdBranch,
 		}
 	}
 
@@ -132,6 +162,12 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		Width:           data.Width,
 		CreatedAt:       data.CreatedAt,
 		UpdatedAt:       data.UpdatedAt,
+		Tags:            data.Tags,
+		Prompt:          data.Prompt,
+		LastActivity:    data.LastActivity,
+		KeepAlive:       data.KeepAlive,
+		AutoPaused:      data.AutoPaused,
+		Transcript:      data.Transcript,
 		IsCloud:         data.IsCloud,
 		CloudInstanceID: data.CloudInstanceID,
 		AttachURL:       data.AttachURL,
@@ -152,6 +188,20 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		},
 	}
 
+	instance.gitWorktree.SetBase(data.Worktree.BaseRef)
+	instance.gitWorktree.SetSparse(data.Worktree.SparsePatterns)
+	if data.Worktree.CreatedBranch != nil {
+		instance.gitWorktree.SetCreatedBranch(*data.Worktree.CreatedBranch)
+	} else {
+		// Sessions always created their branch back then, named after their title. A branch with another name was
+		// continued.
+		instance.gitWorktree.SetCreatedBranch(git.IsSessionBranch(data.Worktree.BranchName, data.Worktree.SessionName))
+	}
+
+	if instance.IsCloud {
+		// Cloud instances have no local session. The worktree data is kept for bringing them home.
+		return instance, nil
+	}
 	if instance.Paused() {
 		instance.started = true
 		instance.tmuxSession = tmux.NewTmuxSession(instance.Title, instance.Program)
@@ -174,6 +224,16 @@ type InstanceOptions struct {
 	Program string
 	// If AutoYes is true, then
 	AutoYes bool
+	// Branch is the branch to create for the instance. If it's empty, the branch is named after the title.
+	Branch string
+	// Base is the branch, tag, commit or pull request (e.g. #123) the branch is started from. It's fetched from
+	// origin if needed. If it's empty, the branch is started from HEAD.
+	Base string
+	// Continue makes the instance work on Branch, which already exists here or on origin, instead of creating it.
+	Continue bool
+	// Sparse are the directories the worktree checks out. If it's nil, the repository's defaults from
+	// git.SparseConfigKey are used, and if it's empty everything is checked out.
+	Sparse []string
 }
 
 func NewInstance(opts InstanceOptions) (*Instance, error) {
@@ -184,17 +244,24 @@ func NewInstance(opts InstanceOptions) (*Instance, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
+	if opts.Continue && opts.Branch == "" {
+		return nil, fmt.Errorf("the branch to continue is missing")
+	}
 
 	return &Instance{
-		Title:     opts.Title,
-		Status:    Ready,
-		Path:      absPath,
-		Program:   opts.Program,
-		Height:    0,
-		Width:     0,
-		CreatedAt: t,
-		UpdatedAt: t,
-		AutoYes:   false,
+		Title:          opts.Title,
+		Status:         Ready,
+		Path:           absPath,
+		Program:        opts.Program,
+		Branch:         opts.Branch,
+		Height:         0,
+		Width:          0,
+		CreatedAt:      t,
+		UpdatedAt:      t,
+		AutoYes:        false,
+		base:           opts.Base,
+		continueBranch: opts.Continue,
+		sparse:         opts.Sparse,
 	}, nil
 }
 
@@ -219,12 +286,9 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 	i.tmuxSession = tmuxSession
 
 	if firstTimeSetup {
-		gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title)
-		if err != nil {
-			return fmt.Errorf("failed to create git worktree: %w", err)
+		if err := i.newGitWorktree(); err != nil {
+			return err
 		}
-		i.gitWorktree = gitWorktree
-		i.Branch = branchName
 	}
 
 	// Setup error handler to cleanup resources on any error
@@ -234,6 +298,7 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			if cleanupErr := i.Kill(); cleanupErr != nil {
 				setupErr = fmt.Errorf("%v (cleanup error: %v)", setupErr, cleanupErr)
 			}
+			i.logger().Error("failed to start", "error", setupErr)
 		} else {
 			i.started = true
 		}
@@ -247,7 +312,11 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 		}
 	} else {
 		// Setup git worktree first
-		if err := i.gitWorktree.Setup(); err != nil {
+		setup := i.gitWorktree.Setup
+		if i.continueBranch {
+			setup = i.gitWorktree.SetupFromExistingBranch
+		}
+		if err := setup(); err != nil {
 			setupErr = fmt.Errorf("failed to setup git worktree: %w", err)
 			return setupErr
 		}
@@ -261,6 +330,8 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			setupErr = fmt.Errorf("failed to start new session: %w", err)
 			return setupErr
 		}
+		i.LastActivity = time.Now()
+		i.logger().Info("started", "branch", i.Branch, "program", i.Program)
 	}
 
 	i.SetStatus(Running)
@@ -268,8 +339,62 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 	return nil
 }
 
+// newGitWorktree sets up the git worktree of a new instance, without creating it yet.
+func (i *Instance) newGitWorktree() error {
+	var gitWorktree *git.GitWorktree
+	var branchName string
+	var err error
+	if i.Branch != "" {
+		gitWorktree, branchName, err = git.NewGitWorktreeWithBranch(i.Path, i.Title, i.Branch)
+	} else {
+		gitWorktree, branchName, err = git.NewGitWorktree(i.Path, i.Title)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create git worktree: %w", err)
+	}
+	if !i.continueBranch {
+		gitWorktree.SetBase(i.base)
+	}
+	if i.sparse == nil {
+		if i.sparse, err = git.SparseDefaults(gitWorktree.GetRepoPath()); err != nil {
+			return err
+		}
+	}
+	gitWorktree.SetSparse(i.sparse)
+	i.gitWorktree = gitWorktree
+	i.Branch = branchName
+	return nil
+}
+
+// StartPaused starts a new instance as if it was paused right away: its branch is created, but not its worktree or
+// its tmux session, so its program doesn't run here. It's for instances which are sent to the cloud as soon as
+// they're created. The branch has to be new.
+func (i *Instance) StartPaused() error {
+	if i.Title == "" {
+		return fmt.Errorf("instance title cannot be empty")
+	}
+	if i.continueBranch {
+		return fmt.Errorf("instance %s continues a branch, so it can't be started paused", i.Title)
+	}
+	if err := i.newGitWorktree(); err != nil {
+		return err
+	}
+	if err := i.gitWorktree.CreateBranch(); err != nil {
+		return err
+	}
+	i.started = true
+	i.LastActivity = time.Now()
+	i.SetStatus(Paused)
+	i.logger().Info("started paused", "branch", i.Branch, "program", i.Program)
+	return nil
+}
+
 // Kill terminates the instance and cleans up all resources
 func (i *Instance) Kill() error {
+	if i.IsCloud {
+		// The cloud instance itself is deleted through the cloud manager.
+		return i.DisconnectCloud()
+	}
 	if !i.started {
 		// If instance was never started, just return success
 		return nil
@@ -292,7 +417,17 @@ func (i *Instance) Kill() error {
 		}
 	}
 
-	return i.combineErrors(errs)
+	if err := i.combineErrors(errs); err != nil {
+		i.logger().Error("failed to kill", "error", err)
+		return err
+	}
+	i.logger().Info("killed")
+	return nil
+}
+
+// logger returns a logger which tags records with the instance.
+func (i *Instance) logger() *slog.Logger {
+	return log.ForInstance(i.Title)
 }
 
 // combineErrors combines multiple errors into a single error
@@ -326,6 +461,89 @@ func (i *Instance) Preview() (string, error) {
 	return i.tmuxSession.CapturePaneContent()
 }
 
+// Scrollback returns the full pane history of the instance, including the visible screen. For a cloud instance,
+// it's the output received since ConnectCloud.
+func (i *Instance) Scrollback() (string, error) {
+	if i.IsCloud {
+		if !i.CloudConnected() {
+			return "", fmt.Errorf("cloud instance %s isn't connected yet", i.Title)
+		}
+		return i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+	}
+	if !i.started || i.Status == Paused {
+		return "", nil
+	}
+	return i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+}
+
+// ScrollbackLines returns the full pane history of the instance as plain text lines, after its transcript if it has
+// one. Escape sequences and the blank lines below the output are removed.
+func (i *Instance) ScrollbackLines() ([]string, error) {
+	var lines []string
+	if i.Transcript != "" {
+		data, err := os.ReadFile(i.Transcript)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read the transcript: %w", err)
+		}
+		if transcript := strings.TrimRight(string(data), "\n "); transcript != "" {
+			lines = strings.Split(transcript, "\n")
+		}
+	}
+
+	content, err := i.Scrollback()
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimRight(StripANSI(content), "\n ")
+	if content == "" {
+		return lines, nil
+	}
+	return append(lines, strings.Split(content, "\n")...), nil
+}
+
+// HasScrollback returns true if the instance has output to scroll through: it's running, or it's paused with a
+// transcript.
+func (i *Instance) HasScrollback() bool {
+	if i.IsCloud {
+		return true
+	}
+	return i.started && (!i.Paused() || i.Transcript != "")
+}
+
+// ConnectCloud connects a cloud instance to the WebSocket of its terminal, conn, and records the output received over
+// it, so its scrollback can be read like a local instance's. conn is closed if the instance is already connected.
+func (i *Instance) ConnectCloud(conn io.ReadWriteCloser) error {
+	if !i.IsCloud {
+		conn.Close()
+		return fmt.Errorf("instance %s isn't in the cloud", i.Title)
+	}
+	if i.CloudConnected() {
+		return conn.Close()
+	}
+	tmuxSession := tmux.NewTmuxSession(i.Title, i.Program)
+	if err := tmuxSession.WSAttach(conn, i.AttachURL); err != nil {
+		conn.Close()
+		return err
+	}
+	i.tmuxSession = tmuxSession
+	return nil
+}
+
+// CloudConnected returns true if the instance is in the cloud and ConnectCloud connected it.
+func (i *Instance) CloudConnected() bool {
+	return i.IsCloud && i.tmuxSession != nil
+}
+
+// DisconnectCloud closes the WebSocket opened by ConnectCloud, if any.
+func (i *Instance) DisconnectCloud() error {
+	if !i.CloudConnected() {
+		return nil
+	}
+	err := i.tmuxSession.Disconnect()
+	i.tmuxSession = nil
+	return err
+}
+
 func (i *Instance) HasUpdated() (updated bool, hasPrompt bool) {
 	if !i.started {
 		return false, false
@@ -339,7 +557,7 @@ func (i *Instance) TapEnter() {
 		return
 	}
 	if err := i.tmuxSession.TapEnter(); err != nil {
-		log.ErrorLog.Printf("error tapping enter: %v", err)
+		i.logger().Error("error tapping enter", "error", err)
 	}
 }
 
@@ -380,17 +598,81 @@ func (i *Instance) SetTitle(title string) error {
 	return nil
 }
 
+// Rename changes the title of a started instance, renaming everything named after it: the tmux session, the git
+// branch (and the remote branch, if it was pushed) and the worktree directory. save is called last, once the
+// instance has its new title, to save the instances. If any step fails, save included, the earlier ones are rolled
+// back and the instance is left unchanged. save may be nil.
+func (i *Instance) Rename(title string, save func() error) error {
+	if save == nil {
+		save = func() error { return nil }
+	}
+	if !i.started {
+		oldTitle := i.Title
+		if err := i.SetTitle(title); err != nil {
+			return err
+		}
+		if err := save(); err != nil {
+			i.Title = oldTitle
+			return err
+		}
+		return nil
+	}
+	if title == "" {
+		return fmt.Errorf("instance title cannot be empty")
+	}
+	if title == i.Title {
+		return nil
+	}
+	if i.IsCloud {
+		return fmt.Errorf("cannot rename cloud instance %s", i.Title)
+	}
+
+	oldTitle, oldBranch := i.Title, i.Branch
+	if err := i.tmuxSession.Rename(title); err != nil {
+		return err
+	}
+	err := i.gitWorktree.Rename(title, func() error {
+		i.Title = title
+		i.Branch = i.gitWorktree.GetBranchName()
+		if err := save(); err != nil {
+			i.Title, i.Branch = oldTitle, oldBranch
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if undoErr := i.tmuxSession.Rename(oldTitle); undoErr != nil {
+			err = fmt.Errorf("%v (rollback error: %v)", err, undoErr)
+		}
+		return fmt.Errorf("failed to rename %s: %w", oldTitle, err)
+	}
+	return nil
+}
+
 func (i *Instance) Paused() bool {
 	return i.Status == Paused
 }
 
-// TmuxAlive returns true if the tmux session is alive. This is a sanity check before attaching.
+// TmuxAlive returns true if the tmux session is alive. This is a sanity check before attaching. It's always false
+// for cloud instances, which have no local tmux session.
 func (i *Instance) TmuxAlive() bool {
+	if i.IsCloud || i.tmuxSession == nil {
+		return false
+	}
 	return i.tmuxSession.DoesSessionExist()
 }
 
-// Pause stops the tmux session and removes the worktree, preserving the branch
+// Pause stops the tmux session and removes the worktree, preserving the branch. The branch name is copied to the
+// clipboard so it can be checked out.
 func (i *Instance) Pause() error {
+	if err := i.pause(); err != nil {
+		return err
+	}
+	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
+	return nil
+}
+
+func (i *Instance) pause() error {
 	if !i.started {
 		return fmt.Errorf("cannot pause instance that has not been started")
 	}
@@ -403,13 +685,14 @@ func (i *Instance) Pause() error {
 	// Check if there are any changes to commit
 	if dirty, err := i.gitWorktree.IsDirty(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to check if worktree is dirty: %w", err))
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 	} else if dirty {
 		// Commit changes with timestamp
 		commitMsg := fmt.Sprintf("[orzbob] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
-		if err := i.gitWorktree.PushChanges(commitMsg, false); err != nil {
+		// The commit only has to outlive the worktree, so it's kept local and pausing works without a remote.
+		if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
 			errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
-			log.ErrorLog.Print(err)
+			i.logger().Error(err.Error())
 			// Return early if we can't commit changes to avoid corrupted state
 			return i.combineErrors(errs)
 		}
@@ -418,7 +701,7 @@ func (i *Instance) Pause() error {
 	// Close tmux session first since it's using the git worktree
 	if err := i.tmuxSession.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 		// Return early if we can't close tmux to avoid corrupted state
 		return i.combineErrors(errs)
 	}
@@ -428,25 +711,25 @@ func (i *Instance) Pause() error {
 		// Remove worktree but keep branch
 		if err := i.gitWorktree.Remove(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove git worktree: %w", err))
-			log.ErrorLog.Print(err)
+			i.logger().Error(err.Error())
 			return i.combineErrors(errs)
 		}
 
 		// Only prune if remove was successful
 		if err := i.gitWorktree.Prune(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to prune git worktrees: %w", err))
-			log.ErrorLog.Print(err)
+			i.logger().Error(err.Error())
 			return i.combineErrors(errs)
 		}
 	}
 
 	if err := i.combineErrors(errs); err != nil {
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 		return err
 	}
 
 	i.SetStatus(Paused)
-	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
+	i.logger().Info("paused")
 	return nil
 }
 
@@ -461,7 +744,7 @@ func (i *Instance) Resume() error {
 
 	// Check if branch is checked out
 	if checked, err := i.gitWorktree.IsBranchCheckedOut(); err != nil {
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 		return fmt.Errorf("failed to check if branch is checked out: %w", err)
 	} else if checked {
 		return fmt.Errorf("cannot resume: branch is checked out, please switch to a different branch")
@@ -469,22 +752,26 @@ func (i *Instance) Resume() error {
 
 	// Setup git worktree
 	if err := i.gitWorktree.Setup(); err != nil {
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 		return fmt.Errorf("failed to setup git worktree: %w", err)
 	}
 
 	// Create new tmux session
 	if err := i.tmuxSession.Start(i.Program, i.gitWorktree.GetWorktreePath()); err != nil {
-		log.ErrorLog.Print(err)
+		i.logger().Error(err.Error())
 		// Cleanup git worktree if tmux session creation fails
 		if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
 			err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
-			log.ErrorLog.Print(err)
+			i.logger().Error(err.Error())
 		}
 		return fmt.Errorf("failed to start new session: %w", err)
 	}
 
+	i.AutoPaused = false
+	// Start the idle clock again so the instance isn't paused straight away.
+	i.LastActivity = time.Now()
 	i.SetStatus(Running)
+	i.logger().Info("resumed")
 	return nil
 }
 
@@ -519,6 +806,22 @@ func (i *Instance) GetDiffStats() *git.DiffStats {
 	return i.diffStats
 }
 
+// readySettle is how long the output of a new instance has to stay the same for WaitReady, and readyTimeout how
+// long it waits at most.
+const (
+	readySettle  = time.Second
+	readyTimeout = 30 * time.Second
+)
+
+// WaitReady waits until the program of an instance which was just started shows its prompt, so a prompt sent to it
+// isn't typed into its start-up screen.
+func (i *Instance) WaitReady() error {
+	if !i.started || i.tmuxSession == nil {
+		return fmt.Errorf("instance not started")
+	}
+	return i.tmuxSession.WaitUntilSettled(readySettle, readyTimeout)
+}
+
 // SendPrompt sends a prompt to the tmux session
 func (i *Instance) SendPrompt(prompt string) error {
 	if !i.started {
@@ -1,6 +1,14 @@
 package session
 
 import (
+	"encoding/json"
+	"io"
+	"orzbob/log"
+	"orzbob/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -52,3 +60,175 @@ func TestCloudInstanceSerialization(t *testing.T) {
 		t.Errorf("Expected restored AttachURL to be ws://example.com/attach, got %s", restored.AttachURL)
 	}
 }
+
+// pipeConn is the client end of a fake WebSocket to a cloud instance's terminal.
+type pipeConn struct {
+	*io.PipeReader
+	io.Writer
+}
+
+func (c pipeConn) Close() error {
+	return c.PipeReader.Close()
+}
+
+func TestCloudScrollback(t *testing.T) {
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+	inst := &Instance{Title: "cloud", IsCloud: true, AttachURL: "https://example.com/attach?token=x"}
+	if _, err := inst.ScrollbackLines(); err == nil {
+		t.Fatal("got the scrollback of a cloud instance which isn't connected")
+	}
+
+	r, w := io.Pipe()
+	if err := inst.ConnectCloud(pipeConn{r, io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("\x1b[32mbuilding\x1b[0m\r\ntests pass\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"building", "tests pass"}
+	var lines []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var err error
+		if lines, err = inst.ScrollbackLines(); err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(lines, want) {
+			break
+		}
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ScrollbackLines() = %q, want %q", lines, want)
+	}
+
+	if err := inst.Kill(); err != nil || inst.CloudConnected() {
+		t.Errorf("Kill() = %v, connected %v", err, inst.CloudConnected())
+	}
+	if _, err := w.Write([]byte("more\n")); err == nil {
+		t.Error("the connection is still open after Kill()")
+	}
+}
+
+func TestCloudInstanceTmuxAlive(t *testing.T) {
+	loaded, err := FromInstanceData(InstanceData{Title: "cloud", IsCloud: true, CloudInstanceID: "cloud-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	connected := &Instance{Title: "connected", IsCloud: true}
+	r, _ := io.Pipe()
+	if err := connected.ConnectCloud(pipeConn{r, io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connected.DisconnectCloud() })
+
+	// Attaching checks this first, so it must not touch the missing tmux session.
+	for _, inst := range []*Instance{loaded, connected} {
+		if inst.TmuxAlive() {
+			t.Errorf("cloud instance %s has a live tmux session", inst.Title)
+		}
+	}
+}
+
+func TestCreatedBranchOfOlderState(t *testing.T) {
+	tests := []struct {
+		name     string
+		worktree string
+		want     bool
+	}{
+		{"session branch", `"branch_name": "session/fix-login"`, true},
+		{"continued branch", `"branch_name": "feature/login"`, false},
+		{"recorded as continued", `"branch_name": "session/fix-login", "created_branch": false`, false},
+		{"recorded as created", `"branch_name": "fix-login-x1", "created_branch": true`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data InstanceData
+			state := `{"title": "fix login", "status": 3, "program": "claude",
+				"worktree": {"session_name": "fix login", ` + tt.worktree + `}}`
+			if err := json.Unmarshal([]byte(state), &data); err != nil {
+				t.Fatal(err)
+			}
+			instance, err := FromInstanceData(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			worktree, err := instance.GetGitWorktree()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := worktree.CreatedBranch(); got != tt.want {
+				t.Errorf("CreatedBranch() = %v, want %v", got, tt.want)
+			}
+			if saved := instance.ToInstanceData().Worktree.CreatedBranch; saved == nil || *saved != tt.want {
+				t.Errorf("saved created_branch = %v, want %v", saved, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartPausedForCloud(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	dir := t.TempDir()
+	origin, repo := filepath.Join(dir, "origin.git"), filepath.Join(dir, "repo")
+	runGit(t, "init", "-q", "--bare", origin)
+	runGit(t, "init", "-q", "-b", "main", repo)
+	runGit(t, "-C", repo, "commit", "-q", "--allow-empty", "-m", "initial")
+	runGit(t, "-C", repo, "remote", "add", "origin", origin)
+	head := runGit(t, "-C", repo, "rev-parse", "HEAD")
+
+	instance, err := NewInstance(InstanceOptions{Title: "cloud task", Path: repo, Program: "claude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := instance.StartPaused(); err != nil {
+		t.Fatal(err)
+	}
+	if !instance.Started() || !instance.Paused() || instance.tmuxSession != nil {
+		t.Fatalf("started %v, paused %v, with a tmux session %v, want a paused instance without one",
+			instance.Started(), instance.Paused(), instance.tmuxSession != nil)
+	}
+	if _, err := os.Stat(instance.gitWorktree.GetWorktreePath()); !os.IsNotExist(err) {
+		t.Errorf("a worktree was created for an instance which runs in the cloud")
+	}
+
+	_, branch, err := instance.PushForCloud()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed := runGit(t, "--git-dir", origin, "rev-parse", branch); pushed != head {
+		t.Errorf("origin has %s at %s, want %s", branch, pushed, head)
+	}
+	if err := instance.MoveToCloud("inst-1", "", "small", "running"); err != nil {
+		t.Fatal(err)
+	}
+	if !instance.IsCloud || instance.gitWorktree.GetBranchName() != branch {
+		t.Errorf("cloud %v on branch %q, want a cloud instance on %s", instance.IsCloud,
+			instance.gitWorktree.GetBranchName(), branch)
+	}
+
+	// An instance which couldn't be sent is killed, which deletes its branch.
+	failed, err := NewInstance(InstanceOptions{Title: "failed task", Path: repo, Program: "claude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.StartPaused(); err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	if git.BranchExists(repo, failed.Branch) {
+		t.Errorf("branch %s was kept after killing the instance", failed.Branch)
+	}
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"orzbob/session/tmux"
+	"time"
+)
+
+// PushForCloud commits the changes of a local instance and pushes its branch, so a cloud instance can check it out.
+// It returns the URL of the repository and the branch.
+func (i *Instance) PushForCloud() (repoURL, branch string, err error) {
+	if !i.started {
+		return "", "", fmt.Errorf("cannot send instance that has not been started")
+	}
+	if i.IsCloud {
+		return "", "", fmt.Errorf("instance %s is already in the cloud", i.Title)
+	}
+	if !i.Paused() {
+		commitMsg := fmt.Sprintf("[orzbob] update from '%s' on %s (sent to cloud)", i.Title,
+			time.Now().Format(time.RFC822))
+		if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+			return "", "", err
+		}
+	}
+	repoURL, err = i.gitWorktree.RemoteURL()
+	if err != nil {
+		return "", "", err
+	}
+	if err := i.gitWorktree.PushBranch(); err != nil {
+		return "", "", err
+	}
+	return repoURL, i.gitWorktree.GetBranchName(), nil
+}
+
+// MoveToCloud turns a local instance into the cloud instance it was sent to. The local session and worktree are
+// removed, but the branch and worktree data are kept so the instance can be brought home.
+func (i *Instance) MoveToCloud(id, attachURL, tier, status string) error {
+	if !i.Paused() {
+		if err := i.pause(); err != nil {
+			return err
+		}
+	}
+	i.IsCloud = true
+	i.CloudInstanceID = id
+	i.AttachURL = attachURL
+	i.CloudTier = tier
+	i.CloudStatus = status
+	i.started = false
+	i.tmuxSession = nil
+	i.SetStatus(Ready)
+	return nil
+}
+
+// MoveHome turns a cloud instance which was sent from here back into a local one. Its branch is fast-forwarded to
+// what the cloud instance pushed and it's resumed in a new worktree. The instance stays in the cloud if that fails.
+func (i *Instance) MoveHome() error {
+	if !i.IsCloud {
+		return fmt.Errorf("instance %s isn't in the cloud", i.Title)
+	}
+	if i.gitWorktree == nil || i.gitWorktree.GetBranchName() == "" {
+		return fmt.Errorf("instance %s was started in the cloud, only instances sent from here can be brought home",
+			i.Title)
+	}
+	if err := i.gitWorktree.PullBranch(); err != nil {
+		return err
+	}
+
+	cloud := *i
+	i.IsCloud = false
+	i.CloudInstanceID = ""
+	i.AttachURL = ""
+	i.CloudTier = ""
+	i.CloudStatus = ""
+	i.started = true
+	i.tmuxSession = tmux.NewTmuxSession(i.Title, i.Program)
+	i.SetStatus(Paused)
+	if err := i.Resume(); err != nil {
+		*i = cloud
+		return err
+	}
+	_ = cloud.DisconnectCloud()
+	return nil
+}
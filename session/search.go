@@ -0,0 +1,199 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"orzbob/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ansiRegex matches CSI and OSC escape sequences so that pane output can be searched as plain text.
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// StripANSI removes terminal escape sequences from s.
+func StripANSI(s string) string {
+	return ansiRegex.ReplaceAllString(s, "")
+}
+
+// SearchOptions configures a search over instance output.
+type SearchOptions struct {
+	// Query is the text (or regular expression if Regexp is set) to look for.
+	Query string
+	// Regexp treats Query as a regular expression.
+	Regexp bool
+	// IgnoreCase makes the match case-insensitive.
+	IgnoreCase bool
+	// Context is the number of lines of context to include before and after each match.
+	Context int
+}
+
+// matcher compiles the options into a line matcher.
+func (o SearchOptions) matcher() (func(string) bool, error) {
+	if o.Regexp {
+		expr := o.Query
+		if o.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search pattern: %w", err)
+		}
+		return re.MatchString, nil
+	}
+
+	if o.IgnoreCase {
+		query := strings.ToLower(o.Query)
+		return func(line string) bool {
+			return strings.Contains(strings.ToLower(line), query)
+		}, nil
+	}
+	return func(line string) bool {
+		return strings.Contains(line, o.Query)
+	}, nil
+}
+
+// Scrollback is a captured copy of an instance's pane history with escape sequences removed, or of a transcript no
+// instance has.
+type Scrollback struct {
+	Instance *Instance
+	// Transcript is the file the lines were read from if they're not an instance's.
+	Transcript string
+	Lines      []string
+}
+
+// SearchMatch is a single line of output that matched a search.
+type SearchMatch struct {
+	Instance *Instance
+	// Transcript is the file the match is in if it's not in an instance's output.
+	Transcript string
+	// Line is the zero-based line number of the match within the instance's scrollback.
+	Line int
+	// Text is the matching line.
+	Text string
+	// Before and After hold up to SearchOptions.Context lines surrounding the match.
+	Before []string
+	After  []string
+}
+
+// Source returns the title of the instance the match is in, or the name of its transcript.
+func (m SearchMatch) Source() string {
+	if m.Instance == nil {
+		return filepath.Base(m.Transcript)
+	}
+	return m.Instance.Title
+}
+
+// TranscriptDir returns the directory holding the transcripts of imported instances, in the config directory.
+func TranscriptDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "transcripts"), nil
+}
+
+// CaptureScrollback captures the history of every running instance, and the transcript of every imported one.
+// Instances which fail to capture are skipped and their errors are returned together, so one dead session doesn't
+// hide results from the others.
+func CaptureScrollback(instances []*Instance) ([]Scrollback, error) {
+	var (
+		captured []Scrollback
+		errs     []error
+	)
+	for _, instance := range instances {
+		if !instance.HasScrollback() || (instance.IsCloud && !instance.CloudConnected()) {
+			continue
+		}
+		lines, err := instance.ScrollbackLines()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not capture %s: %w", instance.Title, err))
+			continue
+		}
+		captured = append(captured, Scrollback{
+			Instance: instance,
+			Lines:    lines,
+		})
+	}
+	return captured, errors.Join(errs...)
+}
+
+// CaptureTranscripts reads the transcripts in dir which none of the instances has, such as those of instances which
+// were deleted.
+func CaptureTranscripts(dir string, instances []*Instance) ([]Scrollback, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		if instance.Transcript != "" {
+			owned[filepath.Clean(instance.Transcript)] = true
+		}
+	}
+
+	var (
+		captured []Scrollback
+		errs     []error
+	)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(path) != ".txt" || owned[path] {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not read transcript %s: %w", entry.Name(), err))
+			continue
+		}
+		if content := strings.TrimRight(string(data), "\n "); content != "" {
+			captured = append(captured, Scrollback{Transcript: path, Lines: strings.Split(content, "\n")})
+		}
+	}
+	return captured, errors.Join(errs...)
+}
+
+// Search returns every line in the scrollbacks which matches the options, in instance order.
+func Search(scrollbacks []Scrollback, opts SearchOptions) ([]SearchMatch, error) {
+	if opts.Query == "" {
+		return nil, nil
+	}
+	match, err := opts.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []SearchMatch
+	for _, sb := range scrollbacks {
+		for _, m := range searchLines(sb.Lines, match, opts.Context) {
+			m.Instance = sb.Instance
+			m.Transcript = sb.Transcript
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+// searchLines returns the matching lines along with their context.
+func searchLines(lines []string, match func(string) bool, context int) []SearchMatch {
+	var matches []SearchMatch
+	for i, line := range lines {
+		if !match(line) {
+			continue
+		}
+		start := max(0, i-context)
+		end := min(len(lines), i+context+1)
+		matches = append(matches, SearchMatch{
+			Line:   i,
+			Text:   line,
+			Before: lines[start:i],
+			After:  lines[i+1 : end],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain text", input: "hello world", want: "hello world"},
+		{name: "color codes", input: "\x1b[31merror\x1b[0m: failed", want: "error: failed"},
+		{name: "truecolor", input: "\x1b[38;2;255;0;0mred\x1b[39m", want: "red"},
+		{name: "osc title", input: "\x1b]0;title\x07prompt", want: "prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.input); got != tt.want {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	first := &Instance{Title: "first"}
+	second := &Instance{Title: "second"}
+	scrollbacks := []Scrollback{
+		{Instance: first, Lines: []string{"building", "Error: boom", "done"}},
+		{Instance: second, Lines: []string{"error: lowercase", "ok"}},
+	}
+
+	tests := []struct {
+		name    string
+		opts    SearchOptions
+		want    []SearchMatch
+		wantErr bool
+	}{
+		{
+			name: "case sensitive",
+			opts: SearchOptions{Query: "Error"},
+			want: []SearchMatch{{Instance: first, Line: 1, Text: "Error: boom", Before: []string{}, After: []string{}}},
+		},
+		{
+			name: "ignore case with context",
+			opts: SearchOptions{Query: "error", IgnoreCase: true, Context: 1},
+			want: []SearchMatch{
+				{Instance: first, Line: 1, Text: "Error: boom", Before: []string{"building"}, After: []string{"done"}},
+				{Instance: second, Line: 0, Text: "error: lowercase", Before: []string{}, After: []string{"ok"}},
+			},
+		},
+		{
+			name: "regexp",
+			opts: SearchOptions{Query: "^(done|ok)$", Regexp: true},
+			want: []SearchMatch{
+				{Instance: first, Line: 2, Text: "done", Before: []string{}, After: []string{}},
+				{Instance: second, Line: 1, Text: "ok", Before: []string{}, After: []string{}},
+			},
+		},
+		{
+			name: "empty query",
+			opts: SearchOptions{Query: ""},
+			want: nil,
+		},
+		{
+			name:    "invalid regexp",
+			opts:    SearchOptions{Query: "(", Regexp: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Search(scrollbacks, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureTranscripts(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"imported.txt": "still imported\n",
+		"deleted.txt":  "build failed\nError: boom\n",
+		"empty.txt":    "\n",
+		"notes.md":     "not a transcript\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	imported := &Instance{Title: "imported", Status: Paused, started: true,
+		Transcript: filepath.Join(dir, "imported.txt")}
+	instances := []*Instance{imported, {Title: "never started"}}
+
+	scrollbacks, err := CaptureScrollback(instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrollbacks) != 1 || scrollbacks[0].Instance != imported ||
+		!reflect.DeepEqual(scrollbacks[0].Lines, []string{"still imported"}) {
+		t.Errorf("CaptureScrollback() = %+v, want the paused instance's transcript", scrollbacks)
+	}
+
+	transcripts, err := CaptureTranscripts(dir, instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Scrollback{{Transcript: filepath.Join(dir, "deleted.txt"), Lines: []string{"build failed", "Error: boom"}}}
+	if !reflect.DeepEqual(transcripts, want) {
+		t.Errorf("CaptureTranscripts() = %+v, want only the transcript no instance has", transcripts)
+	}
+	matches, err := Search(transcripts, SearchOptions{Query: "boom"})
+	if err != nil || len(matches) != 1 || matches[0].Source() != "deleted.txt" || matches[0].Line != 1 {
+		t.Errorf("Search() = %+v, %v, want the line in deleted.txt", matches, err)
+	}
+
+	if transcripts, err := CaptureTranscripts(filepath.Join(dir, "missing"), nil); err != nil || transcripts != nil {
+		t.Errorf("CaptureTranscripts() of a missing directory = %v, %v", transcripts, err)
+	}
+}
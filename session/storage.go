@@ -18,6 +18,13 @@ type InstanceData struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	AutoYes   bool      `json:"auto_yes"`
+	Tags      []string  `json:"tags,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`
+
+	LastActivity time.Time `json:"last_activity,omitempty"`
+	KeepAlive    bool      `json:"keep_alive,omitempty"`
+	AutoPaused   bool      `json:"auto_paused,omitempty"`
+	Transcript   string    `json:"transcript,omitempty"`
 
 	Program   string          `json:"program"`
 	Worktree  GitWorktreeData `json:"worktree"`
@@ -38,6 +45,13 @@ type GitWorktreeData struct {
 	SessionName   string `json:"session_name"`
 	BranchName    string `json:"branch_name"`
 	BaseCommitSHA string `json:"base_commit_sha"`
+	// BaseRef is the branch, tag, commit or pull request the branch was started from, if it wasn't HEAD.
+	BaseRef string `json:"base_ref,omitempty"`
+	// SparsePatterns are the directories the worktree checks out, if it's a sparse checkout.
+	SparsePatterns []string `json:"sparse_patterns,omitempty"`
+	// CreatedBranch is true if the branch was created for the instance rather than continued. It's nil in state saved
+	// before it was recorded.
+	CreatedBranch *bool `json:"created_branch,omitempty"`
 }
 
 // DiffStatsData represents the serializable data of a DiffStats
@@ -64,7 +78,7 @@ func (s *Storage) SaveInstances(instances []*Instance) error {
 	// Convert instances to InstanceData
 	data := make([]InstanceData, 0)
 	for _, instance := range instances {
-		if instance.Started() {
+		if instance.Started() || instance.IsCloud {
 			data = append(data, instance.ToInstanceData())
 		}
 	}
@@ -0,0 +1,94 @@
+package tmux
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxHistoryLines is the number of lines of output kept for WebSocket sessions. It matches tmux's default
+// history-limit plus a screen.
+const maxHistoryLines = 2000 + 50
+
+// defaultScreenHeight is the height of the screen of a WebSocket session until its size is set.
+const defaultScreenHeight = 24
+
+// historyBuffer keeps the most recent lines of output received over a WebSocket. Cloud sessions have no local tmux
+// pane to capture, so it stands in for the pane's scrollback. The last height lines are treated as the visible
+// screen.
+type historyBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+	height  int
+}
+
+func newHistoryBuffer() *historyBuffer {
+	return &historyBuffer{height: defaultScreenHeight}
+}
+
+// Write appends output to the buffer. It never fails.
+func (h *historyBuffer) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data := append(h.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		h.lines = append(h.lines, strings.TrimRight(string(data[:idx]), "\r"))
+		data = data[idx+1:]
+	}
+	h.partial = append([]byte(nil), data...)
+
+	if over := len(h.lines) - maxHistoryLines; over > 0 {
+		h.lines = append([]string(nil), h.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+func (h *historyBuffer) setHeight(height int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if height > 0 {
+		h.height = height
+	}
+}
+
+// capture returns the lines between start and end, which are line numbers in the same form tmux capture-pane
+// takes: 0 is the first line of the visible screen, negative numbers are lines in the history and "-" means the
+// start or end of the history.
+func (h *historyBuffer) capture(start, end string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lines := h.lines
+	if len(h.partial) > 0 {
+		lines = append(lines[:len(lines):len(lines)], strings.TrimRight(string(h.partial), "\r"))
+	}
+	screenTop := max(0, len(lines)-h.height)
+
+	from := resolveLine(start, screenTop, 0)
+	to := resolveLine(end, screenTop, len(lines)-1)
+	from = max(0, from)
+	to = min(len(lines)-1, to)
+	if from > to {
+		return ""
+	}
+	return strings.Join(lines[from:to+1], "\n") + "\n"
+}
+
+// resolveLine converts a tmux line number into an index into the buffer.
+func resolveLine(line string, screenTop, dash int) int {
+	if line == "-" {
+		return dash
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return dash
+	}
+	return screenTop + n
+}
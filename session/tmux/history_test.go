@@ -0,0 +1,69 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryBufferCapture(t *testing.T) {
+	h := newHistoryBuffer()
+	h.setHeight(2)
+	_, _ = h.Write([]byte("one\r\ntwo\nthr"))
+	_, _ = h.Write([]byte("ee\nfour"))
+
+	tests := []struct {
+		start, end string
+		want       string
+	}{
+		{"-", "-", "one\ntwo\nthree\nfour\n"},
+		{"0", "-", "three\nfour\n"},
+		{"-1", "0", "two\nthree\n"},
+		{"-10", "-", "one\ntwo\nthree\nfour\n"},
+	}
+	for _, tt := range tests {
+		if got := h.capture(tt.start, tt.end); got != tt.want {
+			t.Errorf("capture(%q, %q) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryBufferLimit(t *testing.T) {
+	h := newHistoryBuffer()
+	for i := 0; i < maxHistoryLines+10; i++ {
+		_, _ = fmt.Fprintf(h, "line %d\n", i)
+	}
+	lines := strings.Split(strings.TrimSuffix(h.capture("-", "-"), "\n"), "\n")
+	if len(lines) != maxHistoryLines {
+		t.Fatalf("expected %d lines, got %d", maxHistoryLines, len(lines))
+	}
+	if lines[0] != "line 10" {
+		t.Errorf("expected the oldest lines to be dropped, first line is %q", lines[0])
+	}
+}
+
+func TestWaitUntilSettled(t *testing.T) {
+	session := &TmuxSession{program: "claude", isWebSocket: true, history: newHistoryBuffer()}
+	if err := session.WaitUntilSettled(20*time.Millisecond, 100*time.Millisecond); err == nil {
+		t.Error("an empty pane counts as ready")
+	}
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			_, _ = session.history.Write([]byte(fmt.Sprintf("starting %d\n", i)))
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, _ = session.history.Write([]byte("> "))
+	}()
+	start := time.Now()
+	if err := session.WaitUntilSettled(100*time.Millisecond, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("ready after %v, before the output settled", elapsed)
+	}
+	if content, _ := session.CapturePaneContent(); !strings.HasSuffix(content, "> \n") {
+		t.Errorf("ready with %q, before the program was", content)
+	}
+}
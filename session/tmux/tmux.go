@@ -13,6 +13,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/creack/pty"
@@ -44,6 +45,10 @@ type TmuxSession struct {
 	wsClient     io.ReadWriteCloser
 	isWebSocket  bool
 	reconnectURL string
+	// history keeps the output received over the WebSocket, since there's no local pane to capture.
+	history *historyBuffer
+	// disconnected is set by Disconnect, after which the WebSocket failing is expected.
+	disconnected atomic.Bool
 
 	// Initialized by Attach
 	// Deinitilaized by Detach
@@ -59,6 +64,14 @@ type TmuxSession struct {
 
 const TmuxPrefix = "orzbob_"
 
+// detachKey is the byte read from stdin which detaches from an attached session. It defaults to ctrl+q (ASCII 17).
+var detachKey byte = 17
+
+// SetDetachKey sets the byte read from stdin which detaches from an attached session.
+func SetDetachKey(b byte) {
+	detachKey = b
+}
+
 var whiteSpaceRegex = regexp.MustCompile(`\s+`)
 
 func toClaudeSquadTmuxName(str string) string {
@@ -150,6 +163,25 @@ func (t *TmuxSession) Start(program string, workDir string) error {
 	return nil
 }
 
+// Rename renames the tmux session. The PTY attached to it keeps working, since tmux clients follow the session
+// rather than its name. If the session doesn't exist, such as when the instance is paused, only the name it will be
+// started with changes.
+func (t *TmuxSession) Rename(name string) error {
+	sanitizedName := toClaudeSquadTmuxName(name)
+	if sanitizedName != t.sanitizedName && !t.isWebSocket && DoesSessionExist(t.sanitizedName) {
+		if DoesSessionExist(sanitizedName) {
+			return fmt.Errorf("tmux session already exists: %s", sanitizedName)
+		}
+		cmd := exec.Command("tmux", "rename-session", fmt.Sprintf("-t=%s", t.sanitizedName), sanitizedName)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("error renaming tmux session %s: %s (%w)", t.sanitizedName, output, err)
+		}
+	}
+	t.Name = name
+	t.sanitizedName = sanitizedName
+	return nil
+}
+
 // Restore attaches to an existing session and restores the window size
 func (t *TmuxSession) Restore() error {
 	ptmx, err := pty.Start(exec.Command("tmux", "attach-session", "-t", t.sanitizedName))
@@ -205,6 +237,29 @@ func (t *TmuxSession) SendKeys(keys string) error {
 	return err
 }
 
+// WaitUntilSettled waits until the pane shows output which hasn't changed for settle, which is when the program has
+// started and waits for input. It gives up after timeout.
+func (t *TmuxSession) WaitUntilSettled(settle, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var last string
+	changed := time.Now()
+	for {
+		content, err := t.CapturePaneContent()
+		if err != nil {
+			return err
+		}
+		if content != last {
+			last, changed = content, time.Now()
+		} else if strings.TrimSpace(content) != "" && time.Since(changed) >= settle {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to be ready", t.program)
+		}
+		time.Sleep(settle / 10)
+	}
+}
+
 // HasUpdated checks if the tmux pane content has changed since the last tick. It also returns true if
 // the tmux pane has a prompt for aider or claude code.
 func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
@@ -258,7 +313,7 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 			close(timeoutCh)
 		}()
 
-		// Read input from stdin and check for Ctrl+q
+		// Read input from stdin and check for the detach key
 		buf := make([]byte, 32)
 		for {
 			nr, err := os.Stdin.Read(buf)
@@ -283,8 +338,8 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 				continue
 			}
 
-			// Check for Ctrl+q (ASCII 17)
-			if nr == 1 && buf[0] == 17 {
+			// Check for the detach key
+			if nr == 1 && buf[0] == detachKey {
 				// Detach from the session
 				t.Detach()
 				return
@@ -341,6 +396,9 @@ func (t *TmuxSession) Detach() {
 
 // Close terminates the tmux session and cleans up resources
 func (t *TmuxSession) Close() error {
+	if t.isWebSocket {
+		return t.Disconnect()
+	}
 	var errs []error
 
 	if t.ptmx != nil {
@@ -372,6 +430,10 @@ func (t *TmuxSession) Close() error {
 // SetDetachedSize set the width and height of the session while detached. This makes the
 // tmux output conform to the specified shape.
 func (t *TmuxSession) SetDetachedSize(width, height int) error {
+	if t.isWebSocket {
+		t.history.setHeight(height)
+		return nil
+	}
 	return t.updateWindowSize(width, height)
 }
 
@@ -398,6 +460,9 @@ func (t *TmuxSession) DoesSessionExist() bool {
 
 // CapturePaneContent captures the content of the tmux pane
 func (t *TmuxSession) CapturePaneContent() (string, error) {
+	if t.isWebSocket {
+		return t.history.capture("0", "-"), nil
+	}
 	// Add -e flag to preserve escape sequences (ANSI color codes)
 	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-t", t.sanitizedName)
 	output, err := cmd.Output()
@@ -410,6 +475,9 @@ func (t *TmuxSession) CapturePaneContent() (string, error) {
 // CapturePaneContentWithOptions captures the pane content with additional options
 // start and end specify the starting and ending line numbers (use "-" for the start/end of history)
 func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string, error) {
+	if t.isWebSocket {
+		return t.history.capture(start, end), nil
+	}
 	// Add -e flag to preserve escape sequences (ANSI color codes)
 	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-S", start, "-E", end, "-t", t.sanitizedName)
 	output, err := cmd.Output()
@@ -419,6 +487,37 @@ func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string,
 	return string(output), nil
 }
 
+// SessionName returns the name of the tmux session of the instance with the given title.
+func SessionName(title string) string {
+	return toClaudeSquadTmuxName(title)
+}
+
+// ListSessions returns the names of the tmux sessions started by orz. It returns none if no tmux server is running.
+func ListSessions() ([]string, error) {
+	output, err := exec.Command("tmux", "ls", "-F", "#{session_name}").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list tmux sessions: %v", err)
+	}
+	var names []string
+	for _, name := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if strings.HasPrefix(name, TmuxPrefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// KillSession kills the tmux session with the given name.
+func KillSession(name string) error {
+	if output, err := exec.Command("tmux", "kill-session", "-t="+name).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to kill tmux session %s: %s (%v)", name, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
 // CleanupSessions kills all tmux sessions that start with "session-"
 func CleanupSessions() error {
 	// First try to list sessions
@@ -464,12 +563,18 @@ func (t *TmuxSession) WSAttach(wsClient io.ReadWriteCloser, reconnectURL string)
 		return fmt.Errorf("failed to create pipe: %w", err)
 	}
 	t.ptmx = w
+	t.history = newHistoryBuffer()
 
-	// Start goroutine to copy WebSocket data to pipe
+	// Nothing reads the other end of the pipe, so drain it to keep writes from blocking once it fills up.
+	go func() {
+		_, _ = io.Copy(io.Discard, r)
+	}()
+
+	// Start goroutine to copy WebSocket data to the pipe and the history
 	go func() {
 		defer r.Close()
 		defer w.Close()
-		if _, err := io.Copy(w, wsClient); err != nil {
+		if _, err := io.Copy(io.MultiWriter(t.history, w), wsClient); err != nil && !t.disconnected.Load() {
 			log.ErrorLog.Printf("Error copying WebSocket data to pipe: %v", err)
 		}
 	}()
@@ -477,6 +582,17 @@ func (t *TmuxSession) WSAttach(wsClient io.ReadWriteCloser, reconnectURL string)
 	return nil
 }
 
+// Disconnect closes the WebSocket of a cloud session. The cloud instance keeps running.
+func (t *TmuxSession) Disconnect() error {
+	if !t.isWebSocket || t.wsClient == nil {
+		return nil
+	}
+	t.disconnected.Store(true)
+	err := t.wsClient.Close()
+	t.wsClient = nil
+	return err
+}
+
 // IsConnected returns whether the WebSocket is still connected
 func (t *TmuxSession) IsConnected() bool {
 	if !t.isWebSocket || t.wsClient == nil {
@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"orzbob/session"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// MinGridTiles is the fewest instances the grid shows. With fewer, the list is more useful.
+	MinGridTiles = 2
+	// MaxGridTiles is the most instances the grid shows, in a 3x3 layout.
+	MaxGridTiles = 9
+)
+
+// Grid shows live previews of several instances at once, one tile per instance. One tile is focused, and actions
+// such as attaching apply to it.
+type Grid struct {
+	spinner *spinner.Model
+	tiles   []gridTile
+	focused int
+
+	width, height int
+	cols, rows    int
+}
+
+type gridTile struct {
+	instance *session.Instance
+	preview  *PreviewPane
+}
+
+// NewGrid creates a grid of the given instances. Only the first MaxGridTiles are shown.
+func NewGrid(spinner *spinner.Model, instances []*session.Instance) (*Grid, error) {
+	if len(instances) < MinGridTiles {
+		return nil, fmt.Errorf("the grid needs at least %d instances", MinGridTiles)
+	}
+	if len(instances) > MaxGridTiles {
+		instances = instances[:MaxGridTiles]
+	}
+
+	g := &Grid{spinner: spinner}
+	for _, instance := range instances {
+		g.tiles = append(g.tiles, gridTile{instance: instance, preview: NewPreviewPane()})
+	}
+	// Use as square a layout as possible, preferring wider over taller.
+	g.cols = int(math.Ceil(math.Sqrt(float64(len(g.tiles)))))
+	g.rows = (len(g.tiles) + g.cols - 1) / g.cols
+	return g, nil
+}
+
+// SetSize sets the size of the whole grid and sizes each tile's preview to fit.
+func (g *Grid) SetSize(width, height int) {
+	g.width = width
+	g.height = height
+	previewWidth, previewHeight := g.PreviewSize()
+	for _, tile := range g.tiles {
+		tile.preview.SetSize(previewWidth, previewHeight)
+	}
+}
+
+// tileSize returns the outer size of a tile.
+func (g *Grid) tileSize() (int, int) {
+	return g.width / g.cols, g.height / g.rows
+}
+
+// PreviewSize returns the size of the preview inside each tile. Sessions shown in the grid should be resized to it
+// so their output isn't wrapped.
+func (g *Grid) PreviewSize() (int, int) {
+	width, height := g.tileSize()
+	// Each tile has a one line header with the title, status and diff stats.
+	return paneContentSize(gridTileStyle, width, height, 1)
+}
+
+// SetSessionPreviewSize resizes the sessions shown in the grid to fit their tiles.
+func (g *Grid) SetSessionPreviewSize() (err error) {
+	width, height := g.PreviewSize()
+	for _, tile := range g.tiles {
+		if !tile.instance.Started() || tile.instance.Paused() {
+			continue
+		}
+		if innerErr := tile.instance.SetPreviewSize(width, height); innerErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not set preview size for %s: %v", tile.instance.Title, innerErr))
+		}
+	}
+	return
+}
+
+// UpdateContent refreshes the preview of every tile.
+func (g *Grid) UpdateContent() (err error) {
+	for _, tile := range g.tiles {
+		if innerErr := tile.preview.UpdateContent(tile.instance); innerErr != nil {
+			err = errors.Join(err, innerErr)
+		}
+	}
+	return
+}
+
+// Focused returns the instance of the focused tile.
+func (g *Grid) Focused() *session.Instance {
+	return g.tiles[g.focused].instance
+}
+
+// Focus focuses the tile of the given instance, if it's in the grid.
+func (g *Grid) Focus(instance *session.Instance) {
+	for i, tile := range g.tiles {
+		if tile.instance == instance {
+			g.focused = i
+			return
+		}
+	}
+}
+
+// Move moves the focus by dx columns and dy rows, staying within the grid.
+func (g *Grid) Move(dx, dy int) {
+	col := g.focused%g.cols + dx
+	row := g.focused/g.cols + dy
+	if col < 0 || col >= g.cols || row < 0 || row >= g.rows {
+		return
+	}
+	if idx := row*g.cols + col; idx < len(g.tiles) {
+		g.focused = idx
+	}
+}
+
+// String renders the grid.
+func (g *Grid) String() string {
+	if g.width == 0 || g.height == 0 {
+		return ""
+	}
+	tileWidth, _ := g.tileSize()
+
+	rows := make([]string, 0, g.rows)
+	for r := 0; r < g.rows; r++ {
+		var row []string
+		for c := 0; c < g.cols; c++ {
+			idx := r*g.cols + c
+			if idx >= len(g.tiles) {
+				break
+			}
+			row = append(row, g.renderTile(g.tiles[idx], idx == g.focused, tileWidth))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (g *Grid) renderTile(tile gridTile, focused bool, width int) string {
+	style := gridTileStyle
+	if focused {
+		style = gridFocusedTileStyle
+	}
+	innerWidth := width - style.GetHorizontalFrameSize()
+	return lipgloss.JoinVertical(lipgloss.Left,
+		g.renderTileHeader(tile.instance, focused, width),
+		style.Width(innerWidth).Render(tile.preview.String()),
+	)
+}
+
+// renderTileHeader renders the status, title and diff stats of a tile on one line.
+func (g *Grid) renderTileHeader(instance *session.Instance, focused bool, width int) string {
+	title := instance.Title
+	if instance.IsCloud {
+		title = cloudIcon + title
+	}
+
+	var stats string
+	if diff := instance.GetDiffStats(); diff != nil && diff.Error == nil && !diff.IsEmpty() {
+		stats = addedLinesStyle.Render(fmt.Sprintf("+%d", diff.Added)) + " " +
+			removedLinesStyle.Render(fmt.Sprintf("-%d", diff.Removed))
+	}
+
+	titleStyle := gridTitleStyle
+	if focused {
+		titleStyle = gridFocusedTitleStyle
+	}
+	icon := statusIcon(instance.Status, g.spinner)
+	avail := width - lipgloss.Width(icon) - lipgloss.Width(stats) - 2
+	if runes := []rune(title); avail > 3 && lipgloss.Width(title) > avail {
+		title = string(runes[:min(len(runes), avail-3)]) + "..."
+	}
+
+	left := " " + icon + titleStyle.Render(title)
+	gap := max(1, width-lipgloss.Width(left)-lipgloss.Width(stats)-1)
+	return left + strings.Repeat(" ", gap) + stats
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"orzbob/session"
+)
+
+func gridInstances(n int) []*session.Instance {
+	instances := make([]*session.Instance, n)
+	for i := range instances {
+		instances[i] = &session.Instance{Title: fmt.Sprintf("instance-%d", i), Status: session.Ready, IsCloud: true}
+	}
+	return instances
+}
+
+func TestGridLayout(t *testing.T) {
+	s := spinner.New()
+	tests := []struct {
+		instances  int
+		cols, rows int
+		tiles      int
+	}{
+		{instances: 2, cols: 2, rows: 1, tiles: 2},
+		{instances: 3, cols: 2, rows: 2, tiles: 3},
+		{instances: 5, cols: 3, rows: 2, tiles: 5},
+		{instances: 9, cols: 3, rows: 3, tiles: 9},
+		{instances: 12, cols: 3, rows: 3, tiles: 9},
+	}
+	for _, tt := range tests {
+		g, err := NewGrid(&s, gridInstances(tt.instances))
+		if err != nil {
+			t.Fatalf("NewGrid(%d) error = %v", tt.instances, err)
+		}
+		if g.cols != tt.cols || g.rows != tt.rows || len(g.tiles) != tt.tiles {
+			t.Errorf("NewGrid(%d) = %dx%d with %d tiles, want %dx%d with %d", tt.instances, g.cols, g.rows,
+				len(g.tiles), tt.cols, tt.rows, tt.tiles)
+		}
+	}
+
+	if _, err := NewGrid(&s, gridInstances(1)); err == nil {
+		t.Error("expected an error for a single instance")
+	}
+}
+
+func TestGridMoveAndSize(t *testing.T) {
+	s := spinner.New()
+	instances := gridInstances(5)
+	g, err := NewGrid(&s, instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetSize(120, 40)
+
+	width, height := g.PreviewSize()
+	if width != 120/3-2 || height != 40/2-3 {
+		t.Errorf("PreviewSize() = %dx%d", width, height)
+	}
+
+	g.Move(1, 1) // to the second row, second column
+	if g.Focused() != instances[4] {
+		t.Errorf("expected instance-4 to be focused, got %s", g.Focused().Title)
+	}
+	g.Move(1, 0) // there's no tile to the right on the last row
+	if g.Focused() != instances[4] {
+		t.Errorf("expected the focus not to move onto an empty cell, got %s", g.Focused().Title)
+	}
+	g.Focus(instances[0])
+	g.Move(-1, 0)
+	if g.Focused() != instances[0] {
+		t.Errorf("expected the focus to stay inside the grid, got %s", g.Focused().Title)
+	}
+}
@@ -16,54 +16,38 @@ const pausedIcon = "⏸ "
 const promptIcon = "⧗ "
 const cloudIcon = "☁️ "
 
-var readyStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
+// idleIcon replaces pausedIcon for instances which were paused because they were idle.
+const idleIcon = "☾ "
 
-var addedLinesStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
+// keepAliveIcon is shown after the branch of instances which are never paused for being idle.
+const keepAliveIcon = "∞"
 
-var removedLinesStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#de613e"))
-
-var pausedStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#888888", Dark: "#888888"})
-
-var promptStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#f5a623", Dark: "#f5a623"})
-
-var titleStyle = lipgloss.NewStyle().
-	Padding(1, 1, 0, 1).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
-
-var listDescStyle = lipgloss.NewStyle().
-	Padding(0, 1, 1, 1).
-	Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
-
-var selectedTitleStyle = lipgloss.NewStyle().
-	Padding(1, 1, 0, 1).
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"})
-
-var selectedDescStyle = lipgloss.NewStyle().
-	Padding(0, 1, 1, 1).
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#1a1a1a"})
-
-var mainTitle = lipgloss.NewStyle().
-	Background(lipgloss.Color("62")).
-	Foreground(lipgloss.Color("230"))
-
-var autoYesStyle = lipgloss.NewStyle().
-	Background(lipgloss.Color("#dde4f0")).
-	Foreground(lipgloss.Color("#1a1a1a"))
+// markIcon replaces the space before the number of a marked instance.
+const markIcon = "✓"
 
 type List struct {
-	items         []*session.Instance
-	selectedIdx   int
+	items []*session.Instance
+	// selected is the selected instance. It's nil when a collapsed group header is selected, in which case
+	// selectedGroup is the name of that group. Selection is tracked by identity so that it stays on the same
+	// instance when the list is re-sorted or filtered.
+	selected      *session.Instance
+	selectedGroup string
+	// selectedIdx is the row of the selection the last time it was resolved. It's used to pick a neighbouring
+	// row when the selected instance disappears from the view.
+	selectedIdx int
+	// offset is the first row rendered when the list is taller than its height.
+	offset        int
 	height, width int
 	renderer      *InstanceRenderer
 	autoyes       bool
 
+	// view is how instances are filtered, sorted and grouped.
+	view ListView
+	// filtering is true while the user is typing the filter query.
+	filtering bool
+	// marked are the instances marked for a bulk action.
+	marked map[*session.Instance]bool
+
 	// map of repo name to number of instances using it. Used to display the repo name only if there are
 	// multiple repos in play.
 	repos map[string]int
@@ -75,6 +59,8 @@ func NewList(spinner *spinner.Model, autoYes bool) *List {
 		renderer: &InstanceRenderer{spinner: spinner},
 		repos:    make(map[string]int),
 		autoyes:  autoYes,
+		view:     ListView{Collapsed: make(map[string]bool)},
+		marked:   make(map[*session.Instance]bool),
 	}
 }
 
@@ -115,14 +101,32 @@ func (r *InstanceRenderer) setWidth(width int) {
 	r.width = AdjustPreviewWidth(width)
 }
 
+// statusIcon returns the icon shown next to the title of an instance with the given status.
+func statusIcon(status session.Status, spin *spinner.Model) string {
+	switch status {
+	case session.Running:
+		return fmt.Sprintf("%s ", spin.View())
+	case session.Ready:
+		return readyStyle.Render(readyIcon)
+	case session.Paused:
+		return pausedStyle.Render(pausedIcon)
+	case session.WaitingForInput:
+		return promptStyle.Render(promptIcon)
+	}
+	return ""
+}
+
 // ɹ and ɻ are other options.
 const branchIcon = "Ꮧ"
 
-func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool) string {
+func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected, marked bool) string {
 	prefix := fmt.Sprintf(" %d. ", idx)
 	if idx >= 10 {
 		prefix = prefix[:len(prefix)-1]
 	}
+	if marked {
+		prefix = markIcon + prefix[1:]
+	}
 	titleS := selectedTitleStyle
 	descS := selectedDescStyle
 	if !selected {
@@ -131,17 +135,9 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	}
 
 	// add spinner next to title if it's running
-	var join string
-	switch i.Status {
-	case session.Running:
-		join = fmt.Sprintf("%s ", r.spinner.View())
-	case session.Ready:
-		join = readyStyle.Render(readyIcon)
-	case session.Paused:
-		join = pausedStyle.Render(pausedIcon)
-	case session.WaitingForInput:
-		join = promptStyle.Render(promptIcon)
-	default:
+	join := statusIcon(i.Status, r.spinner)
+	if i.Status == session.Paused && i.AutoPaused {
+		join = pausedStyle.Render(idleIcon)
 	}
 
 	// Cut the title if it's too long
@@ -203,13 +199,16 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 		if i.CloudStatus != "" && i.CloudStatus != "Running" {
 			branch = fmt.Sprintf("[%s - %s]", i.CloudTier, i.CloudStatus)
 		}
-	} else if i.Started() && hasMultipleRepos {
-		repoName, err := i.RepoName()
-		if err != nil {
-			log.ErrorLog.Printf("could not get repo name in instance renderer: %v", err)
-		} else {
-			branch += fmt.Sprintf(" (%s)", repoName)
-		}
+	}
+	if size := i.DiskUsage(); size > 0 {
+		branch += " " + session.FormatSize(size)
+	}
+	if i.KeepAlive {
+		branch += " " + keepAliveIcon
+	}
+	// The repo name isn't needed here since the list is grouped by repo when there are several.
+	for _, tag := range i.Tags {
+		branch += " #" + tag
 	}
 	// Don't show branch if there's no space for it. Or show ellipsis if it's too long.
 	if remainingWidth < 0 {
@@ -267,45 +266,151 @@ func (l *List) String() string {
 	}
 
 	b.WriteString("\n")
+	if status := l.viewStatus(); status != "" {
+		b.WriteString(listDescStyle.Render(status))
+	}
 	b.WriteString("\n")
 
-	// Render the list.
-	for i, item := range l.items {
-		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1))
-		if i != len(l.items)-1 {
-			b.WriteString("\n\n")
+	// Render the rows, then only keep the ones that fit around the selection.
+	rows := l.rows()
+	selectedIdx := l.resolveSelection(rows)
+	rendered := make([]string, len(rows))
+	instanceNum := 0
+	for i, row := range rows {
+		if row.instance == nil {
+			rendered[i] = l.renderGroupHeader(row, i == selectedIdx)
+			continue
 		}
+		instanceNum++
+		rendered[i] = l.renderer.Render(row.instance, instanceNum, i == selectedIdx, l.marked[row.instance])
+	}
+
+	// The title takes the first 4 lines and each row is followed by a blank line.
+	available := l.height - 4
+	if selectedIdx < l.offset {
+		l.offset = selectedIdx
+	}
+	for l.offset < selectedIdx && rowsHeight(rendered[l.offset:selectedIdx+1]) > available {
+		l.offset++
+	}
+	l.offset = max(0, min(l.offset, len(rendered)-1))
+
+	end := l.offset
+	for end < len(rendered) && rowsHeight(rendered[l.offset:end+1]) <= available {
+		end++
+	}
+	if len(rendered) > 0 {
+		b.WriteString(strings.Join(rendered[l.offset:max(end, l.offset+1)], "\n\n"))
 	}
 	return lipgloss.Place(l.width, l.height, lipgloss.Left, lipgloss.Top, b.String())
 }
 
+// rowsHeight returns the number of lines the rendered rows take, including the blank lines between them.
+func rowsHeight(rendered []string) int {
+	height := 0
+	for _, r := range rendered {
+		height += lipgloss.Height(r) + 1
+	}
+	return height
+}
+
+// viewStatus describes the active sort, filter and query, or returns "" if the default view is in use.
+func (l *List) viewStatus() string {
+	var parts []string
+	if len(l.marked) > 0 {
+		parts = append(parts, fmt.Sprintf("%d marked", len(l.marked)))
+	}
+	if l.view.Sort != SortCreated {
+		parts = append(parts, "sort: "+string(l.view.Sort))
+	}
+	if l.view.Filter != FilterAll {
+		parts = append(parts, "show: "+string(l.view.Filter))
+	}
+	if l.view.Query != "" || l.filtering {
+		query := "/" + l.view.Query
+		if l.filtering {
+			query += "█"
+		}
+		parts = append(parts, query)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (l *List) renderGroupHeader(row listRow, selected bool) string {
+	icon := "▾"
+	if l.view.Collapsed[row.group] {
+		icon = "▸"
+	}
+	style := groupHeaderStyle
+	if selected {
+		style = selectedGroupHeaderStyle
+	}
+	return style.Width(AdjustPreviewWidth(l.width) + 2).Render(fmt.Sprintf("%s %s (%d)", icon, row.group, row.count))
+}
+
+// rows returns the rows currently shown by the list.
+func (l *List) rows() []listRow {
+	return l.view.rows(l.items, len(l.repos) > 1)
+}
+
+// resolveSelection returns the index of the selected row. If the selected instance or group is no longer shown, the
+// row at the previous position is selected instead.
+func (l *List) resolveSelection(rows []listRow) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	for i, row := range rows {
+		if l.selected != nil && row.instance == l.selected {
+			l.selectedIdx = i
+			return i
+		}
+		if l.selected == nil && row.instance == nil && l.selectedGroup != "" && row.group == l.selectedGroup {
+			l.selectedIdx = i
+			return i
+		}
+	}
+	l.selectRow(rows, max(0, min(l.selectedIdx, len(rows)-1)))
+	return l.selectedIdx
+}
+
+// selectRow selects the row at idx.
+func (l *List) selectRow(rows []listRow, idx int) {
+	l.selectedIdx = idx
+	l.selected = rows[idx].instance
+	l.selectedGroup = ""
+	if rows[idx].instance == nil {
+		l.selectedGroup = rows[idx].group
+	}
+}
+
 // Down selects the next item in the list.
 func (l *List) Down() {
-	if len(l.items) == 0 {
+	rows := l.rows()
+	if len(rows) == 0 {
 		return
 	}
-	if l.selectedIdx < len(l.items)-1 {
-		l.selectedIdx++
+	idx := l.resolveSelection(rows)
+	if idx < len(rows)-1 {
+		l.selectRow(rows, idx+1)
 	}
 }
 
-// Kill selects the next item in the list.
+// Kill kills the selected instance and removes it from the list.
 func (l *List) Kill() {
-	if len(l.items) == 0 {
+	targetInstance := l.GetSelectedInstance()
+	if targetInstance == nil {
 		return
 	}
-	targetInstance := l.items[l.selectedIdx]
 
 	// Kill the tmux session
 	if err := targetInstance.Kill(); err != nil {
 		log.ErrorLog.Printf("could not kill instance: %v", err)
 	}
+	l.Remove(targetInstance)
+}
 
-	// If you delete the last one in the list, select the previous one.
-	if l.selectedIdx == len(l.items)-1 {
-		defer l.Up()
-	}
-
+// Remove removes an instance from the list without killing it.
+func (l *List) Remove(targetInstance *session.Instance) {
 	// Unregister the reponame.
 	repoName, err := targetInstance.RepoName()
 	if err != nil {
@@ -314,22 +419,37 @@ func (l *List) Kill() {
 		l.rmRepo(repoName)
 	}
 
-	// Since there's items after this, the selectedIdx can stay the same.
-	l.items = append(l.items[:l.selectedIdx], l.items[l.selectedIdx+1:]...)
+	for i, item := range l.items {
+		if item == targetInstance {
+			l.items = append(l.items[:i], l.items[i+1:]...)
+			break
+		}
+	}
+	delete(l.marked, targetInstance)
+	// The selection falls to the row that took the removed instance's place, or the previous one if it was last.
+	if l.selected == targetInstance {
+		l.selected = nil
+	}
+	l.resolveSelection(l.rows())
 }
 
 func (l *List) Attach() (chan struct{}, error) {
-	targetInstance := l.items[l.selectedIdx]
+	targetInstance := l.GetSelectedInstance()
+	if targetInstance == nil {
+		return nil, fmt.Errorf("no instance selected")
+	}
 	return targetInstance.Attach()
 }
 
 // Up selects the prev item in the list.
 func (l *List) Up() {
-	if len(l.items) == 0 {
+	rows := l.rows()
+	if len(rows) == 0 {
 		return
 	}
-	if l.selectedIdx > 0 {
-		l.selectedIdx--
+	idx := l.resolveSelection(rows)
+	if idx > 0 {
+		l.selectRow(rows, idx-1)
 	}
 }
 
@@ -368,20 +488,151 @@ func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
 	}
 }
 
-// GetSelectedInstance returns the currently selected instance
+// GetSelectedInstance returns the currently selected instance. It returns nil if a group header is selected.
 func (l *List) GetSelectedInstance() *session.Instance {
-	if len(l.items) == 0 {
+	rows := l.rows()
+	if len(rows) == 0 {
 		return nil
 	}
-	return l.items[l.selectedIdx]
+	return rows[l.resolveSelection(rows)].instance
 }
 
-// SetSelectedInstance sets the selected index. Noop if the index is out of bounds.
+// SetSelectedInstance selects the row at idx. Noop if the index is out of bounds.
 func (l *List) SetSelectedInstance(idx int) {
-	if idx >= len(l.items) {
+	rows := l.rows()
+	if idx < 0 || idx >= len(rows) {
 		return
 	}
-	l.selectedIdx = idx
+	l.selectRow(rows, idx)
+}
+
+// SelectInstance selects the given instance, expanding its group if needed. Returns false if it is not shown in
+// the list.
+func (l *List) SelectInstance(instance *session.Instance) bool {
+	delete(l.view.Collapsed, groupName(instance))
+	rows := l.rows()
+	for i, row := range rows {
+		if row.instance == instance {
+			l.selectRow(rows, i)
+			return true
+		}
+	}
+	return false
+}
+
+// View returns how the list is filtered, sorted and grouped.
+func (l *List) View() ListView {
+	return l.view
+}
+
+// SetView sets how the list is filtered, sorted and grouped.
+func (l *List) SetView(view ListView) {
+	if view.Collapsed == nil {
+		view.Collapsed = make(map[string]bool)
+	}
+	l.view = view
+}
+
+// CycleSort switches to the next sort mode.
+func (l *List) CycleSort() {
+	l.view.Sort = nextSortMode(l.view.Sort)
+}
+
+// CycleFilter switches to the next filter mode.
+func (l *List) CycleFilter() {
+	l.view.Filter = nextFilterMode(l.view.Filter)
+}
+
+// SetFiltering shows or hides the cursor of the filter query.
+func (l *List) SetFiltering(filtering bool) {
+	l.filtering = filtering
+}
+
+// SetQuery sets the text used to filter instances by title or tag.
+func (l *List) SetQuery(query string) {
+	l.view.Query = query
+}
+
+// ToggleGroup collapses or expands the repo group of the selected row.
+func (l *List) ToggleGroup() {
+	rows := l.rows()
+	if len(rows) == 0 {
+		return
+	}
+	group := rows[l.resolveSelection(rows)].group
+	if group == "" {
+		return
+	}
+	if l.view.Collapsed[group] {
+		delete(l.view.Collapsed, group)
+		return
+	}
+	l.view.Collapsed[group] = true
+	// Move the selection to the header so it doesn't jump to another group.
+	l.selected = nil
+	l.selectedGroup = group
+}
+
+// VisibleInstances returns the instances shown by the current view, in the order they're shown. Instances in
+// collapsed groups are left out.
+func (l *List) VisibleInstances() []*session.Instance {
+	var instances []*session.Instance
+	for _, row := range l.rows() {
+		if row.instance != nil {
+			instances = append(instances, row.instance)
+		}
+	}
+	return instances
+}
+
+// ToggleMark marks or unmarks the selected instance for a bulk action. If a group header is selected, the instances
+// of the group which pass the filter are marked, or unmarked if they're already all marked.
+func (l *List) ToggleMark() {
+	rows := l.rows()
+	if len(rows) == 0 {
+		return
+	}
+	selected := rows[l.resolveSelection(rows)]
+	if selected.instance != nil {
+		if l.marked[selected.instance] {
+			delete(l.marked, selected.instance)
+		} else {
+			l.marked[selected.instance] = true
+		}
+		return
+	}
+
+	var group []*session.Instance
+	allMarked := true
+	for _, item := range l.items {
+		if groupName(item) == selected.group && l.view.matches(item) {
+			group = append(group, item)
+			allMarked = allMarked && l.marked[item]
+		}
+	}
+	for _, item := range group {
+		if allMarked {
+			delete(l.marked, item)
+		} else {
+			l.marked[item] = true
+		}
+	}
+}
+
+// MarkedInstances returns the marked instances in the order they were added.
+func (l *List) MarkedInstances() []*session.Instance {
+	var instances []*session.Instance
+	for _, item := range l.items {
+		if l.marked[item] {
+			instances = append(instances, item)
+		}
+	}
+	return instances
+}
+
+// ClearMarks unmarks every instance.
+func (l *List) ClearMarks() {
+	l.marked = make(map[*session.Instance]bool)
 }
 
 // GetInstances returns all instances in the list
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
+
+	"orzbob/log"
 	"orzbob/session"
 )
 
@@ -118,3 +120,41 @@ func TestMenuWithCloudOption(t *testing.T) {
 		t.Error("Expected 'C' key for cloud in menu with instance")
 	}
 }
+
+func TestListMarks(t *testing.T) {
+	// Removing an instance which was never started logs that it has no repo.
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	s := spinner.New()
+	l := NewList(&s, false)
+	items := []*session.Instance{
+		{Title: "alpha", IsCloud: true},
+		{Title: "beta", IsCloud: true},
+		{Title: "gamma", IsCloud: true},
+	}
+	for _, item := range items {
+		l.AddInstance(item)
+	}
+
+	l.ToggleMark()
+	l.Down()
+	l.Down()
+	l.ToggleMark()
+	if got := l.MarkedInstances(); len(got) != 2 || got[0] != items[0] || got[1] != items[2] {
+		t.Fatalf("expected alpha and gamma to be marked, got %v", got)
+	}
+
+	l.ToggleMark()
+	if got := l.MarkedInstances(); len(got) != 1 || got[0] != items[0] {
+		t.Errorf("expected toggling again to unmark gamma, got %v", got)
+	}
+
+	l.Remove(items[0])
+	if got := l.MarkedInstances(); len(got) != 0 {
+		t.Errorf("expected removing an instance to unmark it, got %v", got)
+	}
+	if l.NumInstances() != 2 || l.GetSelectedInstance() != items[2] {
+		t.Errorf("expected removing another instance to keep the selection, got %v", l.GetSelectedInstance())
+	}
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"orzbob/session"
+)
+
+// SortMode is the order in which the list shows instances.
+type SortMode string
+
+const (
+	// SortCreated shows instances in the order they were created.
+	SortCreated SortMode = ""
+	// SortStatus shows instances waiting for input first, then running, ready and paused ones.
+	SortStatus SortMode = "status"
+	// SortRepo sorts instances by repository name.
+	SortRepo SortMode = "repo"
+	// SortProgram sorts instances by the program they run.
+	SortProgram SortMode = "program"
+	// SortAge shows the newest instances first.
+	SortAge SortMode = "age"
+	// SortDiff shows the instances with the largest diffs first.
+	SortDiff SortMode = "diff"
+	// SortLocation shows local instances before cloud instances.
+	SortLocation SortMode = "location"
+)
+
+var sortModes = []SortMode{SortCreated, SortStatus, SortRepo, SortProgram, SortAge, SortDiff, SortLocation}
+
+// FilterMode restricts which instances the list shows.
+type FilterMode string
+
+const (
+	// FilterAll shows every instance.
+	FilterAll FilterMode = ""
+	// FilterWaiting shows instances waiting for input.
+	FilterWaiting FilterMode = "waiting"
+	// FilterRunning shows instances which are running or ready.
+	FilterRunning FilterMode = "running"
+	// FilterPaused shows paused instances.
+	FilterPaused FilterMode = "paused"
+	// FilterLocal shows local instances.
+	FilterLocal FilterMode = "local"
+	// FilterCloud shows cloud instances.
+	FilterCloud FilterMode = "cloud"
+)
+
+var filterModes = []FilterMode{FilterAll, FilterWaiting, FilterRunning, FilterPaused, FilterLocal, FilterCloud}
+
+// ListView holds how the list filters, sorts and groups instances.
+type ListView struct {
+	Sort   SortMode
+	Filter FilterMode
+	// Query filters instances by title or tag.
+	Query string
+	// Collapsed is the set of repo groups which are collapsed.
+	Collapsed map[string]bool
+}
+
+// nextSortMode returns the sort mode after m, wrapping around.
+func nextSortMode(m SortMode) SortMode {
+	for i, mode := range sortModes {
+		if mode == m {
+			return sortModes[(i+1)%len(sortModes)]
+		}
+	}
+	return SortCreated
+}
+
+// nextFilterMode returns the filter mode after m, wrapping around.
+func nextFilterMode(m FilterMode) FilterMode {
+	for i, mode := range filterModes {
+		if mode == m {
+			return filterModes[(i+1)%len(filterModes)]
+		}
+	}
+	return FilterAll
+}
+
+// statusRank orders statuses for SortStatus. Instances that need attention come first.
+var statusRank = map[session.Status]int{
+	session.WaitingForInput: 0,
+	session.Running:         1,
+	session.Ready:           2,
+	session.Loading:         3,
+	session.Paused:          4,
+}
+
+// listRow is a single row of the list, either a repo group header or an instance.
+type listRow struct {
+	// group is the repo group the row belongs to. It's empty if the list is not grouped.
+	group string
+	// instance is nil for group header rows.
+	instance *session.Instance
+	// count is the number of instances in the group, for header rows.
+	count int
+}
+
+// groupName returns the name of the group an instance is shown under when the list is grouped by repo.
+func groupName(instance *session.Instance) string {
+	if instance.IsCloud {
+		return "cloud"
+	}
+	if !instance.Started() {
+		return ""
+	}
+	repo, err := instance.RepoName()
+	if err != nil {
+		return ""
+	}
+	return repo
+}
+
+// matches returns true if the instance passes the view's filter mode and query.
+func (v ListView) matches(instance *session.Instance) bool {
+	// Never hide an instance that's still being created, since the user is typing its name.
+	if !instance.Started() && !instance.IsCloud {
+		return true
+	}
+
+	switch v.Filter {
+	case FilterWaiting:
+		if instance.Status != session.WaitingForInput {
+			return false
+		}
+	case FilterRunning:
+		if instance.Status != session.Running && instance.Status != session.Ready {
+			return false
+		}
+	case FilterPaused:
+		if instance.Status != session.Paused {
+			return false
+		}
+	case FilterLocal:
+		if instance.IsCloud {
+			return false
+		}
+	case FilterCloud:
+		if !instance.IsCloud {
+			return false
+		}
+	}
+
+	if v.Query == "" {
+		return true
+	}
+	query := strings.ToLower(v.Query)
+	if strings.Contains(strings.ToLower(instance.Title), query) {
+		return true
+	}
+	for _, tag := range instance.Tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// less reports whether a sorts before b in the view's sort mode.
+func (v ListView) less(a, b *session.Instance) bool {
+	switch v.Sort {
+	case SortStatus:
+		return statusRank[a.Status] < statusRank[b.Status]
+	case SortRepo:
+		return groupName(a) < groupName(b)
+	case SortProgram:
+		return a.Program < b.Program
+	case SortAge:
+		return a.CreatedAt.After(b.CreatedAt)
+	case SortDiff:
+		return diffSize(a) > diffSize(b)
+	case SortLocation:
+		return !a.IsCloud && b.IsCloud
+	}
+	return false
+}
+
+func diffSize(instance *session.Instance) int {
+	stats := instance.GetDiffStats()
+	if stats == nil {
+		return 0
+	}
+	return stats.Added + stats.Removed
+}
+
+// rows filters and sorts the instances and, if grouped is true, groups them under repo headers. Instances in
+// collapsed groups are left out, but their header is kept so the group can be expanded again.
+func (v ListView) rows(items []*session.Instance, grouped bool) []listRow {
+	visible := make([]*session.Instance, 0, len(items))
+	for _, item := range items {
+		if v.matches(item) {
+			visible = append(visible, item)
+		}
+	}
+	sort.SliceStable(visible, func(i, j int) bool {
+		return v.less(visible[i], visible[j])
+	})
+
+	rows := make([]listRow, 0, len(visible))
+	if !grouped {
+		for _, item := range visible {
+			rows = append(rows, listRow{instance: item})
+		}
+		return rows
+	}
+
+	// Groups are shown in alphabetical order. Instances without a group (for example one being created) come last.
+	groups := make(map[string][]*session.Instance)
+	var names []string
+	for _, item := range visible {
+		name := groupName(item)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], item)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "" || names[j] == "" {
+			return names[j] == ""
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if name != "" {
+			rows = append(rows, listRow{group: name, count: len(groups[name])})
+			if v.Collapsed[name] {
+				continue
+			}
+		}
+		for _, item := range groups[name] {
+			rows = append(rows, listRow{group: name, instance: item})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"orzbob/session"
+)
+
+func titles(rows []listRow) []string {
+	var out []string
+	for _, row := range rows {
+		if row.instance == nil {
+			out = append(out, "["+row.group+"]")
+			continue
+		}
+		out = append(out, row.instance.Title)
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListViewRows(t *testing.T) {
+	now := time.Now()
+	items := []*session.Instance{
+		{Title: "alpha", Status: session.Paused, Program: "codex", IsCloud: true, CreatedAt: now.Add(-3 * time.Hour)},
+		{Title: "beta", Status: session.WaitingForInput, Program: "claude", IsCloud: true, CreatedAt: now.Add(-1 * time.Hour),
+			Tags: []string{"Frontend"}},
+		{Title: "gamma", Status: session.Running, Program: "aider", IsCloud: true, CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name    string
+		view    ListView
+		grouped bool
+		want    []string
+	}{
+		{
+			name: "created order",
+			view: ListView{},
+			want: []string{"alpha", "beta", "gamma"},
+		},
+		{
+			name: "status puts waiting first",
+			view: ListView{Sort: SortStatus},
+			want: []string{"beta", "gamma", "alpha"},
+		},
+		{
+			name: "program",
+			view: ListView{Sort: SortProgram},
+			want: []string{"gamma", "beta", "alpha"},
+		},
+		{
+			name: "age puts newest first",
+			view: ListView{Sort: SortAge},
+			want: []string{"beta", "gamma", "alpha"},
+		},
+		{
+			name: "waiting filter",
+			view: ListView{Filter: FilterWaiting},
+			want: []string{"beta"},
+		},
+		{
+			name: "local filter hides cloud instances",
+			view: ListView{Filter: FilterLocal},
+			want: nil,
+		},
+		{
+			name: "query matches title",
+			view: ListView{Query: "AMM"},
+			want: []string{"gamma"},
+		},
+		{
+			name: "query matches tag",
+			view: ListView{Query: "front"},
+			want: []string{"beta"},
+		},
+		{
+			name:    "grouped",
+			view:    ListView{Sort: SortStatus},
+			grouped: true,
+			want:    []string{"[cloud]", "beta", "gamma", "alpha"},
+		},
+		{
+			name:    "collapsed group keeps its header",
+			view:    ListView{Collapsed: map[string]bool{"cloud": true}},
+			grouped: true,
+			want:    []string{"[cloud]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := titles(tt.view.rows(items, tt.grouped))
+			if !equalStrings(got, tt.want) {
+				t.Errorf("rows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextSortModeWraps(t *testing.T) {
+	mode := SortCreated
+	for range sortModes {
+		mode = nextSortMode(mode)
+	}
+	if mode != SortCreated {
+		t.Errorf("expected to wrap around to SortCreated, got %q", mode)
+	}
+}
@@ -0,0 +1,131 @@
+package overlay
+
+import (
+	"orzbob/ui/theme"
+
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FormOverlay is an overlay with a few labelled single line inputs, such as the title and branch of a new instance.
+type FormOverlay struct {
+	Title   string
+	labels  []string
+	inputs  []textinput.Model
+	focused int
+	err     error
+	// Submitted is true if the form was submitted with enter.
+	Submitted bool
+	// Canceled is true if the form was closed with escape.
+	Canceled bool
+
+	width int
+}
+
+// NewFormOverlay creates a form with one input per label, filled with the given values.
+func NewFormOverlay(title string, labels []string, values []string) *FormOverlay {
+	f := &FormOverlay{Title: title, labels: labels}
+	for i := range labels {
+		ti := textinput.New()
+		ti.Prompt = ""
+		if i < len(values) {
+			ti.SetValue(values[i])
+		}
+		f.inputs = append(f.inputs, ti)
+	}
+	f.focus(0)
+	return f
+}
+
+// SetSize sets the size of the overlay.
+func (f *FormOverlay) SetSize(width, height int) {
+	f.width = width
+	for i := range f.inputs {
+		f.inputs[i].Width = width - 8
+	}
+}
+
+func (f *FormOverlay) focus(i int) {
+	f.inputs[f.focused].Blur()
+	f.focused = (i + len(f.inputs)) % len(f.inputs)
+	f.inputs[f.focused].Focus()
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly. Tab and the arrow keys move between
+// fields and enter submits the form. Returns true if the overlay should be closed.
+func (f *FormOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyEsc:
+		f.Canceled = true
+		return true
+	case tea.KeyEnter:
+		f.Submitted = true
+		return true
+	case tea.KeyTab, tea.KeyDown:
+		f.focus(f.focused + 1)
+		return false
+	case tea.KeyShiftTab, tea.KeyUp:
+		f.focus(f.focused - 1)
+		return false
+	}
+	f.inputs[f.focused], _ = f.inputs[f.focused].Update(msg)
+	f.err = nil
+	return false
+}
+
+// Value returns the value of the i-th field.
+func (f *FormOverlay) Value(i int) string {
+	return f.inputs[i].Value()
+}
+
+// Reject reopens a submitted form and shows why its values can't be used.
+func (f *FormOverlay) Reject(err error) {
+	f.Submitted = false
+	f.err = err
+}
+
+// Render renders the form.
+func (f *FormOverlay) Render() string {
+	t := theme.Current()
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(f.width)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	labelStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Muted))
+	focusedLabelStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	errorStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Error))
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(f.Title))
+	b.WriteString("\n")
+	for i, label := range f.labels {
+		b.WriteString("\n")
+		if i == f.focused {
+			b.WriteString(focusedLabelStyle.Render(label))
+		} else {
+			b.WriteString(labelStyle.Render(label))
+		}
+		b.WriteString("\n")
+		b.WriteString(f.inputs[i].View())
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	if f.err != nil {
+		b.WriteString(errorStyle.Render(f.err.Error()))
+		b.WriteString("\n")
+	}
+	b.WriteString(labelStyle.Render("tab next field • enter confirm • esc cancel"))
+
+	return style.Render(b.String())
+}
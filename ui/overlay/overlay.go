@@ -1,6 +1,8 @@
 package overlay
 
 import (
+	"orzbob/ui/theme"
+
 	"bytes"
 	"regexp"
 	"strings"
@@ -102,7 +104,7 @@ func PlaceOverlay(
 	// Handle shadow if enabled
 	if shadow {
 		// Define shadow style and character
-		shadowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#333333"))
+		shadowStyle := lipgloss.NewStyle().Foreground(theme.Current().Color(theme.Shadow))
 		shadowChar := shadowStyle.Render("░")
 
 		// Create shadow string with same dimensions as foreground
@@ -261,3 +263,13 @@ func (w whitespace) render(width int) string {
 
 	return w.style.Styled(b.String())
 }
+
+// selectionStyle returns the style of the selected item in an overlay. Themes without a primary color get reversed
+// text instead.
+func selectionStyle() lipgloss.Style {
+	t := theme.Current()
+	if !t.HasColor(theme.Primary) {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return lipgloss.NewStyle().Background(t.Color(theme.Primary)).Foreground(t.Color(theme.OnPrimary))
+}
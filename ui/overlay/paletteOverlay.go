@@ -0,0 +1,266 @@
+package overlay
+
+import (
+	"orzbob/ui/theme"
+
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PaletteItem is a single entry in the command palette.
+type PaletteItem struct {
+	// Title is the text matched against the query.
+	Title string
+	// Key is the key binding shown next to the title. It may be empty.
+	Key string
+	// Kind is shown dimmed after the title, for example to tell instances apart from actions.
+	Kind string
+}
+
+// paletteMatch is an item which matches the current query.
+type paletteMatch struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// PaletteOverlay is an overlay with a query input which fuzzy matches a list of items as you type.
+type PaletteOverlay struct {
+	input    textinput.Model
+	items    []PaletteItem
+	matches  []paletteMatch
+	selected int
+	// Submitted is true if an item was chosen with enter.
+	Submitted bool
+	// Canceled is true if the overlay was closed with escape.
+	Canceled bool
+
+	title, action string
+	width, height int
+}
+
+// NewPaletteOverlay creates a new command palette showing the given items. With an empty query, every item is shown
+// in the given order.
+func NewPaletteOverlay(items []PaletteItem) *PaletteOverlay {
+	ti := textinput.New()
+	ti.Placeholder = "Type a command or instance..."
+	ti.Prompt = "> "
+	ti.Focus()
+
+	p := &PaletteOverlay{
+		input:  ti,
+		items:  items,
+		title:  "Commands",
+		action: "run",
+	}
+	p.filter()
+	return p
+}
+
+// SetLabels replaces the title, the placeholder of the query and what enter does in the help line, for using the
+// overlay to pick something other than a command.
+func (p *PaletteOverlay) SetLabels(title, placeholder, action string) {
+	p.title = title
+	p.input.Placeholder = placeholder
+	p.action = action
+}
+
+// SetSize sets the size of the overlay.
+func (p *PaletteOverlay) SetSize(width, height int) {
+	p.width = width
+	p.height = height
+	p.input.Width = width - 8
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (p *PaletteOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyEsc:
+		p.Canceled = true
+		return true
+	case tea.KeyEnter:
+		if len(p.matches) == 0 {
+			return false
+		}
+		p.Submitted = true
+		return true
+	case tea.KeyUp, tea.KeyCtrlP:
+		if p.selected > 0 {
+			p.selected--
+		}
+		return false
+	case tea.KeyDown, tea.KeyCtrlN:
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+		}
+		return false
+	}
+
+	prev := p.input.Value()
+	p.input, _ = p.input.Update(msg)
+	if p.input.Value() != prev {
+		p.filter()
+	}
+	return false
+}
+
+// Selected returns the index of the chosen item in the items the overlay was created with, or -1 if nothing
+// matches.
+func (p *PaletteOverlay) Selected() int {
+	if len(p.matches) == 0 {
+		return -1
+	}
+	return p.matches[p.selected].index
+}
+
+// filter matches the items against the query, best matches first.
+func (p *PaletteOverlay) filter() {
+	query := p.input.Value()
+	p.matches = p.matches[:0]
+	for i, item := range p.items {
+		if score, positions, ok := fuzzyMatch(query, item.Title); ok {
+			p.matches = append(p.matches, paletteMatch{index: i, score: score, positions: positions})
+		}
+	}
+	// Keep the given order for equal scores so that an empty query lists the items as given.
+	sort.SliceStable(p.matches, func(i, j int) bool {
+		return p.matches[i].score > p.matches[j].score
+	})
+	p.selected = 0
+}
+
+// fuzzyMatch reports whether every rune of query appears in text in order, ignoring case. The score rewards
+// matches at the start of words and runs of consecutive matches, so "pb" ranks "Push branch" above "Update branch".
+// It also returns the positions of the matched runes in text.
+func fuzzyMatch(query, text string) (score int, positions []int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	runes := []rune(text)
+
+	qi := 0
+	prev := -2
+	for i, r := range runes {
+		if qi == len(q) {
+			break
+		}
+		if unicode.ToLower(r) != q[qi] {
+			continue
+		}
+		points := 1
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			points += 8
+		}
+		if prev == i-1 {
+			points += 5
+		}
+		score += points
+		positions = append(positions, i)
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, nil, false
+	}
+	// Prefer shorter texts, which are closer to the query.
+	score -= len(runes) - len(q)
+	return score, positions, true
+}
+
+// Render renders the command palette.
+func (p *PaletteOverlay) Render() string {
+	t := theme.Current()
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(p.width)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	matchStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Accent)).
+		Bold(true)
+	keyStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Key))
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Muted))
+	selectedStyle := selectionStyle()
+
+	innerWidth := max(p.width-6, 10)
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(p.title))
+	b.WriteString("\n\n")
+	b.WriteString(p.input.View())
+	b.WriteString("\n\n")
+
+	// Only show the items that fit, scrolling so the selected one is visible.
+	visible := max(1, p.height-7)
+	start := 0
+	if p.selected >= visible {
+		start = p.selected - visible + 1
+	}
+
+	if len(p.matches) == 0 {
+		b.WriteString(mutedStyle.Render("No matches"))
+		b.WriteString("\n")
+	}
+	for i := start; i < len(p.matches) && i < start+visible; i++ {
+		match := p.matches[i]
+		item := p.items[match.index]
+
+		title := item.Title
+		if kind := item.Kind; kind != "" {
+			title += " (" + kind + ")"
+		}
+		avail := innerWidth - lipgloss.Width(item.Key) - 1
+		if runes := []rune(title); len(runes) > avail && avail > 3 {
+			title = string(runes[:avail-3]) + "..."
+		}
+		gap := strings.Repeat(" ", max(1, innerWidth-lipgloss.Width(title)-lipgloss.Width(item.Key)))
+
+		if i == p.selected {
+			b.WriteString(selectedStyle.Render(title + gap + item.Key))
+		} else {
+			b.WriteString(highlightRunes(title, match.positions, matchStyle))
+			b.WriteString(gap)
+			b.WriteString(keyStyle.Render(item.Key))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(mutedStyle.Render(fmt.Sprintf("%d/%d • ↑/↓ select • enter %s • esc close",
+		len(p.matches), len(p.items), p.action)))
+
+	return style.Render(b.String())
+}
+
+// highlightRunes renders the runes of s at the given positions with style.
+func highlightRunes(s string, positions []int, style lipgloss.Style) string {
+	if len(positions) == 0 {
+		return s
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+	var b strings.Builder
+	for i, r := range []rune(s) {
+		if matched[i] {
+			b.WriteString(style.Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
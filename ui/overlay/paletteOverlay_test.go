@@ -0,0 +1,55 @@
+package overlay
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query     string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"", "anything", true, nil},
+		{"pb", "Push branch", true, []int{0, 5}},
+		{"PUSH", "push branch", true, []int{0, 1, 2, 3}},
+		{"bp", "Push branch", false, nil},
+		{"xyz", "Push branch", false, nil},
+	}
+	for _, tt := range tests {
+		_, positions, ok := fuzzyMatch(tt.query, tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, want %v, %v", tt.query, tt.text, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestPaletteRanking(t *testing.T) {
+	p := NewPaletteOverlay([]PaletteItem{
+		{Title: "Update branch"},
+		{Title: "Push branch", Key: "p"},
+		{Title: "Pause", Key: "c"},
+	})
+	if p.Selected() != 0 {
+		t.Fatalf("an empty query should keep the given order, got %d", p.Selected())
+	}
+
+	for _, r := range "pb" {
+		p.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if len(p.matches) != 2 || p.Selected() != 1 {
+		t.Errorf("expected word starts to rank Push branch first, got %+v", p.matches)
+	}
+
+	p.HandleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+	if p.Selected() != 0 {
+		t.Errorf("expected down to select the next match, got %d", p.Selected())
+	}
+	if !p.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}) || !p.Submitted {
+		t.Error("expected enter to submit")
+	}
+}
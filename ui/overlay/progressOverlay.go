@@ -0,0 +1,173 @@
+package overlay
+
+import (
+	"orzbob/ui/theme"
+
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type progressState int
+
+const (
+	progressPending progressState = iota
+	progressRunning
+	progressDone
+	progressFailed
+	progressCanceled
+)
+
+// ProgressOverlay shows the progress of an action run on several items, one line per item with its result.
+type ProgressOverlay struct {
+	Title  string
+	names  []string
+	states []progressState
+	errs   []error
+
+	width int
+}
+
+// NewProgressOverlay creates a progress overlay for items with the given names, all pending.
+func NewProgressOverlay(title string, names []string) *ProgressOverlay {
+	return &ProgressOverlay{
+		Title:  title,
+		names:  names,
+		states: make([]progressState, len(names)),
+		errs:   make([]error, len(names)),
+	}
+}
+
+// SetSize sets the size of the overlay.
+func (p *ProgressOverlay) SetSize(width, height int) {
+	p.width = width
+}
+
+// Start marks the i-th item as running.
+func (p *ProgressOverlay) Start(i int) {
+	p.states[i] = progressRunning
+}
+
+// Finish marks the i-th item as done, or failed if err isn't nil.
+func (p *ProgressOverlay) Finish(i int, err error) {
+	p.states[i] = progressDone
+	if err != nil {
+		p.states[i] = progressFailed
+		p.errs[i] = err
+	}
+}
+
+// Cancel marks every pending item as canceled.
+func (p *ProgressOverlay) Cancel() {
+	for i, state := range p.states {
+		if state == progressPending {
+			p.states[i] = progressCanceled
+		}
+	}
+}
+
+// Done returns true once no item is pending or running.
+func (p *ProgressOverlay) Done() bool {
+	for _, state := range p.states {
+		if state == progressPending || state == progressRunning {
+			return false
+		}
+	}
+	return true
+}
+
+// Next returns the index of the first pending item, or -1 if there are none left.
+func (p *ProgressOverlay) Next() int {
+	for i, state := range p.states {
+		if state == progressPending {
+			return i
+		}
+	}
+	return -1
+}
+
+// HandleKeyPress processes a key press. While items are pending, escape cancels them. Once every item has
+// finished, enter or escape closes the overlay. Returns true if the overlay should be closed.
+func (p *ProgressOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	if msg.Type != tea.KeyEnter && msg.Type != tea.KeyEsc {
+		return false
+	}
+	if !p.Done() {
+		if msg.Type == tea.KeyEsc {
+			p.Cancel()
+		}
+		return false
+	}
+	return true
+}
+
+// Render renders the progress overlay.
+func (p *ProgressOverlay) Render() string {
+	t := theme.Current()
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(p.width)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	mutedStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Muted))
+	doneStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Success))
+	runningStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Warning))
+	errorStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Error))
+
+	innerWidth := max(p.width-6, 10)
+	truncate := func(s string) string {
+		if runes := []rune(s); len(runes) > innerWidth {
+			return string(runes[:innerWidth-3]) + "..."
+		}
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(p.Title))
+	b.WriteString("\n\n")
+
+	var done, failed, canceled int
+	for i, name := range p.names {
+		switch p.states[i] {
+		case progressPending:
+			b.WriteString(mutedStyle.Render(truncate("· " + name)))
+		case progressRunning:
+			b.WriteString(runningStyle.Render(truncate("… " + name)))
+		case progressDone:
+			done++
+			b.WriteString(doneStyle.Render(truncate("✓ " + name)))
+		case progressFailed:
+			failed++
+			// Errors from git and tmux can span lines, so keep only the first.
+			msg, _, _ := strings.Cut(p.errs[i].Error(), "\n")
+			b.WriteString(errorStyle.Render(truncate(fmt.Sprintf("✗ %s: %s", name, msg))))
+		case progressCanceled:
+			canceled++
+			b.WriteString(mutedStyle.Render(truncate("- " + name + " (canceled)")))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	summary := fmt.Sprintf("%d/%d done", done, len(p.names))
+	if failed > 0 {
+		summary += fmt.Sprintf(" • %d failed", failed)
+	}
+	if canceled > 0 {
+		summary += fmt.Sprintf(" • %d canceled", canceled)
+	}
+	if p.Done() {
+		summary += " • enter close"
+	} else {
+		summary += " • esc cancel the rest"
+	}
+	b.WriteString(mutedStyle.Render(summary))
+
+	return style.Render(b.String())
+}
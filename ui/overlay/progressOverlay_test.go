@@ -0,0 +1,51 @@
+package overlay
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestProgressOverlay(t *testing.T) {
+	p := NewProgressOverlay("Pausing 3 instances", []string{"one", "two", "three"})
+	p.SetSize(60, 20)
+
+	i := p.Next()
+	if i != 0 {
+		t.Fatalf("Next() = %d, want 0", i)
+	}
+	p.Start(i)
+	p.Finish(i, errors.New("worktree is dirty\nmore details"))
+
+	p.Start(p.Next())
+	// Escape while an item is running cancels the pending ones but keeps the overlay open.
+	if p.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEsc}) {
+		t.Fatal("the overlay closed before every item finished")
+	}
+	if p.Next() != -1 {
+		t.Fatalf("Next() = %d after canceling, want -1", p.Next())
+	}
+	if p.Done() {
+		t.Fatal("Done() = true while an item is running")
+	}
+	p.Finish(1, nil)
+	if !p.Done() {
+		t.Fatal("Done() = false after the running item finished")
+	}
+
+	out := p.Render()
+	for _, want := range []string{"✗ one: worktree is dirty", "✓ two", "three (canceled)", "1/3 done • 1 failed • 1 canceled"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render() doesn't contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "more details") {
+		t.Errorf("Render() shows more than the first line of an error:\n%s", out)
+	}
+
+	if !p.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}) {
+		t.Error("enter didn't close the finished overlay")
+	}
+}
@@ -0,0 +1,185 @@
+package overlay
+
+import (
+	"orzbob/ui/theme"
+
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SearchResult is a single result shown in the search overlay.
+type SearchResult struct {
+	// Title is the name of the instance the result belongs to.
+	Title string
+	// Line is the zero-based line number of the match.
+	Line int
+	// Text is the matching line.
+	Text string
+	// Before and After hold the lines surrounding the match.
+	Before []string
+	After  []string
+}
+
+// SearchFunc runs a search for the query and returns the results to display.
+type SearchFunc func(query string) ([]SearchResult, error)
+
+// SearchOverlay is an overlay with a query input and a list of results which updates as you type.
+type SearchOverlay struct {
+	input    textinput.Model
+	search   SearchFunc
+	results  []SearchResult
+	err      error
+	selected int
+	// Submitted is true if a result was chosen with enter.
+	Submitted bool
+	// Canceled is true if the overlay was closed with escape.
+	Canceled bool
+
+	width, height int
+}
+
+// NewSearchOverlay creates a new search overlay which calls search whenever the query changes.
+func NewSearchOverlay(search SearchFunc) *SearchOverlay {
+	ti := textinput.New()
+	ti.Placeholder = "Search all instances..."
+	ti.Prompt = "/ "
+	ti.Focus()
+
+	return &SearchOverlay{
+		input:  ti,
+		search: search,
+	}
+}
+
+// SetSize sets the size of the overlay.
+func (s *SearchOverlay) SetSize(width, height int) {
+	s.width = width
+	s.height = height
+	s.input.Width = width - 8
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (s *SearchOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyEsc:
+		s.Canceled = true
+		return true
+	case tea.KeyEnter:
+		if len(s.results) == 0 {
+			return false
+		}
+		s.Submitted = true
+		return true
+	case tea.KeyUp, tea.KeyCtrlP:
+		if s.selected > 0 {
+			s.selected--
+		}
+		return false
+	case tea.KeyDown, tea.KeyCtrlN:
+		if s.selected < len(s.results)-1 {
+			s.selected++
+		}
+		return false
+	}
+
+	prev := s.input.Value()
+	s.input, _ = s.input.Update(msg)
+	if query := s.input.Value(); query != prev {
+		s.results, s.err = s.search(query)
+		s.selected = 0
+	}
+	return false
+}
+
+// Selected returns the index of the selected result, or -1 if there are no results.
+func (s *SearchOverlay) Selected() int {
+	if len(s.results) == 0 {
+		return -1
+	}
+	return s.selected
+}
+
+// Render renders the search overlay.
+func (s *SearchOverlay) Render() string {
+	t := theme.Current()
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(s.width)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	instanceStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Primary)).
+		Bold(true)
+	contextStyle := lipgloss.NewStyle().
+		Foreground(t.Color(theme.Muted))
+	selectedStyle := selectionStyle()
+	errorStyle := lipgloss.NewStyle().Foreground(t.Color(theme.Error))
+
+	innerWidth := max(s.width-6, 10)
+	truncate := func(line string) string {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if len(line) > innerWidth {
+			return line[:innerWidth-3] + "..."
+		}
+		return line
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Search"))
+	b.WriteString("\n\n")
+	b.WriteString(s.input.View())
+	b.WriteString("\n\n")
+
+	// Each result takes the header line plus its context lines. Only show the results that fit, scrolling so the
+	// selected one is visible.
+	perResult := 1
+	if len(s.results) > 0 {
+		perResult += len(s.results[0].Before) + len(s.results[0].After)
+	}
+	visible := max(1, (s.height-6)/perResult)
+	start := 0
+	if s.selected >= visible {
+		start = s.selected - visible + 1
+	}
+
+	switch {
+	case s.err != nil:
+		b.WriteString(errorStyle.Render(s.err.Error()))
+	case s.input.Value() != "" && len(s.results) == 0:
+		b.WriteString(contextStyle.Render("No matches"))
+	default:
+		for i := start; i < len(s.results) && i < start+visible; i++ {
+			r := s.results[i]
+			for _, line := range r.Before {
+				b.WriteString(contextStyle.Render("  " + truncate(line)))
+				b.WriteString("\n")
+			}
+			header := truncate(fmt.Sprintf("%s:%d: %s", r.Title, r.Line+1, r.Text))
+			if i == s.selected {
+				b.WriteString(selectedStyle.Render(header))
+			} else {
+				b.WriteString(instanceStyle.Render(r.Title) + truncate(strings.TrimPrefix(header, r.Title)))
+			}
+			b.WriteString("\n")
+			for _, line := range r.After {
+				b.WriteString(contextStyle.Render("  " + truncate(line)))
+				b.WriteString("\n")
+			}
+		}
+		if len(s.results) > 0 {
+			b.WriteString(contextStyle.Render(fmt.Sprintf("%d/%d matches • ↑/↓ select • enter jump • esc close",
+				s.selected+1, len(s.results))))
+		}
+	}
+
+	return style.Render(b.String())
+}
@@ -1,6 +1,9 @@
 package overlay
 
 import (
+	"orzbob/ui/theme"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -15,6 +18,26 @@ type TextInputOverlay struct {
 	Canceled      bool
 	OnSubmit      func()
 	width, height int
+
+	// TemplateRequested is true if the overlay was closed with ctrl+t to pick a prompt template, see AllowTemplates.
+	TemplateRequested bool
+	templates         bool
+
+	// EditorRequested is true if the overlay was closed with ctrl+o to write the prompt in $EDITOR, see AllowEditor.
+	EditorRequested bool
+	editor          bool
+
+	// history holds earlier prompts from the oldest to the newest, see SetHistory. historyIndex is the one shown
+	// while browsing them with up and down, or len(history) if none is, and draft is what was typed before.
+	history      []string
+	historyIndex int
+	draft        string
+	// searching is true while the history is searched for query with ctrl+r. match is the index of the prompt
+	// found, or -1, and beforeSearch is what to go back to if the search is canceled.
+	searching    bool
+	query        string
+	match        int
+	beforeSearch string
 }
 
 // NewTextInputOverlay creates a new text input overlay with the given title and initial value.
@@ -59,6 +82,12 @@ func (t *TextInputOverlay) View() string {
 // HandleKeyPress processes a key press and updates the state accordingly.
 // Returns true if the overlay should be closed.
 func (t *TextInputOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	if t.searching && t.handleSearchKey(msg) {
+		return false
+	}
+	if t.FocusIndex == 0 && t.browseHistory(msg) {
+		return false
+	}
 	switch msg.Type {
 	case tea.KeyTab:
 		// Toggle focus between input and enter button.
@@ -81,6 +110,26 @@ func (t *TextInputOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
 	case tea.KeyEsc:
 		t.Canceled = true
 		return true
+	case tea.KeyCtrlT:
+		if !t.templates {
+			return false
+		}
+		t.TemplateRequested = true
+		return true
+	case tea.KeyCtrlO:
+		if !t.editor {
+			return false
+		}
+		t.EditorRequested = true
+		return true
+	case tea.KeyCtrlR:
+		if t.FocusIndex == 0 && len(t.history) > 0 {
+			t.searching = true
+			t.query = ""
+			t.match = -1
+			t.beforeSearch = t.textarea.Value()
+		}
+		return false
 	case tea.KeyEnter:
 		if t.FocusIndex == 1 {
 			// Enter button is focused, so submit.
@@ -99,6 +148,125 @@ func (t *TextInputOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
 	}
 }
 
+// AllowTemplates makes ctrl+t close the overlay with TemplateRequested set, so a prompt template can be picked and
+// inserted with InsertTemplate.
+func (t *TextInputOverlay) AllowTemplates() {
+	t.templates = true
+}
+
+// InsertTemplate reopens the overlay after a template was requested and inserts text, the filled in template, at the
+// cursor. text may be empty if no template was picked.
+func (t *TextInputOverlay) InsertTemplate(text string) {
+	t.TemplateRequested = false
+	t.FocusIndex = 0
+	t.textarea.Focus()
+	t.textarea.InsertString(text)
+}
+
+// AllowEditor makes ctrl+o close the overlay with EditorRequested set, so the prompt can be written in an editor and
+// put back with SetEditorText.
+func (t *TextInputOverlay) AllowEditor() {
+	t.editor = true
+}
+
+// SetEditorText reopens the overlay after the editor was requested and replaces the prompt with text, what was
+// written in it.
+func (t *TextInputOverlay) SetEditorText(text string) {
+	t.EditorRequested = false
+	t.FocusIndex = 0
+	t.textarea.Focus()
+	t.textarea.SetValue(text)
+	t.historyIndex = len(t.history)
+}
+
+// SetHistory sets the earlier prompts, from the oldest to the newest. Up on the first line shows the previous one
+// and down on the last line the next one, and ctrl+r searches them.
+func (t *TextInputOverlay) SetHistory(history []string) {
+	t.history = history
+	t.historyIndex = len(history)
+}
+
+// browseHistory shows the previous or next prompt of the history for up and down, unless the cursor can still move
+// up or down in the text. It returns true if the key was handled.
+func (t *TextInputOverlay) browseHistory(msg tea.KeyMsg) bool {
+	switch {
+	case msg.Type == tea.KeyUp && t.historyIndex > 0 && t.textarea.Line() == 0:
+		if t.historyIndex == len(t.history) {
+			t.draft = t.textarea.Value()
+		}
+		t.historyIndex--
+		t.textarea.SetValue(t.history[t.historyIndex])
+		return true
+	case msg.Type == tea.KeyDown && t.historyIndex < len(t.history) && t.textarea.Line() == t.textarea.LineCount()-1:
+		t.historyIndex++
+		if t.historyIndex == len(t.history) {
+			t.textarea.SetValue(t.draft)
+		} else {
+			t.textarea.SetValue(t.history[t.historyIndex])
+		}
+		return true
+	}
+	return false
+}
+
+// handleSearchKey handles a key while the history is searched. Typing refines the search, ctrl+r finds an older
+// match, enter keeps the match and esc goes back to the prompt from before. Any other key keeps the match and is
+// handled as usual, in which case it returns false.
+func (t *TextInputOverlay) handleSearchKey(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		t.query += string(msg.Runes)
+		t.search(len(t.history))
+	case tea.KeyBackspace:
+		if t.query != "" {
+			runes := []rune(t.query)
+			t.query = string(runes[:len(runes)-1])
+			t.search(len(t.history))
+		}
+	case tea.KeyCtrlR:
+		if t.match > 0 {
+			t.search(t.match)
+		}
+	case tea.KeyEsc, tea.KeyCtrlG:
+		t.searching = false
+		t.textarea.SetValue(t.beforeSearch)
+	case tea.KeyEnter:
+		t.stopSearch()
+	default:
+		t.stopSearch()
+		return false
+	}
+	return true
+}
+
+// search shows the newest prompt of the history before index before which contains the query, ignoring case. The
+// prompt shown stays the same if there's none.
+func (t *TextInputOverlay) search(before int) {
+	query := strings.ToLower(t.query)
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(t.history[i]), query) {
+			t.match = i
+			t.textarea.SetValue(t.history[i])
+			return
+		}
+	}
+	if before == len(t.history) {
+		t.match = -1
+		t.textarea.SetValue(t.beforeSearch)
+	}
+}
+
+// stopSearch ends the search keeping the prompt found, and browsing the history continues from it.
+func (t *TextInputOverlay) stopSearch() {
+	t.searching = false
+	if t.match >= 0 {
+		if t.historyIndex == len(t.history) {
+			t.draft = t.beforeSearch
+		}
+		t.historyIndex = t.match
+	}
+}
+
 // GetValue returns the current value of the text input.
 func (t *TextInputOverlay) GetValue() string {
 	return t.textarea.Value()
@@ -122,23 +290,21 @@ func (t *TextInputOverlay) SetOnSubmit(onSubmit func()) {
 // Render renders the text input overlay.
 func (t *TextInputOverlay) Render() string {
 	// Create styles
+	th := theme.Current()
 	style := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
+		BorderForeground(th.Color(theme.Primary)).
 		Padding(1, 2)
 
 	titleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("62")).
+		Foreground(th.Color(theme.Primary)).
 		Bold(true).
 		MarginBottom(1)
 
 	buttonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("7"))
+		Foreground(th.Color(theme.Text))
 
-	focusedButtonStyle := buttonStyle
-	focusedButtonStyle = focusedButtonStyle.
-		Background(lipgloss.Color("62")).
-		Foreground(lipgloss.Color("0"))
+	focusedButtonStyle := selectionStyle()
 
 	// Set textarea width to fit within the overlay
 	t.textarea.SetWidth(t.width - 6) // Account for padding and borders
@@ -155,6 +321,28 @@ func (t *TextInputOverlay) Render() string {
 		enterButton = buttonStyle.Render(enterButton)
 	}
 	content += enterButton
+	var hints []string
+	if t.searching {
+		hint := "search history: " + t.query
+		if t.match < 0 && t.query != "" {
+			hint += " (no match)"
+		}
+		hints = append(hints, hint, "ctrl+r older", "enter keep", "esc cancel")
+	} else {
+		if t.templates {
+			hints = append(hints, "ctrl+t template")
+		}
+		if t.editor {
+			hints = append(hints, "ctrl+o editor")
+		}
+		if len(t.history) > 0 {
+			hints = append(hints, "↑/ctrl+r history")
+		}
+	}
+	if len(hints) > 0 {
+		hintStyle := lipgloss.NewStyle().Foreground(th.Color(theme.Muted))
+		content += "  " + hintStyle.Render(strings.Join(hints, " • "))
+	}
 
 	return style.Render(content)
 }
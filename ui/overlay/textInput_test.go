@@ -0,0 +1,90 @@
+package overlay
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeText(t *TextInputOverlay, text string) {
+	for _, r := range text {
+		t.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestTextInputHistory(t *testing.T) {
+	ti := NewTextInputOverlay("Enter prompt", "")
+	ti.SetHistory([]string{"fix the login", "add billing tests", "two\nlines"})
+	typeText(ti, "draft")
+
+	steps := []struct {
+		key  tea.KeyType
+		want string
+	}{
+		{tea.KeyUp, "two\nlines"},
+		// The cursor is on the last line of the prompt, so up moves it before showing an older one.
+		{tea.KeyUp, "two\nlines"},
+		{tea.KeyUp, "add billing tests"},
+		{tea.KeyUp, "fix the login"},
+		{tea.KeyUp, "fix the login"},
+		{tea.KeyDown, "add billing tests"},
+		{tea.KeyDown, "two\nlines"},
+		{tea.KeyDown, "draft"},
+		{tea.KeyDown, "draft"},
+	}
+	for i, step := range steps {
+		if ti.HandleKeyPress(tea.KeyMsg{Type: step.key}) {
+			t.Fatalf("step %d closed the overlay", i)
+		}
+		if got := ti.GetValue(); got != step.want {
+			t.Fatalf("step %d: value = %q, want %q", i, got, step.want)
+		}
+	}
+}
+
+func TestTextInputSearch(t *testing.T) {
+	ti := NewTextInputOverlay("Enter prompt", "")
+	ti.SetHistory([]string{"Fix the login", "add billing tests", "fix the logout"})
+	typeText(ti, "draft")
+
+	ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlR})
+	typeText(ti, "FIX")
+	if got := ti.GetValue(); got != "fix the logout" {
+		t.Fatalf("search found %q, want the newest match", got)
+	}
+	ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if got := ti.GetValue(); got != "Fix the login" {
+		t.Fatalf("ctrl+r found %q, want the next older match", got)
+	}
+	if ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}) || ti.Submitted {
+		t.Fatal("enter in a search submitted the prompt")
+	}
+	ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+	if got := ti.GetValue(); got != "add billing tests" {
+		t.Errorf("down after a search shows %q, want the prompt after the match", got)
+	}
+
+	ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlR})
+	typeText(ti, "nothing")
+	if ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEsc}) {
+		t.Fatal("esc in a search closed the overlay")
+	}
+	if got := ti.GetValue(); got != "add billing tests" {
+		t.Errorf("canceling the search left %q, want the prompt from before", got)
+	}
+}
+
+func TestTextInputEditor(t *testing.T) {
+	ti := NewTextInputOverlay("Enter prompt", "")
+	if ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlO}) {
+		t.Fatal("ctrl+o closed the overlay without AllowEditor")
+	}
+	ti.AllowEditor()
+	if !ti.HandleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlO}) || !ti.EditorRequested {
+		t.Fatal("ctrl+o didn't request the editor")
+	}
+	ti.SetEditorText("written\nin the editor")
+	if ti.EditorRequested || ti.GetValue() != "written\nin the editor" {
+		t.Errorf("SetEditorText() left %q, requested %v", ti.GetValue(), ti.EditorRequested)
+	}
+}
@@ -8,14 +8,32 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var previewPaneStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
-
 type PreviewPane struct {
 	width  int
 	height int
 
 	previewState previewState
+
+	// history is set when the pane is pinned to a position in the instance's scrollback, for example after
+	// jumping to a search result. While it's set, UpdateContent does not replace the content.
+	history *previewHistory
+}
+
+type previewHistory struct {
+	// lines is the captured scrollback with escape sequences removed.
+	lines []string
+	// offset is the first line shown.
+	offset int
+	// highlight is the line to highlight, or -1 for none.
+	highlight int
+	// follow is true if the history is re-captured on every update and kept scrolled to the bottom.
+	follow bool
+
+	// searching is true while the search query is being typed.
+	searching bool
+	query     string
+	// matches are the lines matching the query, in order.
+	matches []int
 }
 
 type previewState struct {
@@ -42,27 +60,247 @@ func (p *PreviewPane) setFallbackState(message string) {
 	}
 }
 
+// ScrollToLine pins the preview to the given scrollback lines, centered on and highlighting line.
+func (p *PreviewPane) ScrollToLine(lines []string, line int) {
+	p.history = &previewHistory{lines: lines, highlight: line}
+	p.history.offset = p.clampOffset(line - p.historyHeight()/2)
+}
+
+// EnterScrollMode pins the preview to the given scrollback lines, scrolled to the bottom.
+func (p *PreviewPane) EnterScrollMode(lines []string) {
+	p.history = &previewHistory{lines: lines, highlight: -1}
+	p.ScrollToBottom()
+}
+
+// ResetScroll unpins the preview so it follows the live pane content again.
+func (p *PreviewPane) ResetScroll() {
+	p.history = nil
+}
+
+// IsScrolled returns true if the preview is pinned to a position in the scrollback.
+func (p *PreviewPane) IsScrolled() bool {
+	return p.history != nil
+}
+
+// historyHeight is the number of scrollback lines shown. One line is used by the status bar.
+func (p *PreviewPane) historyHeight() int {
+	return max(1, p.height-1)
+}
+
+func (p *PreviewPane) clampOffset(offset int) int {
+	return max(0, min(offset, len(p.history.lines)-p.historyHeight()))
+}
+
+// ScrollBy scrolls the scrollback by n lines. Scrolling up stops following the output.
+func (p *PreviewPane) ScrollBy(n int) {
+	if p.history == nil {
+		return
+	}
+	if n < 0 {
+		p.history.follow = false
+	}
+	p.history.offset = p.clampOffset(p.history.offset + n)
+}
+
+// PageBy scrolls the scrollback by n screens.
+func (p *PreviewPane) PageBy(n int) {
+	p.ScrollBy(n * p.historyHeight())
+}
+
+// ScrollToTop scrolls to the oldest line of the scrollback.
+func (p *PreviewPane) ScrollToTop() {
+	p.ScrollBy(-len(p.history.lines))
+}
+
+// ScrollToBottom scrolls to the newest line of the scrollback.
+func (p *PreviewPane) ScrollToBottom() {
+	if p.history == nil {
+		return
+	}
+	p.history.offset = p.clampOffset(len(p.history.lines))
+}
+
+// ToggleFollow switches following the output on or off. Turning it on scrolls to the bottom.
+func (p *PreviewPane) ToggleFollow() {
+	if p.history == nil {
+		return
+	}
+	p.history.follow = !p.history.follow
+	if p.history.follow {
+		p.ScrollToBottom()
+	}
+}
+
+// IsFollowing returns true if the scrollback follows the output.
+func (p *PreviewPane) IsFollowing() bool {
+	return p.history != nil && p.history.follow
+}
+
+// SetHistoryLines replaces the scrollback lines, keeping the scroll position, or the bottom when following.
+func (p *PreviewPane) SetHistoryLines(lines []string) {
+	if p.history == nil {
+		return
+	}
+	p.history.lines = lines
+	p.history.matches = findMatches(lines, p.history.query)
+	if p.history.follow {
+		p.ScrollToBottom()
+		return
+	}
+	p.history.offset = p.clampOffset(p.history.offset)
+}
+
+// StartSearch starts typing a search query in the scrollback.
+func (p *PreviewPane) StartSearch() {
+	if p.history == nil {
+		return
+	}
+	p.history.searching = true
+	p.history.query = ""
+	p.history.matches = nil
+}
+
+// IsSearching returns true while a search query is being typed.
+func (p *PreviewPane) IsSearching() bool {
+	return p.history != nil && p.history.searching
+}
+
+// SearchQuery returns the current search query.
+func (p *PreviewPane) SearchQuery() string {
+	if p.history == nil {
+		return ""
+	}
+	return p.history.query
+}
+
+// SetSearchQuery updates the search query and jumps to the newest match above the bottom of the view.
+func (p *PreviewPane) SetSearchQuery(query string) {
+	if p.history == nil {
+		return
+	}
+	p.history.query = query
+	p.history.matches = findMatches(p.history.lines, query)
+	p.history.highlight = -1
+	p.jumpToMatch(p.history.offset+p.historyHeight(), true)
+}
+
+// EndSearch stops typing the search query. The query and its matches are kept for NextMatch unless cancel is true.
+func (p *PreviewPane) EndSearch(cancel bool) {
+	if p.history == nil {
+		return
+	}
+	p.history.searching = false
+	if cancel {
+		p.history.query = ""
+		p.history.matches = nil
+		p.history.highlight = -1
+	}
+}
+
+// NextMatch jumps to the next match of the search query. Older searches up through the scrollback, towards the
+// oldest output, the same way searching the history of a shell does.
+func (p *PreviewPane) NextMatch(older bool) {
+	if p.history == nil {
+		return
+	}
+	from := p.history.highlight
+	if from < 0 {
+		from = p.history.offset + p.historyHeight()
+	}
+	if !older {
+		from++
+	}
+	p.jumpToMatch(from, older)
+}
+
+// jumpToMatch highlights the closest match before line when older is true, or at or after line otherwise.
+func (p *PreviewPane) jumpToMatch(line int, older bool) {
+	matches := p.history.matches
+	target := -1
+	if older {
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i] < line {
+				target = matches[i]
+				break
+			}
+		}
+	} else {
+		for _, m := range matches {
+			if m >= line {
+				target = m
+				break
+			}
+		}
+	}
+	if target < 0 {
+		return
+	}
+	p.history.follow = false
+	p.history.highlight = target
+	p.history.offset = p.clampOffset(target - p.historyHeight()/2)
+}
+
+// findMatches returns the lines containing query. The search ignores case unless the query has an upper case
+// letter.
+func findMatches(lines []string, query string) []int {
+	if query == "" {
+		return nil
+	}
+	ignoreCase := strings.ToLower(query) == query
+	var matches []int
+	for i, line := range lines {
+		if ignoreCase {
+			line = strings.ToLower(line)
+		}
+		if strings.Contains(line, query) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
 // Updates the preview pane content with the tmux pane content
 func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
+	if p.history != nil {
+		if !p.history.follow || instance == nil {
+			return nil
+		}
+		lines, err := instance.ScrollbackLines()
+		if err != nil {
+			return err
+		}
+		p.SetHistoryLines(lines)
+		return nil
+	}
+
 	switch {
 	case instance == nil:
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
 		return nil
-	case instance.Status == session.Paused:
+	case instance.Status == session.Paused && instance.AutoPaused:
 		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
-			"Session is paused. Press 'r' to resume.",
+			"Session was paused because it was idle. Press 'r' to resume.",
 			"",
-			lipgloss.NewStyle().
-				Foreground(lipgloss.AdaptiveColor{
-					Light: "#FFD700",
-					Dark:  "#FFD700",
-				}).
-				Render(fmt.Sprintf(
-					"The instance can be checked out at '%s' (copied to your clipboard)",
-					instance.Branch,
-				)),
+			pausedNoticeStyle.Render(fmt.Sprintf(
+				"Its work was committed to '%s'. Press 'K' to keep it running from now on",
+				instance.Branch,
+			)),
 		))
 		return nil
+	case instance.Status == session.Paused:
+		lines := []string{
+			"Session is paused. Press 'r' to resume.",
+			"",
+			pausedNoticeStyle.Render(fmt.Sprintf(
+				"The instance can be checked out at '%s' (copied to your clipboard)",
+				instance.Branch,
+			)),
+		}
+		if instance.Transcript != "" {
+			lines = append(lines, pausedNoticeStyle.Render("Press shift+↑ to read its output from before it was imported"))
+		}
+		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center, lines...))
+		return nil
 	}
 
 	content, err := instance.Preview()
@@ -121,6 +359,10 @@ func (p *PreviewPane) String() string {
 			Render(strings.Join(lines, ""))
 	}
 
+	if p.history != nil {
+		return previewPaneStyle.Width(p.width).Render(p.historyString())
+	}
+
 	// Calculate available height accounting for border and margin
 	availableHeight := p.height - 1 //  1 for ellipsis
 
@@ -142,3 +384,45 @@ func (p *PreviewPane) String() string {
 	rendered := previewPaneStyle.Width(p.width).Render(content)
 	return rendered
 }
+
+// historyString renders the window of pinned scrollback lines and a status bar below them.
+func (p *PreviewPane) historyString() string {
+	h := p.history
+	height := p.historyHeight()
+	end := min(len(h.lines), h.offset+height)
+
+	window := make([]string, 0, p.height)
+	for i := h.offset; i < end; i++ {
+		if i == h.highlight {
+			window = append(window, previewHighlightStyle.Render(h.lines[i]))
+		} else {
+			window = append(window, h.lines[i])
+		}
+	}
+	// Pad with empty lines to fill available height
+	window = append(window, make([]string, height-len(window))...)
+	window = append(window, previewStatusStyle.Render(p.historyStatus()))
+	return strings.Join(window, "\n")
+}
+
+// historyStatus describes the scroll position, follow mode and search of the scrollback.
+func (p *PreviewPane) historyStatus() string {
+	h := p.history
+	parts := []string{fmt.Sprintf("lines %d-%d/%d", min(h.offset+1, len(h.lines)),
+		min(h.offset+p.historyHeight(), len(h.lines)), len(h.lines))}
+	if h.follow {
+		parts = append(parts, "follow on")
+	} else {
+		parts = append(parts, "follow off")
+	}
+
+	switch {
+	case h.searching:
+		parts = append(parts, "/"+h.query+"█")
+	case h.query != "":
+		parts = append(parts, fmt.Sprintf("/%s (%d matches) n/N", h.query, len(h.matches)))
+	default:
+		parts = append(parts, "/ search • f follow • esc exit")
+	}
+	return strings.Join(parts, " · ")
+}
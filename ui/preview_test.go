@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+)
+
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	return lines
+}
+
+func TestPreviewScrollMode(t *testing.T) {
+	p := NewPreviewPane()
+	p.SetSize(80, 11) // 10 lines of history and the status bar
+
+	p.EnterScrollMode(numberedLines(100))
+	if p.history.offset != 90 {
+		t.Fatalf("expected to start at the bottom, offset = %d", p.history.offset)
+	}
+
+	p.ToggleFollow()
+	p.ScrollBy(-5)
+	if p.history.offset != 85 || p.IsFollowing() {
+		t.Errorf("scrolling up should move the view and stop following, offset = %d follow = %v",
+			p.history.offset, p.IsFollowing())
+	}
+
+	p.PageBy(-100)
+	if p.history.offset != 0 {
+		t.Errorf("expected paging past the top to stop at 0, got %d", p.history.offset)
+	}
+
+	p.ToggleFollow()
+	p.SetHistoryLines(numberedLines(120))
+	if p.history.offset != 110 {
+		t.Errorf("following should keep the view at the bottom, offset = %d", p.history.offset)
+	}
+}
+
+func TestPreviewScrollSearch(t *testing.T) {
+	p := NewPreviewPane()
+	p.SetSize(80, 11)
+
+	lines := numberedLines(100)
+	lines[20] = "Error: first"
+	lines[70] = "error: second"
+	p.EnterScrollMode(lines)
+
+	p.StartSearch()
+	p.SetSearchQuery("error")
+	if p.history.highlight != 70 {
+		t.Fatalf("expected the newest match to be highlighted, got %d", p.history.highlight)
+	}
+	p.EndSearch(false)
+
+	p.NextMatch(true)
+	if p.history.highlight != 20 {
+		t.Errorf("expected n to go to the older match, got %d", p.history.highlight)
+	}
+	p.NextMatch(false)
+	if p.history.highlight != 70 {
+		t.Errorf("expected N to go back to the newer match, got %d", p.history.highlight)
+	}
+
+	// An upper case letter makes the search case sensitive.
+	p.SetSearchQuery("Error")
+	if len(p.history.matches) != 1 || p.history.matches[0] != 20 {
+		t.Errorf("expected a case sensitive match on line 20, got %v", p.history.matches)
+	}
+
+	p.EndSearch(true)
+	if p.SearchQuery() != "" || p.history.highlight != -1 {
+		t.Error("cancelling the search should clear it")
+	}
+}
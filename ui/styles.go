@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"orzbob/ui/theme"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	inactiveTabBorder = tabBorderWithBottom("┴", "─", "┴")
+	activeTabBorder   = tabBorderWithBottom("┘", " ", "└")
+)
+
+// Styles used by the components in this package. They're built from the current theme by ApplyTheme.
+var (
+	// List
+	readyStyle               lipgloss.Style
+	addedLinesStyle          lipgloss.Style
+	removedLinesStyle        lipgloss.Style
+	pausedStyle              lipgloss.Style
+	promptStyle              lipgloss.Style
+	titleStyle               lipgloss.Style
+	listDescStyle            lipgloss.Style
+	selectedTitleStyle       lipgloss.Style
+	selectedDescStyle        lipgloss.Style
+	mainTitle                lipgloss.Style
+	autoYesStyle             lipgloss.Style
+	groupHeaderStyle         lipgloss.Style
+	selectedGroupHeaderStyle lipgloss.Style
+
+	// Menu
+	keyStyle         lipgloss.Style
+	descStyle        lipgloss.Style
+	sepStyle         lipgloss.Style
+	actionGroupStyle lipgloss.Style
+	menuStyle        lipgloss.Style
+
+	// Tabbed window
+	inactiveTabStyle lipgloss.Style
+	activeTabStyle   lipgloss.Style
+	windowStyle      lipgloss.Style
+
+	// Grid
+	gridTileStyle         lipgloss.Style
+	gridFocusedTileStyle  lipgloss.Style
+	gridTitleStyle        lipgloss.Style
+	gridFocusedTitleStyle lipgloss.Style
+
+	// Preview
+	previewPaneStyle      lipgloss.Style
+	previewHighlightStyle lipgloss.Style
+	previewStatusStyle    lipgloss.Style
+	pausedNoticeStyle     lipgloss.Style
+
+	// Diff
+	AdditionStyle lipgloss.Style
+	DeletionStyle lipgloss.Style
+	HunkStyle     lipgloss.Style
+
+	// Error box
+	errStyle lipgloss.Style
+)
+
+func init() {
+	ApplyTheme(theme.Current())
+}
+
+// filled returns a style with the fg color on the bg color. Themes without a bg color, such as no-color, get
+// reversed text instead so the element still stands out.
+func filled(t *theme.Theme, fg, bg theme.Token) lipgloss.Style {
+	if !t.HasColor(bg) {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return lipgloss.NewStyle().Background(t.Color(bg)).Foreground(t.Color(fg))
+}
+
+// ApplyTheme makes t the current theme and rebuilds the styles of every component from it.
+func ApplyTheme(t *theme.Theme) {
+	theme.Set(t)
+
+	readyStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Success))
+	addedLinesStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Success))
+	removedLinesStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Error))
+	pausedStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Muted))
+	promptStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Warning))
+	titleStyle = lipgloss.NewStyle().
+		Padding(1, 1, 0, 1).
+		Foreground(t.Color(theme.Text))
+	listDescStyle = lipgloss.NewStyle().
+		Padding(0, 1, 1, 1).
+		Foreground(t.Color(theme.Muted))
+	selectedTitleStyle = filled(t, theme.SelectedFg, theme.SelectedBg).
+		Padding(1, 1, 0, 1)
+	selectedDescStyle = filled(t, theme.SelectedFg, theme.SelectedBg).
+		Padding(0, 1, 1, 1)
+	mainTitle = filled(t, theme.OnPrimary, theme.Primary)
+	autoYesStyle = filled(t, theme.SelectedFg, theme.SelectedBg)
+	groupHeaderStyle = lipgloss.NewStyle().
+		Padding(0, 1).
+		Bold(true).
+		Foreground(t.Color(theme.Primary))
+	selectedGroupHeaderStyle = filled(t, theme.SelectedFg, theme.SelectedBg).
+		Padding(0, 1).
+		Bold(true)
+
+	keyStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Key))
+	descStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Muted))
+	sepStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Subtle))
+	actionGroupStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Primary))
+	menuStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Accent))
+
+	inactiveTabStyle = lipgloss.NewStyle().
+		Border(inactiveTabBorder, true).
+		BorderForeground(t.Color(theme.Primary)).
+		AlignHorizontal(lipgloss.Center)
+	activeTabStyle = inactiveTabStyle.
+		Border(activeTabBorder, true).
+		AlignHorizontal(lipgloss.Center)
+	windowStyle = lipgloss.NewStyle().
+		BorderForeground(t.Color(theme.Primary)).
+		Border(lipgloss.NormalBorder(), false, true, true, true)
+
+	gridTileStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(t.Color(theme.Subtle))
+	gridFocusedTileStyle = gridTileStyle.
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(t.Color(theme.Primary))
+	gridTitleStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Text))
+	gridFocusedTitleStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Color(theme.Primary))
+
+	previewPaneStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Text))
+	previewHighlightStyle = lipgloss.NewStyle().Reverse(true)
+	previewStatusStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Muted))
+	pausedNoticeStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Warning))
+
+	AdditionStyle = lipgloss.NewStyle().Foreground(t.Color(theme.DiffAdded))
+	DeletionStyle = lipgloss.NewStyle().Foreground(t.Color(theme.DiffRemoved))
+	HunkStyle = lipgloss.NewStyle().Foreground(t.Color(theme.DiffHunk))
+
+	errStyle = lipgloss.NewStyle().Foreground(t.Color(theme.Error))
+}
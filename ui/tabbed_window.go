@@ -14,22 +14,6 @@ func tabBorderWithBottom(left, middle, right string) lipgloss.Border {
 	return border
 }
 
-var (
-	inactiveTabBorder = tabBorderWithBottom("┴", "─", "┴")
-	activeTabBorder   = tabBorderWithBottom("┘", " ", "└")
-	highlightColor    = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	inactiveTabStyle  = lipgloss.NewStyle().
-				Border(inactiveTabBorder, true).
-				BorderForeground(highlightColor).
-				AlignHorizontal(lipgloss.Center)
-	activeTabStyle = inactiveTabStyle.
-			Border(activeTabBorder, true).
-			AlignHorizontal(lipgloss.Center)
-	windowStyle = lipgloss.NewStyle().
-			BorderForeground(highlightColor).
-			Border(lipgloss.NormalBorder(), false, true, true, true)
-)
-
 const (
 	PreviewTab = iota
 	DiffTab
@@ -78,13 +62,18 @@ func (w *TabbedWindow) SetSize(width, height int) {
 	// 2. Window style vertical frame size
 	// 3. Additional padding/spacing (2 for the newline and spacing)
 	tabHeight := activeTabStyle.GetVerticalFrameSize() + 1
-	contentHeight := height - tabHeight - windowStyle.GetVerticalFrameSize() - 2
-	contentWidth := w.width - windowStyle.GetHorizontalFrameSize()
+	contentWidth, contentHeight := paneContentSize(windowStyle, w.width, height, tabHeight+2)
 
 	w.preview.SetSize(contentWidth, contentHeight)
 	w.diff.SetSize(contentWidth, contentHeight)
 }
 
+// paneContentSize returns the size left for the content of a pane drawn with style, below a header which is
+// headerHeight rows tall.
+func paneContentSize(style lipgloss.Style, width, height, headerHeight int) (int, int) {
+	return width - style.GetHorizontalFrameSize(), height - headerHeight - style.GetVerticalFrameSize()
+}
+
 func (w *TabbedWindow) GetPreviewSize() (width, height int) {
 	return w.preview.width, w.preview.height
 }
@@ -108,6 +97,27 @@ func (w *TabbedWindow) UpdateDiff(instance *session.Instance) {
 	w.diff.SetDiff(instance)
 }
 
+// ScrollPreviewTo switches to the preview tab and pins it to the given line of the scrollback.
+func (w *TabbedWindow) ScrollPreviewTo(lines []string, line int) {
+	w.activeTab = PreviewTab
+	w.preview.ScrollToLine(lines, line)
+}
+
+// ResetPreviewScroll makes the preview follow the live pane content again.
+func (w *TabbedWindow) ResetPreviewScroll() {
+	w.preview.ResetScroll()
+}
+
+// IsPreviewScrolled returns true if the preview is pinned to a position in the scrollback.
+func (w *TabbedWindow) IsPreviewScrolled() bool {
+	return w.preview.IsScrolled()
+}
+
+// Preview returns the preview pane, for scrolling through its history.
+func (w *TabbedWindow) Preview() *PreviewPane {
+	return w.preview
+}
+
 // Add these new methods for handling scroll events
 func (w *TabbedWindow) ScrollUp() {
 	if w.activeTab == 1 { // Diff tab
@@ -0,0 +1,269 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Token names a role a color plays in the UI. Styles are built from tokens rather than colors so that themes
+// only need to pick a color for each role.
+type Token string
+
+const (
+	// Text is the color of regular text.
+	Text Token = "text"
+	// Muted is the color of secondary text, such as descriptions and paused instances.
+	Muted Token = "muted"
+	// Subtle is the color of separators.
+	Subtle Token = "subtle"
+	// Key is the color of key names in the menu.
+	Key Token = "key"
+	// Primary is the color of borders, tabs, group headers and other highlights.
+	Primary Token = "primary"
+	// OnPrimary is the color of text drawn on a Primary background.
+	OnPrimary Token = "on_primary"
+	// Accent is the color of the menu.
+	Accent Token = "accent"
+	// SelectedFg and SelectedBg are the colors of the selected row in the list.
+	SelectedFg Token = "selected_fg"
+	SelectedBg Token = "selected_bg"
+	// Success is the color of ready instances and added lines.
+	Success Token = "success"
+	// Warning is the color of instances waiting for input and other notices.
+	Warning Token = "warning"
+	// Error is the color of errors and removed lines.
+	Error Token = "error"
+	// Info is the color of headings in the help screen.
+	Info Token = "info"
+	// Emphasis is the color of keys in the help screen.
+	Emphasis Token = "emphasis"
+	// DiffAdded, DiffRemoved and DiffHunk are the colors used in the diff tab.
+	DiffAdded   Token = "diff_added"
+	DiffRemoved Token = "diff_removed"
+	DiffHunk    Token = "diff_hunk"
+	// Shadow is the color of the shadow drawn behind overlays.
+	Shadow Token = "shadow"
+)
+
+// Tokens is every token a theme can set.
+var Tokens = []Token{
+	Text, Muted, Subtle, Key, Primary, OnPrimary, Accent, SelectedFg, SelectedBg, Success, Warning, Error, Info,
+	Emphasis, DiffAdded, DiffRemoved, DiffHunk, Shadow,
+}
+
+// Auto is the theme name which picks the dark or light theme from the terminal background.
+const Auto = "auto"
+
+// NoColor is the name of the theme which doesn't use any colors.
+const NoColor = "no-color"
+
+// Theme maps tokens to colors. Colors are hex codes ("#7D56F4") or ANSI color numbers ("62"). A token without a
+// color is drawn in the terminal's default color.
+type Theme struct {
+	Name string `json:"name"`
+	// Base is the built-in theme a custom theme starts from. Tokens the custom theme doesn't set keep the base's
+	// colors. It defaults to the dark theme.
+	Base   string           `json:"base,omitempty"`
+	Colors map[Token]string `json:"colors"`
+}
+
+// Color returns the color for a token.
+func (t *Theme) Color(token Token) lipgloss.TerminalColor {
+	if c := t.Colors[token]; c != "" {
+		return lipgloss.Color(c)
+	}
+	return lipgloss.NoColor{}
+}
+
+// HasColor returns true if the theme sets a color for the token.
+func (t *Theme) HasColor(token Token) bool {
+	return t.Colors[token] != ""
+}
+
+var builtins = map[string]*Theme{
+	"dark": {
+		Name: "dark",
+		Colors: map[Token]string{
+			Text:        "#dddddd",
+			Muted:       "#777777",
+			Subtle:      "#3C3C3C",
+			Key:         "#7F7A7A",
+			Primary:     "#7D56F4",
+			OnPrimary:   "230",
+			Accent:      "205",
+			SelectedFg:  "#1a1a1a",
+			SelectedBg:  "#dde4f0",
+			Success:     "#51bd73",
+			Warning:     "#f5a623",
+			Error:       "#de613e",
+			Info:        "#36CFC9",
+			Emphasis:    "#FFCC00",
+			DiffAdded:   "#22c55e",
+			DiffRemoved: "#ef4444",
+			DiffHunk:    "#0ea5e9",
+			Shadow:      "#333333",
+		},
+	},
+	"light": {
+		Name: "light",
+		Colors: map[Token]string{
+			Text:        "#1a1a1a",
+			Muted:       "#8a858b",
+			Subtle:      "#DDDADA",
+			Key:         "#655F5F",
+			Primary:     "#874BFD",
+			OnPrimary:   "#ffffff",
+			Accent:      "162",
+			SelectedFg:  "#1a1a1a",
+			SelectedBg:  "#d3dcef",
+			Success:     "#2e8b4f",
+			Warning:     "#b86e00",
+			Error:       "#c23b22",
+			Info:        "#0e7f86",
+			Emphasis:    "#9a6700",
+			DiffAdded:   "#15803d",
+			DiffRemoved: "#b91c1c",
+			DiffHunk:    "#0369a1",
+			Shadow:      "#bbbbbb",
+		},
+	},
+	"high-contrast": {
+		Name: "high-contrast",
+		Colors: map[Token]string{
+			Text:        "15",
+			Muted:       "7",
+			Subtle:      "7",
+			Key:         "14",
+			Primary:     "12",
+			OnPrimary:   "0",
+			Accent:      "13",
+			SelectedFg:  "0",
+			SelectedBg:  "15",
+			Success:     "10",
+			Warning:     "11",
+			Error:       "9",
+			Info:        "14",
+			Emphasis:    "11",
+			DiffAdded:   "10",
+			DiffRemoved: "9",
+			DiffHunk:    "14",
+			Shadow:      "8",
+		},
+	},
+	NoColor: {
+		Name:   NoColor,
+		Colors: map[Token]string{},
+	},
+}
+
+// Builtin returns the built-in theme with the given name.
+func Builtin(name string) (*Theme, bool) {
+	t, ok := builtins[name]
+	return t, ok
+}
+
+// Names returns the names of the built-in themes.
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var current = builtins["dark"]
+
+// Current returns the theme in use.
+func Current() *Theme {
+	return current
+}
+
+// Set changes the theme in use. Styles built from the previous theme are not updated.
+func Set(t *Theme) {
+	current = t
+}
+
+// Detect picks a theme from the environment: no-color if NO_COLOR is set, otherwise dark or light depending on
+// the terminal background.
+func Detect() *Theme {
+	if os.Getenv("NO_COLOR") != "" {
+		return builtins[NoColor]
+	}
+	if lipgloss.HasDarkBackground() {
+		return builtins["dark"]
+	}
+	return builtins["light"]
+}
+
+// Load returns the theme with the given name. An empty name or "auto" detects the theme, built-in names return
+// the built-in theme and anything else is loaded from <dir>/<name>.json.
+func Load(name, dir string) (*Theme, error) {
+	if name == "" || name == Auto {
+		return Detect(), nil
+	}
+	if t, ok := builtins[name]; ok {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme %s: %w", name, err)
+	}
+	return Parse(name, data)
+}
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Parse parses a custom theme, filling in the tokens it doesn't set from its base theme.
+func Parse(name string, data []byte) (*Theme, error) {
+	var custom Theme
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse theme %s: %w", name, err)
+	}
+
+	baseName := custom.Base
+	if baseName == "" {
+		baseName = "dark"
+	}
+	base, ok := builtins[baseName]
+	if !ok {
+		return nil, fmt.Errorf("theme %s: unknown base theme %q", name, baseName)
+	}
+
+	known := make(map[Token]bool, len(Tokens))
+	for _, token := range Tokens {
+		known[token] = true
+	}
+
+	t := &Theme{Name: name, Base: baseName, Colors: make(map[Token]string, len(Tokens))}
+	for token, c := range base.Colors {
+		t.Colors[token] = c
+	}
+	for token, c := range custom.Colors {
+		if !known[token] {
+			return nil, fmt.Errorf("theme %s: unknown token %q", name, token)
+		}
+		if !validColor(c) {
+			return nil, fmt.Errorf("theme %s: invalid color %q for %s", name, c, token)
+		}
+		t.Colors[token] = c
+	}
+	return t, nil
+}
+
+// validColor returns true for hex colors, ANSI color numbers and the empty string, which means no color.
+func validColor(c string) bool {
+	if c == "" || hexColorRegex.MatchString(c) {
+		return true
+	}
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
@@ -0,0 +1,117 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+		check   func(t *testing.T, th *Theme)
+	}{
+		{
+			name: "unset tokens come from the base",
+			data: `{"base": "light", "colors": {"primary": "#ff0000"}}`,
+			check: func(t *testing.T, th *Theme) {
+				if th.Colors[Primary] != "#ff0000" {
+					t.Errorf("primary = %q, want #ff0000", th.Colors[Primary])
+				}
+				if th.Colors[Text] != builtins["light"].Colors[Text] {
+					t.Errorf("text = %q, want the light theme's", th.Colors[Text])
+				}
+			},
+		},
+		{
+			name: "base defaults to dark",
+			data: `{"colors": {"text": "15"}}`,
+			check: func(t *testing.T, th *Theme) {
+				if th.Base != "dark" || th.Colors[Muted] != builtins["dark"].Colors[Muted] {
+					t.Errorf("expected the dark theme as base, got %q", th.Base)
+				}
+			},
+		},
+		{
+			name: "empty color removes it",
+			data: `{"colors": {"selected_bg": ""}}`,
+			check: func(t *testing.T, th *Theme) {
+				if th.HasColor(SelectedBg) {
+					t.Error("expected selected_bg to have no color")
+				}
+			},
+		},
+		{
+			name:    "unknown token",
+			data:    `{"colors": {"sparkle": "#fff"}}`,
+			wantErr: `unknown token "sparkle"`,
+		},
+		{
+			name:    "invalid color",
+			data:    `{"colors": {"text": "blue"}}`,
+			wantErr: `invalid color "blue"`,
+		},
+		{
+			name:    "unknown base",
+			data:    `{"base": "solarized"}`,
+			wantErr: `unknown base theme "solarized"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th, err := Parse("custom", []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			tt.check(t, th)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mine.json"), []byte(`{"colors": {"primary": "99"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	th, err := Load("high-contrast", dir)
+	if err != nil || th.Name != "high-contrast" {
+		t.Errorf("Load(high-contrast) = %v, %v", th, err)
+	}
+
+	th, err = Load("mine", dir)
+	if err != nil {
+		t.Fatalf("Load(mine) error = %v", err)
+	}
+	if th.Name != "mine" || th.Colors[Primary] != "99" {
+		t.Errorf("Load(mine) = %+v", th)
+	}
+
+	if _, err := Load("missing", dir); err == nil {
+		t.Error("expected an error for a theme that doesn't exist")
+	}
+}
+
+func TestBuiltinsSetEveryToken(t *testing.T) {
+	for _, name := range Names() {
+		if name == NoColor {
+			continue
+		}
+		th, _ := Builtin(name)
+		for _, token := range Tokens {
+			if !th.HasColor(token) {
+				t.Errorf("theme %s doesn't set %s", name, token)
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ChannelStable only has releases which aren't prereleases. It's the default.
+	ChannelStable = "stable"
+	// ChannelBeta adds betas and release candidates to the stable releases.
+	ChannelBeta = "beta"
+	// ChannelNightly adds nightly builds to the beta releases.
+	ChannelNightly = "nightly"
+
+	// minimumVersionTimeout is how long the control plane gets to say which version it requires, so starting orz
+	// offline isn't held up.
+	minimumVersionTimeout = 3 * time.Second
+)
+
+// Channels are the update channels, from the most to the least stable.
+var Channels = []string{ChannelStable, ChannelBeta, ChannelNightly}
+
+// ErrUnsupportedVersion is returned when the running version is older than the control plane's minimum, and
+// orz has to stop.
+var ErrUnsupportedVersion = errors.New("this version of orz is no longer supported")
+
+// channelRank returns the position of channel in Channels, or an error for an unknown channel. An empty channel is
+// the stable one.
+func channelRank(channel string) (int, error) {
+	if channel == "" {
+		return 0, nil
+	}
+	for i, c := range Channels {
+		if c == channel {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown update channel %q, use %s", channel, strings.Join(Channels, ", "))
+}
+
+// Channel returns the least stable channel the release is on: nightly for nightly builds, beta for other
+// prereleases, and stable for the rest.
+func (r ReleaseInfo) Channel() string {
+	version := strings.TrimPrefix(r.TagName, "v")
+	switch {
+	case strings.Contains(version, "nightly"):
+		return ChannelNightly
+	case r.Prerelease || strings.Contains(version, "-"):
+		return ChannelBeta
+	}
+	return ChannelStable
+}
+
+// Version returns the version of the release, without the v of its tag.
+func (r ReleaseInfo) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// Signed reports whether the release has the signed checksums file orz update verifies its archives with. Releases
+// published before releases were signed don't.
+func (r ReleaseInfo) Signed() bool {
+	var checksums, signature bool
+	for _, asset := range r.Assets {
+		checksums = checksums || asset.Name == ChecksumsName
+		signature = signature || asset.Name == SignatureName
+	}
+	return checksums && signature
+}
+
+// ReleasesOn returns the signed releases on the channel. Every channel includes the releases of the more stable ones.
+func ReleasesOn(releases []ReleaseInfo, channel string) ([]ReleaseInfo, error) {
+	rank, err := channelRank(channel)
+	if err != nil {
+		return nil, err
+	}
+	var on []ReleaseInfo
+	for _, release := range releases {
+		if releaseRank, _ := channelRank(release.Channel()); releaseRank <= rank && release.Signed() {
+			on = append(on, release)
+		}
+	}
+	return on, nil
+}
+
+// FindRelease returns the release of the version, with or without a leading v. It fails if the release isn't signed,
+// since it couldn't be installed.
+func FindRelease(releases []ReleaseInfo, version string) (*ReleaseInfo, error) {
+	version = strings.TrimPrefix(version, "v")
+	for i := range releases {
+		if releases[i].Version() != version {
+			continue
+		}
+		if !releases[i].Signed() {
+			return nil, fmt.Errorf("release v%s has no signed %s, so orz update can't verify it", version,
+				ChecksumsName)
+		}
+		return &releases[i], nil
+	}
+	return nil, fmt.Errorf("there is no release of version %s", version)
+}
+
+// ListReleases fetches the published releases, newest first.
+func ListReleases() ([]ReleaseInfo, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/releases?per_page=100", githubAPI, GitHubRepo)
+
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned non-OK status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API response: %w", err)
+	}
+
+	var all []ReleaseInfo
+	if err := json.Unmarshal(body, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	var releases []ReleaseInfo
+	for _, release := range all {
+		if !release.Draft {
+			releases = append(releases, release)
+		}
+	}
+	sort.SliceStable(releases, func(a, b int) bool {
+		return CompareVersions(releases[a].Version(), releases[b].Version()) > 0
+	})
+	return releases, nil
+}
+
+// MinimumVersion asks the control plane for the oldest version of orz it supports. It's empty if there's none.
+func MinimumVersion() (string, error) {
+	apiURL := os.Getenv("ORZBOB_API_URL")
+	if apiURL == "" {
+		apiURL = "http://api.orzbob.com"
+	}
+	client := &http.Client{Timeout: minimumVersionTimeout}
+	resp, err := client.Get(apiURL + "/health")
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the control plane: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("control plane returned non-OK status: %s", resp.Status)
+	}
+	var health struct {
+		MinimumClientVersion string `json:"minimum_client_version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return "", fmt.Errorf("failed to parse control plane response: %w", err)
+	}
+	return strings.TrimPrefix(health.MinimumClientVersion, "v"), nil
+}
+
+// semver is a parsed semantic version. Build metadata is dropped, since it doesn't affect precedence.
+type semver struct {
+	core       [3]int
+	prerelease []string
+}
+
+func parseSemver(version string) (semver, bool) {
+	var v semver
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		v.prerelease = strings.Split(version[i+1:], ".")
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.core[i] = n
+	}
+	return v, true
+}
+
+// CompareVersions compares two semantic versions like 1.2.3 and 1.3.0-beta.1, returning -1, 0 or 1 if a is older
+// than, the same as or newer than b. Versions which can't be parsed, like dev, are older than any which can.
+func CompareVersions(a, b string) int {
+	va, okA := parseSemver(a)
+	vb, okB := parseSemver(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			return compareInts(va.core[i], vb.core[i])
+		}
+	}
+	// A prerelease comes before the release.
+	switch {
+	case len(va.prerelease) == 0 && len(vb.prerelease) == 0:
+		return 0
+	case len(va.prerelease) == 0:
+		return 1
+	case len(vb.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(va.prerelease) && i < len(vb.prerelease); i++ {
+		x, y := va.prerelease[i], vb.prerelease[i]
+		nx, errX := strconv.Atoi(x)
+		ny, errY := strconv.Atoi(y)
+		switch {
+		case errX == nil && errY == nil:
+			if nx != ny {
+				return compareInts(nx, ny)
+			}
+		case errX == nil:
+			// Numeric identifiers come before alphanumeric ones.
+			return -1
+		case errY == nil:
+			return 1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInts(len(va.prerelease), len(vb.prerelease))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
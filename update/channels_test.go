@@ -0,0 +1,201 @@
+package update
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orzbob/config"
+	"orzbob/log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.10.0", "1.9.9", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.3.0-beta.1", "1.3.0", -1},
+		{"1.3.0-beta.1", "1.2.9", 1},
+		{"1.3.0-beta.2", "1.3.0-beta.10", -1},
+		{"1.3.0-alpha", "1.3.0-beta", -1},
+		{"1.3.0-rc.1", "1.3.0-beta.5", 1},
+		{"1.3.0-beta", "1.3.0-beta.1", -1},
+		{"1.3.0-1", "1.3.0-alpha", -1},
+		{"1.3.0+build.5", "1.3.0", 0},
+		{"1.3", "1.3.0", 0},
+		{"dev", "0.0.1", -1},
+		{"0.0.1", "dev", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// verifiedAssets are the assets a release needs to be offered, without their contents.
+var verifiedAssets = []Asset{{Name: ChecksumsName}, {Name: SignatureName}}
+
+var testReleases = []ReleaseInfo{
+	{TagName: "v1.4.0-nightly.20261018", Prerelease: true, Assets: verifiedAssets},
+	{TagName: "v1.3.0-beta.1", Prerelease: true, Assets: verifiedAssets},
+	{TagName: "v1.2.1", Assets: []Asset{{Name: ChecksumsName}}},
+	{TagName: "v1.2.0", Assets: verifiedAssets},
+	{TagName: "v1.1.0", Assets: verifiedAssets},
+	{TagName: "v1.0.0"},
+}
+
+func serveReleases(t *testing.T, releases []ReleaseInfo, minimum string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + GitHubRepo + "/releases":
+			// GitHub lists releases by date, which isn't always the order of their versions.
+			shuffled := append([]ReleaseInfo{}, releases[1:]...)
+			shuffled = append(shuffled, releases[0], ReleaseInfo{TagName: "v9.9.9", Draft: true})
+			_ = json.NewEncoder(w).Encode(shuffled)
+		case "/health":
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "minimum_client_version": minimum})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	oldAPI, oldVersion := githubAPI, CurrentVersion
+	githubAPI = server.URL
+	t.Setenv("ORZBOB_API_URL", server.URL)
+	t.Cleanup(func() {
+		githubAPI, CurrentVersion = oldAPI, oldVersion
+	})
+}
+
+func TestCheckForUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		cfg     config.Config
+		want    string
+	}{
+		{"stable", "1.1.0", config.Config{}, "1.2.0"},
+		{"stable and up to date", "1.2.0", config.Config{UpdateChannel: ChannelStable}, ""},
+		{"beta", "1.2.0", config.Config{UpdateChannel: ChannelBeta}, "1.3.0-beta.1"},
+		{"nightly", "1.2.0", config.Config{UpdateChannel: ChannelNightly}, "1.4.0-nightly.20261018"},
+		{"ahead of the channel", "1.3.0-beta.1", config.Config{}, ""},
+		{"dev build", "dev", config.Config{}, "1.2.0"},
+		{"pinned older", "1.2.0", config.Config{PinnedVersion: "1.1.0"}, "1.1.0"},
+		{"pinned and on it", "1.1.0", config.Config{PinnedVersion: "v1.1.0", UpdateChannel: ChannelNightly}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveReleases(t, testReleases, "")
+			CurrentVersion = tt.current
+
+			release, hasUpdate, err := CheckForUpdates(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if hasUpdate {
+				got = release.Version()
+			}
+			if got != tt.want {
+				t.Errorf("got update %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckForUpdatesRejectsUnknownChannelsAndPins(t *testing.T) {
+	serveReleases(t, testReleases, "")
+	CurrentVersion = "1.1.0"
+	if _, _, err := CheckForUpdates(&config.Config{UpdateChannel: "edge"}); err == nil {
+		t.Error("an unknown channel was accepted")
+	}
+	if _, _, err := CheckForUpdates(&config.Config{PinnedVersion: "0.9.0"}); err == nil {
+		t.Error("a pin to a version which wasn't released was accepted")
+	}
+}
+
+func TestUnsignedReleases(t *testing.T) {
+	on, err := ReleasesOn(testReleases, ChannelStable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, release := range on {
+		if !release.Signed() {
+			t.Errorf("ReleasesOn() offers the unsigned release %s", release.TagName)
+		}
+	}
+	if len(on) != 2 {
+		t.Errorf("ReleasesOn() = %d releases, want the 2 signed stable ones", len(on))
+	}
+	for _, version := range []string{"1.2.1", "v1.0.0"} {
+		if _, err := FindRelease(testReleases, version); err == nil || !strings.Contains(err.Error(), "signed") {
+			t.Errorf("FindRelease(%q) = %v, want an error about the missing signature", version, err)
+		}
+	}
+	if _, err := FindRelease(testReleases, "1.2.0"); err != nil {
+		t.Errorf("FindRelease() of a signed release: %v", err)
+	}
+}
+
+func TestMinimumVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		minimum string
+		below   bool
+	}{
+		{"1.1.0", "1.2.0", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.3.0-beta.1", "v1.2.0", false},
+		{"1.2.0-beta.1", "1.2.0", true},
+		{"1.1.0", "", false},
+		{"dev", "1.2.0", false},
+	}
+	for _, tt := range tests {
+		serveReleases(t, testReleases, tt.minimum)
+		CurrentVersion = tt.current
+
+		min, err := MinimumVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := BelowMinimum(min); got != tt.below {
+			t.Errorf("BelowMinimum(%q) running %s = %v, want %v", min, tt.current, got, tt.below)
+		}
+	}
+}
+
+func TestEnforceMinimumVersionRemembersIt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+	serveReleases(t, testReleases, "1.1.0")
+	CurrentVersion = "1.2.0"
+
+	cfg := config.LoadConfig()
+	if err := enforceMinimumVersion(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if saved := config.LoadConfig(); saved.MinimumVersion != "1.1.0" {
+		t.Errorf("saved minimum version = %q, want 1.1.0", saved.MinimumVersion)
+	}
+
+	now := time.Now()
+	cfg.LastUpdateCheck = now.Add(-time.Hour).Unix()
+	if !checkedRecently(cfg, now) {
+		t.Error("a check an hour ago isn't recent")
+	}
+	cfg.LastUpdateCheck = now.Add(-25 * time.Hour).Unix()
+	if checkedRecently(cfg, now) {
+		t.Error("a check 25 hours ago is recent")
+	}
+}
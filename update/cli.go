@@ -4,70 +4,33 @@ import (
 	"fmt"
 	"orzbob/config"
 	"orzbob/log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	forceFlag bool
+	forceFlag    bool
+	rollbackFlag bool
+	listFlag     bool
+	versionFlag  string
+	channelFlag  string
+	pinFlag      string
+	unpinFlag    bool
 
 	// UpdateCmd is the command for checking and applying updates
 	UpdateCmd = &cobra.Command{
 		Use:   "update",
 		Short: "Check for and apply updates",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Initialize(false)
-			defer log.Close()
-
-			fmt.Println("Checking for updates...")
-
-			release, hasUpdate, err := CheckForUpdates()
-			if err != nil {
-				return fmt.Errorf("failed to check for updates: %w", err)
-			}
-
-			cfg := config.LoadConfig()
-			if err := config.UpdateLastUpdateCheck(cfg); err != nil {
-				log.ErrorLog.Printf("Failed to update last update check timestamp: %v", err)
-			}
-
-			if !hasUpdate {
-				fmt.Printf("You're already running the latest version (v%s).\n", CurrentVersion)
-				return nil
-			}
-
-			fmt.Printf("Update available: v%s → v%s\n", CurrentVersion, release.TagName[1:])
-			fmt.Printf("Release URL: %s\n", release.URL)
+		Long: `Check for a newer release on the update channel and install it.
 
-			if forceFlag || cfg.AutoInstallUpdates {
-				fmt.Println("Installing update...")
-				if err := DownloadAndInstall(release); err != nil {
-					return fmt.Errorf("failed to install update: %w", err)
-				}
-				fmt.Println("Update successfully installed. Please restart the application.")
-				return nil
-			}
-
-			fmt.Print("Do you want to install this update? [y/N]: ")
-			var response string
-			if _, err := fmt.Scanln(&response); err != nil {
-				// Treat error as "no" response
-				response = "N"
-			}
-
-			if response == "y" || response == "Y" {
-				fmt.Println("Installing update...")
-				if err := DownloadAndInstall(release); err != nil {
-					return fmt.Errorf("failed to install update: %w", err)
-				}
-				fmt.Println("Update successfully installed. Please restart the application.")
-			} else {
-				fmt.Println("Update skipped.")
-			}
-
-			return nil
-		},
+The channel is stable unless update_channel in the config says beta or nightly,
+and --channel overrides it once. --list shows the releases on the channel to
+pick one from, and --version installs a specific one. --pin installs a version
+and keeps orz at it until --unpin.`,
+		RunE: runUpdate,
 	}
 
 	// AutoUpdateCmd is a hidden command for automated update checks
@@ -78,16 +41,17 @@ var (
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := config.LoadConfig()
 
-			if !cfg.EnableAutoUpdate {
-				return nil
-			}
-
-			// If last check was within 24 hours, skip
-			if cfg.LastUpdateCheck > 0 {
-				lastCheck := time.Unix(cfg.LastUpdateCheck, 0)
-				if time.Since(lastCheck) < 24*time.Hour {
+			// If last check was within 24 hours, skip, unless the control plane required a newer version then
+			if checkedRecently(cfg, time.Now()) {
+				if !BelowMinimum(cfg.MinimumVersion) {
 					return nil
 				}
+				return enforceMinimumVersion(cfg)
+			}
+
+			// The check isn't recorded when this fails, so the minimum is enforced again on the next start
+			if err := enforceMinimumVersion(cfg); err != nil {
+				return err
 			}
 
 			// Update the last check timestamp regardless of outcome
@@ -97,7 +61,11 @@ var (
 				}
 			}()
 
-			release, hasUpdate, err := CheckForUpdates()
+			if !cfg.EnableAutoUpdate {
+				return nil
+			}
+
+			release, hasUpdate, err := CheckForUpdates(cfg)
 			if err != nil {
 				log.ErrorLog.Printf("Auto-update check failed: %v", err)
 				return nil // Don't propagate auto-update errors
@@ -115,11 +83,11 @@ var (
 				}
 
 				// Notify the user about the successful update instead of restarting
-				fmt.Printf("\nUpdate installed: v%s → v%s\n", CurrentVersion, release.TagName[1:])
+				fmt.Printf("\nUpdate installed: v%s → v%s\n", CurrentVersion, release.Version())
 				fmt.Printf("Please restart the application to use the new version.\n\n")
 			} else {
 				// Just notify the user about the update
-				fmt.Printf("\nUpdate available: v%s → v%s\n", CurrentVersion, release.TagName[1:])
+				fmt.Printf("\nUpdate available: v%s → v%s\n", CurrentVersion, release.Version())
 				fmt.Printf("Run 'orz update' to install the update.\n\n")
 			}
 
@@ -128,6 +96,260 @@ var (
 	}
 )
 
+func runUpdate(cmd *cobra.Command, args []string) error {
+	log.Initialize(false)
+	defer log.Close()
+
+	if rollbackFlag {
+		version, err := Rollback()
+		if err != nil {
+			return fmt.Errorf("failed to roll back: %w", err)
+		}
+		fmt.Printf("Rolled back to v%s. Run 'orz update --rollback' again to undo.\n", version)
+		return nil
+	}
+
+	cfg := config.LoadConfig()
+	if unpinFlag {
+		cfg.PinnedVersion = ""
+		if err := config.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println("Unpinned. Updates follow the update channel again.")
+		return nil
+	}
+
+	// The channel flag only applies to this run, so check with a copy of the config
+	policy := *cfg
+	if channelFlag != "" {
+		if _, err := channelRank(channelFlag); err != nil {
+			return err
+		}
+		policy.UpdateChannel = channelFlag
+		policy.PinnedVersion = ""
+	}
+
+	if listFlag || versionFlag != "" || pinFlag != "" {
+		return installChosenVersion(cfg, &policy)
+	}
+
+	fmt.Println("Checking for updates...")
+
+	release, hasUpdate, err := CheckForUpdates(&policy)
+	if err != nil {
+		return fmt.Errorf("failed to check for updates: %w", err)
+	}
+
+	// The minimum is saved with the check, since starting orz only enforces it until updates are due again
+	min, minErr := MinimumVersion()
+	if minErr == nil {
+		cfg.MinimumVersion = min
+	}
+	if err := config.UpdateLastUpdateCheck(cfg); err != nil {
+		log.ErrorLog.Printf("Failed to update last update check timestamp: %v", err)
+	}
+
+	if minErr == nil && BelowMinimum(min) {
+		fmt.Printf("v%s is no longer supported by Orzbob Cloud, v%s or newer is required.\n", CurrentVersion, min)
+	}
+
+	if !hasUpdate {
+		if policy.PinnedVersion != "" {
+			fmt.Printf("You're running the pinned version (v%s).\n", CurrentVersion)
+			return nil
+		}
+		fmt.Printf("You're already running the latest version (v%s).\n", CurrentVersion)
+		return nil
+	}
+
+	fmt.Printf("Update available: v%s → v%s\n", CurrentVersion, release.Version())
+	fmt.Printf("Release URL: %s\n", release.URL)
+
+	return installWithConfirmation(release, forceFlag || cfg.AutoInstallUpdates)
+}
+
+// installChosenVersion installs the release chosen with --list, --version or --pin, and pins it for --pin.
+func installChosenVersion(cfg *config.Config, policy *config.Config) error {
+	releases, err := ListReleases()
+	if err != nil {
+		return fmt.Errorf("failed to list releases: %w", err)
+	}
+
+	var release *ReleaseInfo
+	switch {
+	case pinFlag != "":
+		release, err = FindRelease(releases, pinFlag)
+	case versionFlag != "":
+		release, err = FindRelease(releases, versionFlag)
+	default:
+		release, err = pickRelease(releases, policy)
+	}
+	if err != nil || release == nil {
+		return err
+	}
+
+	if release.Version() == CurrentVersion {
+		fmt.Printf("You're already running v%s.\n", CurrentVersion)
+	} else if err := installWithConfirmation(release, forceFlag || pinFlag != ""); err != nil {
+		return err
+	}
+
+	if pinFlag != "" {
+		cfg.PinnedVersion = release.Version()
+		if err := config.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Pinned to v%s. Run 'orz update --unpin' to follow the update channel again.\n", release.Version())
+	}
+	return nil
+}
+
+// pickRelease lists the releases on the channel of the policy and asks which one to install. It returns nil if none
+// was picked.
+func pickRelease(releases []ReleaseInfo, policy *config.Config) (*ReleaseInfo, error) {
+	channel := policy.UpdateChannel
+	if channel == "" {
+		channel = ChannelStable
+	}
+	candidates, err := ReleasesOn(releases, channel)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("There are no releases on the %s channel.\n", channel)
+		return nil, nil
+	}
+
+	fmt.Printf("Releases on the %s channel:\n", channel)
+	for i, release := range candidates {
+		var notes []string
+		if release.Channel() != ChannelStable {
+			notes = append(notes, release.Channel())
+		}
+		if release.Version() == CurrentVersion {
+			notes = append(notes, "current")
+		}
+		if release.Version() == policy.PinnedVersion {
+			notes = append(notes, "pinned")
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = "  (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Printf("%3d  v%-20s %s%s\n", i+1, release.Version(), release.Date.Format("2006-01-02"), note)
+	}
+
+	fmt.Print("Install which version? Enter a number or version, or nothing to cancel: ")
+	var answer string
+	if _, err := fmt.Scanln(&answer); err != nil || answer == "" {
+		fmt.Println("Update skipped.")
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(candidates) {
+			return nil, fmt.Errorf("pick a number from 1 to %d", len(candidates))
+		}
+		return &candidates[n-1], nil
+	}
+	return FindRelease(releases, answer)
+}
+
+// installWithConfirmation installs the release, asking first unless force is set.
+func installWithConfirmation(release *ReleaseInfo, force bool) error {
+	if !force && !confirm(fmt.Sprintf("Do you want to install v%s? [y/N]: ", release.Version()), false) {
+		fmt.Println("Update skipped.")
+		return nil
+	}
+	fmt.Println("Installing update...")
+	if err := DownloadAndInstall(release); err != nil {
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	fmt.Println("Update successfully installed. Please restart the application.")
+	return nil
+}
+
+// confirm asks a yes or no question, answered with the default if nothing is entered.
+func confirm(prompt string, defaultYes bool) bool {
+	fmt.Print(prompt)
+	var response string
+	if _, err := fmt.Scanln(&response); err != nil {
+		// Treat error as the default response
+		return defaultYes
+	}
+	switch strings.ToLower(response) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return defaultYes
+}
+
+// checkedRecently reports whether updates were checked for in the last 24 hours.
+func checkedRecently(cfg *config.Config, now time.Time) bool {
+	return cfg.LastUpdateCheck > 0 && now.Sub(time.Unix(cfg.LastUpdateCheck, 0)) < 24*time.Hour
+}
+
+// enforceMinimumVersion updates orz if the control plane doesn't support the running version any more. It returns
+// ErrUnsupportedVersion if orz has to stop, either because the update was declined or because it was installed and
+// orz has to be started again. An unreachable control plane enforces nothing.
+func enforceMinimumVersion(cfg *config.Config) error {
+	min, err := MinimumVersion()
+	if err != nil {
+		log.WarningLog.Printf("could not get the minimum version: %v", err)
+		return nil
+	}
+	if min != cfg.MinimumVersion {
+		// Remember it so it's enforced on the next start too, before updates are due to be checked again.
+		cfg.MinimumVersion = min
+		if err := config.SaveConfig(cfg); err != nil {
+			log.WarningLog.Printf("could not save the minimum version: %v", err)
+		}
+	}
+	if !BelowMinimum(min) {
+		return nil
+	}
+	if cfg.PinnedVersion != "" && CompareVersions(cfg.PinnedVersion, min) < 0 {
+		return fmt.Errorf("%w: v%s or newer is required, but v%s is pinned. Run 'orz update --unpin'",
+			ErrUnsupportedVersion, min, cfg.PinnedVersion)
+	}
+
+	releases, err := ListReleases()
+	if err != nil {
+		return fmt.Errorf("%w: v%s or newer is required, and the releases couldn't be listed: %v",
+			ErrUnsupportedVersion, min, err)
+	}
+	var release *ReleaseInfo
+	if cfg.PinnedVersion != "" {
+		release, err = FindRelease(releases, cfg.PinnedVersion)
+	} else {
+		var candidates []ReleaseInfo
+		candidates, err = ReleasesOn(releases, cfg.UpdateChannel)
+		if err == nil && len(candidates) > 0 {
+			release = &candidates[0]
+		}
+	}
+	if err != nil || release == nil || CompareVersions(release.Version(), min) < 0 {
+		return fmt.Errorf("%w: v%s or newer is required, but there's no such release on the update channel",
+			ErrUnsupportedVersion, min)
+	}
+
+	fmt.Printf("\norz v%s is no longer supported, v%s or newer is required.\n", CurrentVersion, min)
+	if !cfg.AutoInstallUpdates && !confirm(fmt.Sprintf("Install v%s now? [Y/n]: ", release.Version()), true) {
+		return fmt.Errorf("%w: v%s or newer is required, run 'orz update' to install it", ErrUnsupportedVersion, min)
+	}
+	if err := DownloadAndInstall(release); err != nil {
+		return fmt.Errorf("%w: failed to install v%s: %v", ErrUnsupportedVersion, release.Version(), err)
+	}
+	return fmt.Errorf("%w: v%s was installed, start orz again", ErrUnsupportedVersion, release.Version())
+}
+
 func init() {
 	UpdateCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Force update without confirmation")
+	UpdateCmd.Flags().BoolVar(&rollbackFlag, "rollback", false, "Restore the version the last update replaced")
+	UpdateCmd.Flags().BoolVarP(&listFlag, "list", "l", false, "List the releases on the update channel to pick one")
+	UpdateCmd.Flags().StringVar(&versionFlag, "version", "", "Install this version")
+	UpdateCmd.Flags().StringVar(&channelFlag, "channel", "", "Update channel to use this time: stable, beta or nightly")
+	UpdateCmd.Flags().StringVar(&pinFlag, "pin", "", "Install this version and stay on it")
+	UpdateCmd.Flags().BoolVar(&unpinFlag, "unpin", false, "Follow the update channel again")
 }
@@ -1,10 +1,10 @@
 package update
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"orzbob/config"
 	"orzbob/log"
 	"os"
 	"os/exec"
@@ -20,6 +20,9 @@ var (
 
 	// GitHubRepo is the GitHub repository to check for updates
 	GitHubRepo = "carnivoroustoad/orzbob"
+
+	// githubAPI is the base URL of the GitHub API. Tests point it at a stand-in.
+	githubAPI = "https://api.github.com"
 )
 
 // ReleaseInfo represents the GitHub API response for a release
@@ -29,6 +32,9 @@ type ReleaseInfo struct {
 	Assets  []Asset   `json:"assets"`
 	URL     string    `json:"html_url"`
 	Date    time.Time `json:"published_at"`
+	// Prerelease is set for betas and nightly builds.
+	Prerelease bool `json:"prerelease"`
+	Draft      bool `json:"draft"`
 }
 
 // Asset represents a release asset
@@ -38,22 +44,41 @@ type Asset struct {
 	Size        int    `json:"size"`
 }
 
-// CheckForUpdates checks if there is a newer version available
-func CheckForUpdates() (*ReleaseInfo, bool, error) {
+// CheckForUpdates checks if there is a newer version on the update channel of the config. If a version is pinned,
+// that version is the update, even if it's older.
+func CheckForUpdates(cfg *config.Config) (*ReleaseInfo, bool, error) {
 	// Don't log if InfoLog is nil (happens during initial check)
 	if log.InfoLog != nil {
 		log.InfoLog.Println("Checking for updates...")
 	}
 
-	latestRelease, err := getLatestRelease()
+	releases, err := ListReleases()
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to get latest release: %w", err)
+		return nil, false, fmt.Errorf("failed to list releases: %w", err)
 	}
 
-	// Extract version from TagName by removing 'v' prefix
-	latestVersion := strings.TrimPrefix(latestRelease.TagName, "v")
+	if cfg.PinnedVersion != "" {
+		pinned, err := FindRelease(releases, cfg.PinnedVersion)
+		if err != nil {
+			return nil, false, fmt.Errorf("pinned version: %w", err)
+		}
+		if pinned.Version() == CurrentVersion {
+			return nil, false, nil
+		}
+		return pinned, true, nil
+	}
+
+	candidates, err := ReleasesOn(releases, cfg.UpdateChannel)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(candidates) == 0 {
+		return nil, false, nil
+	}
+	latestRelease := &candidates[0]
 
 	// Compare versions
+	latestVersion := latestRelease.Version()
 	hasUpdate := isNewerVersion(CurrentVersion, latestVersion)
 
 	if hasUpdate {
@@ -69,54 +94,52 @@ func CheckForUpdates() (*ReleaseInfo, bool, error) {
 	return nil, false, nil
 }
 
-// getLatestRelease fetches the latest release from GitHub API
-func getLatestRelease() (*ReleaseInfo, error) {
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", GitHubRepo)
-
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from GitHub API: %w", err)
+// isNewerVersion determines if available version is newer than current. Every version is an update of a build
+// without a proper version, like dev.
+func isNewerVersion(currentVersion, availableVersion string) bool {
+	if _, ok := parseSemver(currentVersion); !ok {
+		return availableVersion != currentVersion
 	}
-	defer resp.Body.Close()
+	return CompareVersions(availableVersion, currentVersion) > 0
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned non-OK status: %s", resp.Status)
+// BelowMinimum reports whether the running version is older than min. Builds without a proper version never are.
+func BelowMinimum(min string) bool {
+	if _, ok := parseSemver(CurrentVersion); !ok || min == "" {
+		return false
 	}
+	return CompareVersions(CurrentVersion, min) < 0
+}
 
-	body, err := io.ReadAll(resp.Body)
+// DownloadAndInstall downloads the release for this platform, verifies it and replaces the running binary with
+// it. The replaced binary is kept for Rollback.
+func DownloadAndInstall(release *ReleaseInfo) error {
+	execPath, err := executablePath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %w", err)
-	}
-
-	var release ReleaseInfo
-	if err := json.Unmarshal(body, &release); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
+		return err
 	}
-
-	return &release, nil
+	return installRelease(release, execPath)
 }
 
-// isNewerVersion determines if available version is newer than current
-func isNewerVersion(currentVersion, availableVersion string) bool {
-	// Simple version comparison - can be replaced with semver for more robust handling
-	return availableVersion != currentVersion
+// executablePath returns the path of the running binary, with symlinks resolved so the binary itself is replaced.
+func executablePath() (string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return filepath.EvalSymlinks(execPath)
 }
 
-// DownloadAndInstall downloads and installs the latest version
-func DownloadAndInstall(release *ReleaseInfo) error {
+// installRelease installs the release as the binary at execPath. Nothing is installed unless the signature of the
+// checksums file verifies with the release key, the archive matches its checksum, and the binary in it passes a
+// self-check.
+func installRelease(release *ReleaseInfo, execPath string) error {
 	log.InfoLog.Println("Starting update process...")
 
 	// Get platform and architecture
 	platform := runtime.GOOS
 	architecture := runtime.GOARCH
 
-	// Normalize architecture naming to match release asset naming
-	if architecture == "amd64" {
-		architecture = "amd64"
-	} else if architecture == "arm64" {
-		architecture = "arm64"
-	}
-
 	// Determine archive extension
 	archiveExt := ".tar.gz"
 	if platform == "windows" {
@@ -129,17 +152,15 @@ func DownloadAndInstall(release *ReleaseInfo) error {
 	// Determine archive name using the same format as in install.sh
 	archiveName := fmt.Sprintf("orzbob_%s_%s_%s%s", version, platform, architecture, archiveExt)
 
-	// Find the correct asset
-	var downloadURL string
+	// Find the archive and what it's verified with
+	urls := make(map[string]string)
 	for _, asset := range release.Assets {
-		if asset.Name == archiveName {
-			downloadURL = asset.DownloadURL
-			break
-		}
+		urls[asset.Name] = asset.DownloadURL
 	}
-
-	if downloadURL == "" {
-		return fmt.Errorf("could not find download URL for %s", archiveName)
+	for _, name := range []string{archiveName, ChecksumsName, SignatureName} {
+		if urls[name] == "" {
+			return fmt.Errorf("release %s has no %s, so it can't be verified", release.TagName, name)
+		}
 	}
 
 	// Create temporary directory
@@ -149,11 +170,39 @@ func DownloadAndInstall(release *ReleaseInfo) error {
 	}
 	defer os.RemoveAll(tmpDir)
 
+	// Verify the checksums file before trusting anything in it
+	checksumsPath := filepath.Join(tmpDir, ChecksumsName)
+	signaturePath := filepath.Join(tmpDir, SignatureName)
+	if err := downloadFile(urls[ChecksumsName], checksumsPath); err != nil {
+		return fmt.Errorf("failed to download %s: %w", ChecksumsName, err)
+	}
+	if err := downloadFile(urls[SignatureName], signaturePath); err != nil {
+		return fmt.Errorf("failed to download %s: %w", SignatureName, err)
+	}
+	checksums, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(checksums, signature); err != nil {
+		return err
+	}
+	checksum, err := checksumFor(checksums, archiveName)
+	if err != nil {
+		return err
+	}
+
 	// Download the archive
 	archivePath := filepath.Join(tmpDir, archiveName)
-	if err := downloadFile(downloadURL, archivePath); err != nil {
+	if err := downloadFile(urls[archiveName], archivePath); err != nil {
 		return fmt.Errorf("failed to download update: %w", err)
 	}
+	if err := verifyChecksum(archivePath, checksum); err != nil {
+		return err
+	}
 
 	// Extract the archive based on platform
 	if platform == "windows" {
@@ -170,44 +219,91 @@ func DownloadAndInstall(release *ReleaseInfo) error {
 		}
 	}
 
-	// Get executable path
-	execPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	// Create backup of current executable
-	backupPath := execPath + ".backup"
-	if err := copyFile(execPath, backupPath); err != nil {
-		return fmt.Errorf("failed to create backup: %w", err)
-	}
-
 	// Binary name with platform-specific extension
 	binaryName := "orzbob"
 	if platform == "windows" {
 		binaryName += ".exe"
 	}
-
-	// Replace current executable with new one
 	extractedBinaryPath := filepath.Join(tmpDir, binaryName)
-	if err := copyFile(extractedBinaryPath, execPath); err != nil {
+	if err := selfCheck(extractedBinaryPath, version); err != nil {
+		return err
+	}
+
+	if err := replaceBinary(extractedBinaryPath, execPath); err != nil {
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	log.InfoLog.Printf("Successfully updated to version %s", release.TagName)
+	return nil
+}
+
+// BackupPath returns where the binary at execPath is kept when it's replaced by an update.
+func BackupPath(execPath string) string {
+	return execPath + ".backup"
+}
+
+// replaceBinary installs the binary at src as execPath and keeps the binary it replaces at BackupPath. The new binary
+// is copied next to execPath first, so it's swapped in by renames which can't leave a half-written binary behind.
+func replaceBinary(src, execPath string) error {
+	newPath := execPath + ".new"
+	if err := copyFile(src, newPath); err != nil {
+		return err
+	}
+	if err := os.Chmod(newPath, 0755); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("failed to set executable permissions: %w", err)
+	}
+
+	backupPath := BackupPath(execPath)
+	_ = os.Remove(backupPath)
+	if err := os.Rename(execPath, backupPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	if err := os.Rename(newPath, execPath); err != nil {
 		// Restore from backup if update fails
-		restoreErr := copyFile(backupPath, execPath)
-		if restoreErr != nil {
+		if restoreErr := os.Rename(backupPath, execPath); restoreErr != nil {
 			log.ErrorLog.Printf("Failed to restore from backup: %v", restoreErr)
 		}
-		return fmt.Errorf("failed to install update: %w", err)
+		return err
 	}
+	return nil
+}
 
-	// Set executable permissions
-	if platform != "windows" {
-		if err := os.Chmod(execPath, 0755); err != nil {
-			return fmt.Errorf("failed to set executable permissions: %w", err)
-		}
+// Rollback swaps the running binary with the one the last update replaced, and returns the version rolled back to.
+// Rolling back again undoes the rollback.
+func Rollback() (string, error) {
+	execPath, err := executablePath()
+	if err != nil {
+		return "", err
 	}
+	return rollback(execPath)
+}
 
-	log.InfoLog.Printf("Successfully updated to version %s", release.TagName)
-	return nil
+func rollback(execPath string) (string, error) {
+	backupPath := BackupPath(execPath)
+	if _, err := os.Stat(backupPath); err != nil {
+		return "", fmt.Errorf("there is no previous version to roll back to")
+	}
+	version, err := binaryVersion(backupPath)
+	if err != nil {
+		return "", fmt.Errorf("the previous version is broken: %w", err)
+	}
+
+	currentPath := execPath + ".new"
+	if err := os.Rename(execPath, currentPath); err != nil {
+		return "", err
+	}
+	if err := os.Rename(backupPath, execPath); err != nil {
+		if restoreErr := os.Rename(currentPath, execPath); restoreErr != nil {
+			log.ErrorLog.Printf("Failed to restore the current version: %v", restoreErr)
+		}
+		return "", err
+	}
+	if err := os.Rename(currentPath, backupPath); err != nil {
+		return "", err
+	}
+	log.InfoLog.Printf("Rolled back to version %s", version)
+	return version, nil
 }
 
 // downloadFile downloads a file from URL to a local path
@@ -0,0 +1,259 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orzbob/config"
+	"orzbob/log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeBinary is a script which answers the version command like orz does.
+func fakeBinary(version string) []byte {
+	return []byte(fmt.Sprintf("#!/bin/sh\necho 'orz version %s'\n", version))
+}
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// releaseServer stands in for the GitHub releases API and the release assets.
+type releaseServer struct {
+	*httptest.Server
+	// assets are served by name from /download/.
+	assets map[string][]byte
+}
+
+func newReleaseServer(t *testing.T, tag string, assets map[string][]byte) *releaseServer {
+	s := &releaseServer{assets: assets}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/"+GitHubRepo+"/releases" {
+			release := ReleaseInfo{TagName: tag}
+			for name, content := range s.assets {
+				release.Assets = append(release.Assets, Asset{Name: name, DownloadURL: s.URL + "/download/" + name,
+					Size: len(content)})
+			}
+			_ = json.NewEncoder(w).Encode([]ReleaseInfo{release})
+			return
+		}
+		content, ok := s.assets[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// signedAssets returns the assets of a release of version holding binary, signed with key.
+func signedAssets(t *testing.T, version string, binary []byte, key ed25519.PrivateKey) map[string][]byte {
+	archiveName := fmt.Sprintf("orzbob_%s_%s_%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+	archive := tarGz(t, "orzbob", binary)
+	sum := sha256.Sum256(archive)
+	checksums := []byte(fmt.Sprintf("%s  %s\n%s  orzbob_%s_other_arch.tar.gz\n", hex.EncodeToString(sum[:]),
+		archiveName, strings.Repeat("0", 64), version))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, checksums))
+	return map[string][]byte{
+		archiveName:   archive,
+		ChecksumsName: checksums,
+		SignatureName: []byte(signature + "\n"),
+	}
+}
+
+func setupUpdate(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake binaries are shell scripts")
+	}
+	t.Setenv("HOME", t.TempDir())
+	log.Initialize(false)
+	t.Cleanup(log.Close)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, oldAPI, oldVersion := releaseKey, githubAPI, CurrentVersion
+	releaseKey = public
+	CurrentVersion = "1.0.0"
+	t.Cleanup(func() {
+		releaseKey, githubAPI, CurrentVersion = oldKey, oldAPI, oldVersion
+	})
+
+	execPath := filepath.Join(t.TempDir(), "orzbob")
+	if err := os.WriteFile(execPath, fakeBinary("1.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return private, execPath
+}
+
+func checkBinary(t *testing.T, path, version string) {
+	t.Helper()
+	got, err := binaryVersion(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != version {
+		t.Errorf("%s is version %s, want %s", path, got, version)
+	}
+}
+
+func TestInstallAndRollback(t *testing.T) {
+	key, execPath := setupUpdate(t)
+	server := newReleaseServer(t, "v1.2.3", signedAssets(t, "1.2.3", fakeBinary("1.2.3"), key))
+	githubAPI = server.URL
+
+	release, hasUpdate, err := CheckForUpdates(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUpdate {
+		t.Fatal("v1.2.3 wasn't found as an update of 1.0.0")
+	}
+	if err := installRelease(release, execPath); err != nil {
+		t.Fatal(err)
+	}
+	checkBinary(t, execPath, "1.2.3")
+	checkBinary(t, BackupPath(execPath), "1.0.0")
+
+	version, err := rollback(execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.0.0" {
+		t.Errorf("rolled back to %s, want 1.0.0", version)
+	}
+	checkBinary(t, execPath, "1.0.0")
+
+	// Rolling back again undoes the rollback.
+	if _, err := rollback(execPath); err != nil {
+		t.Fatal(err)
+	}
+	checkBinary(t, execPath, "1.2.3")
+}
+
+func TestInstallRefusesUnverifiedReleases(t *testing.T) {
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		// change makes the assets of a good release bad.
+		change func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey)
+		want   string
+	}{
+		{
+			name: "no signature",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				delete(assets, SignatureName)
+			},
+			want: "no signed checksums.txt",
+		},
+		{
+			name: "signed with another key",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				for name, content := range signedAssets(t, "1.2.3", fakeBinary("1.2.3"), otherKey) {
+					assets[name] = content
+				}
+			},
+			want: "signature",
+		},
+		{
+			name: "tampered archive",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				evil := signedAssets(t, "1.2.3", []byte("#!/bin/sh\necho 'orz version 1.2.3'; echo evil\n"), key)
+				archiveName := fmt.Sprintf("orzbob_1.2.3_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+				assets[archiveName] = evil[archiveName]
+			},
+			want: "checksum",
+		},
+		{
+			name: "tampered checksums",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				assets[ChecksumsName] = append(assets[ChecksumsName], []byte("# extra\n")...)
+			},
+			want: "signature",
+		},
+		{
+			name: "broken binary",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				for name, content := range signedAssets(t, "1.2.3", []byte("#!/bin/sh\nexit 1\n"), key) {
+					assets[name] = content
+				}
+			},
+			want: "self-check",
+		},
+		{
+			name: "wrong version",
+			change: func(t *testing.T, assets map[string][]byte, key ed25519.PrivateKey) {
+				for name, content := range signedAssets(t, "1.2.3", fakeBinary("dev"), key) {
+					assets[name] = content
+				}
+			},
+			want: "reports version dev",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, execPath := setupUpdate(t)
+			assets := signedAssets(t, "1.2.3", fakeBinary("1.2.3"), key)
+			tt.change(t, assets, key)
+			server := newReleaseServer(t, "v1.2.3", assets)
+			githubAPI = server.URL
+
+			releases, err := ListReleases()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A release without a signature is already refused when it's looked up.
+			release, err := FindRelease(releases, "1.2.3")
+			if err == nil {
+				err = installRelease(release, execPath)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("installing = %v, want an error about %q", err, tt.want)
+			}
+			checkBinary(t, execPath, "1.0.0")
+			if _, err := os.Stat(BackupPath(execPath)); err == nil {
+				t.Error("a backup was made although nothing was installed")
+			}
+		})
+	}
+}
+
+func TestRollbackWithoutBackup(t *testing.T) {
+	_, execPath := setupUpdate(t)
+	if _, err := rollback(execPath); err == nil {
+		t.Fatal("rolled back without a previous version")
+	}
+	checkBinary(t, execPath, "1.0.0")
+}
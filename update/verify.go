@@ -0,0 +1,115 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// ChecksumsName is the asset of a release holding the SHA-256 checksums of its archives, as written by sha256sum.
+	ChecksumsName = "checksums.txt"
+	// SignatureName is the asset holding the base64 ed25519 signature of ChecksumsName.
+	SignatureName = ChecksumsName + ".sig"
+	// selfCheckTimeout is how long a new binary gets to print its version.
+	selfCheckTimeout = 10 * time.Second
+)
+
+// releaseKeyBase64 is the public half of the key releases are signed with by hack/sign-release.
+//
+//go:embed release_key.pub
+var releaseKeyBase64 string
+
+// releaseKey verifies the signatures of releases. Tests replace it with a key of their own.
+var releaseKey = mustDecodeKey(releaseKeyBase64)
+
+func mustDecodeKey(encoded string) ed25519.PublicKey {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		panic(fmt.Sprintf("invalid release key %q", encoded))
+	}
+	return ed25519.PublicKey(key)
+}
+
+// verifySignature checks that signature, the base64 contents of SignatureName, is a signature of checksums by the
+// release key.
+func verifySignature(checksums, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if !ed25519.Verify(releaseKey, checksums, sig) {
+		return fmt.Errorf("the signature of %s doesn't match, the release may have been tampered with", ChecksumsName)
+	}
+	return nil
+}
+
+// checksumFor finds the checksum of the named file in the contents of ChecksumsName.
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks files read in binary mode with a *.
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", ChecksumsName, name)
+}
+
+// verifyChecksum checks that the SHA-256 checksum of the file at path is want.
+func verifyChecksum(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum of %s is %s, want %s", path, got, want)
+	}
+	return nil
+}
+
+// binaryVersion runs the binary at path with the version command and returns the version it reports.
+func binaryVersion(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed to run: %w", path, err)
+	}
+	firstLine := strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)[0]
+	version := strings.TrimPrefix(firstLine, "orz version ")
+	if version == firstLine {
+		return "", fmt.Errorf("%s doesn't report a version, it says %q", path, firstLine)
+	}
+	return version, nil
+}
+
+// selfCheck checks that the binary at path runs and reports the expected version, so a binary which doesn't run on
+// this machine is never installed.
+func selfCheck(path, version string) error {
+	got, err := binaryVersion(path)
+	if err != nil {
+		return fmt.Errorf("the new binary failed its self-check: %w", err)
+	}
+	if got != version {
+		return fmt.Errorf("the new binary reports version %s, want %s", got, version)
+	}
+	return nil
+}